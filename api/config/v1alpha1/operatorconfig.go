@@ -0,0 +1,450 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+const (
+	DefaultRequiredSuffix          = "-tools"
+	DefaultAccountNameTemplate     = "ScannerCLI_{{ .NamespacePrefix }}"
+	DefaultMaxConcurrentReconciles = 4
+	DefaultRetryBaseDelay          = 5 * time.Millisecond
+	DefaultRetryMaxDelay           = 1000 * time.Second
+	DefaultRateLimitQPS            = 10
+	DefaultRateLimitBurst          = 100
+	DefaultTemplatesDirectory      = "templates"
+	DefaultScannerImage            = "registry.aquasec.com/scanner:2022.4"
+	DefaultReportImage             = "docker.io/bcdevopscluster/aqua-scanner-operator-bundle:v0.0.5" // the operator's image, it ships kubectl-aqua
+	DefaultImageScanTimeout        = 30 * time.Minute
+	DefaultImageScanTTL            = time.Hour
+	DefaultPasswordLength          = 16
+	DefaultPasswordSymbols         = "!@#$"
+	DefaultPasswordMinEntropyBits  = 80
+	DefaultAuditFile               = "/var/log/aqua-scanner-operator/audit.jsonl"
+	DefaultAuditTimeout            = 10 * time.Second
+	DefaultAuditMaxAge             = 90 * 24 * time.Hour
+	DefaultAuditMaxRecords         = 100000
+	DefaultTracingSamplingRatio    = 1.0
+	DefaultTracingServiceName      = "aqua-scanner-operator"
+	DefaultAdminCertDir            = "/tmp/k8s-admin-server/serving-certs"
+	DefaultAllowedRegistry         = "Docker Hub" // the registry of AquaScannerAccounts without spec.scope
+	// the bounds of passwords.length
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+var (
+	supportedAuthMethods   = []string{"password", "token", "apiKey"}
+	supportedTLSVersions   = []string{"1.0", "1.1", "1.2", "1.3"}
+	overridableTemplates   = []string{ApplicationScopeTemplate, PermissionSetTemplate, RoleTemplate, UserTemplate}
+	exampleAccountNameData = AccountNameData{Namespace: "example-tools", NamespacePrefix: "example"}
+	passwordClasses        = []string{"lowercase", "uppercase", "digits", "symbols"}
+	supportedAuditSinks    = []string{AuditSinkFile, AuditSinkStdout, AuditSinkHTTP}
+)
+
+// characters html/template escapes in the user template or that have to be escaped in its json
+const unsafePasswordSymbols = "\"\\'<>&+` \t\r\n"
+
+// the values of audit.sink
+const (
+	AuditSinkFile   = "file"
+	AuditSinkStdout = "stdout"
+	AuditSinkHTTP   = "http"
+)
+
+// the values available to the naming.accountNameTemplate
+type AccountNameData struct {
+	Namespace       string
+	NamespacePrefix string
+}
+
+// Default fills in every unset field that has a default
+func (c *OperatorConfig) Default() {
+	if c.Namespaces.RequiredSuffix == nil {
+		suffix := DefaultRequiredSuffix
+		c.Namespaces.RequiredSuffix = &suffix
+	}
+	if c.Naming.AccountNameTemplate == "" {
+		c.Naming.AccountNameTemplate = DefaultAccountNameTemplate
+	}
+	if c.Reconcile.MaxConcurrentReconciles == 0 {
+		c.Reconcile.MaxConcurrentReconciles = DefaultMaxConcurrentReconciles
+	}
+	if c.Reconcile.Retry.BaseDelay == nil {
+		c.Reconcile.Retry.BaseDelay = &metav1.Duration{Duration: DefaultRetryBaseDelay}
+	}
+	if c.Reconcile.Retry.MaxDelay == nil {
+		c.Reconcile.Retry.MaxDelay = &metav1.Duration{Duration: DefaultRetryMaxDelay}
+	}
+	if c.Reconcile.RateLimit.QPS == 0 {
+		c.Reconcile.RateLimit.QPS = DefaultRateLimitQPS
+	}
+	if c.Reconcile.RateLimit.Burst == 0 {
+		c.Reconcile.RateLimit.Burst = DefaultRateLimitBurst
+	}
+	if c.Templates.Directory == "" {
+		c.Templates.Directory = DefaultTemplatesDirectory
+	}
+	if c.ImageScans.ScannerImage == "" {
+		c.ImageScans.ScannerImage = DefaultScannerImage
+	}
+	if c.ImageScans.ReportImage == "" {
+		c.ImageScans.ReportImage = DefaultReportImage
+	}
+	if c.ImageScans.Timeout == nil {
+		c.ImageScans.Timeout = &metav1.Duration{Duration: DefaultImageScanTimeout}
+	}
+	if c.ImageScans.TTLAfterFinished == nil {
+		c.ImageScans.TTLAfterFinished = &metav1.Duration{Duration: DefaultImageScanTTL}
+	}
+	if c.Scope.AllowedRegistries == nil {
+		c.Scope.AllowedRegistries = []string{DefaultAllowedRegistry}
+	}
+	if c.Passwords.Length == 0 {
+		c.Passwords.Length = DefaultPasswordLength
+	}
+	if len(c.Passwords.RequiredClasses) == 0 {
+		c.Passwords.RequiredClasses = append([]string{}, passwordClasses...)
+	}
+	if c.Passwords.Symbols == "" {
+		c.Passwords.Symbols = DefaultPasswordSymbols
+	}
+	if c.Passwords.MinEntropyBits == nil {
+		minEntropyBits := DefaultPasswordMinEntropyBits
+		c.Passwords.MinEntropyBits = &minEntropyBits
+	}
+	if c.Audit.File == "" {
+		c.Audit.File = DefaultAuditFile
+	}
+	if c.Audit.Timeout == nil {
+		c.Audit.Timeout = &metav1.Duration{Duration: DefaultAuditTimeout}
+	}
+	if c.Audit.MaxAge == nil {
+		c.Audit.MaxAge = &metav1.Duration{Duration: DefaultAuditMaxAge}
+	}
+	if c.Audit.MaxRecords == 0 {
+		c.Audit.MaxRecords = DefaultAuditMaxRecords
+	}
+	if c.Tracing.SamplingRatio == nil {
+		ratio := DefaultTracingSamplingRatio
+		c.Tracing.SamplingRatio = &ratio
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = DefaultTracingServiceName
+	}
+	if c.Admin.CertDir == "" {
+		c.Admin.CertDir = DefaultAdminCertDir
+	}
+	for _, toggle := range []**bool{&c.Passwords.FollowAquaPolicy, &c.Features.AquaInstances, &c.Features.CredentialsReload, &c.Features.Webhooks, &c.Features.ImageScans} {
+		if *toggle == nil {
+			enabled := true
+			*toggle = &enabled
+		}
+	}
+}
+
+// Validate returns an error for every field that holds a value the operator can not run with
+func (c *OperatorConfig) Validate() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if c.SyncPeriod != nil && c.SyncPeriod.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("syncPeriod"), c.SyncPeriod.Duration.String(), "must be greater than 0"))
+	}
+
+	allErrs = append(allErrs, c.Aqua.validate(field.NewPath("aqua"))...)
+	allErrs = append(allErrs, c.validateNamespaces(field.NewPath("namespaces"))...)
+
+	namingPath := field.NewPath("naming", "accountNameTemplate")
+	if name, err := c.Naming.AccountName(exampleAccountNameData); err != nil {
+		allErrs = append(allErrs, field.Invalid(namingPath, c.Naming.AccountNameTemplate, err.Error()))
+	} else if strings.TrimSpace(name) == "" {
+		allErrs = append(allErrs, field.Invalid(namingPath, c.Naming.AccountNameTemplate, "must not render an empty name"))
+	}
+
+	reconcilePath := field.NewPath("reconcile")
+	if c.Reconcile.MaxConcurrentReconciles < 1 {
+		allErrs = append(allErrs, field.Invalid(reconcilePath.Child("maxConcurrentReconciles"), c.Reconcile.MaxConcurrentReconciles, "must be at least 1"))
+	}
+	allErrs = append(allErrs, validatePositiveDuration(reconcilePath.Child("retry", "baseDelay"), c.Reconcile.Retry.BaseDelay)...)
+	allErrs = append(allErrs, validatePositiveDuration(reconcilePath.Child("retry", "maxDelay"), c.Reconcile.Retry.MaxDelay)...)
+	if c.Reconcile.Retry.BaseDelay != nil && c.Reconcile.Retry.MaxDelay != nil && c.Reconcile.Retry.MaxDelay.Duration < c.Reconcile.Retry.BaseDelay.Duration {
+		allErrs = append(allErrs, field.Invalid(reconcilePath.Child("retry", "maxDelay"), c.Reconcile.Retry.MaxDelay.Duration.String(), "must not be less than retry.baseDelay"))
+	}
+	if c.Reconcile.RateLimit.QPS < 1 {
+		allErrs = append(allErrs, field.Invalid(reconcilePath.Child("rateLimit", "qps"), c.Reconcile.RateLimit.QPS, "must be at least 1"))
+	}
+	if c.Reconcile.RateLimit.Burst < c.Reconcile.RateLimit.QPS {
+		allErrs = append(allErrs, field.Invalid(reconcilePath.Child("rateLimit", "burst"), c.Reconcile.RateLimit.Burst, "must not be less than rateLimit.qps"))
+	}
+
+	imageScansPath := field.NewPath("imageScans")
+	allErrs = append(allErrs, validatePositiveDuration(imageScansPath.Child("timeout"), c.ImageScans.Timeout)...)
+	allErrs = append(allErrs, validatePositiveDuration(imageScansPath.Child("ttlAfterFinished"), c.ImageScans.TTLAfterFinished)...)
+	for i, pullSecret := range c.ImageScans.ImagePullSecrets {
+		for _, msg := range validation.IsDNS1123Subdomain(pullSecret) {
+			allErrs = append(allErrs, field.Invalid(imageScansPath.Child("imagePullSecrets").Index(i), pullSecret, msg))
+		}
+	}
+
+	allErrs = append(allErrs, c.Scope.validate(field.NewPath("scope"))...)
+	allErrs = append(allErrs, c.Passwords.validate(field.NewPath("passwords"))...)
+
+	auditPath := field.NewPath("audit")
+	if c.Audit.Sink != "" && !contains(supportedAuditSinks, c.Audit.Sink) {
+		allErrs = append(allErrs, field.NotSupported(auditPath.Child("sink"), c.Audit.Sink, supportedAuditSinks))
+	}
+	if c.Audit.Sink == AuditSinkHTTP {
+		if u, err := url.Parse(c.Audit.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(auditPath.Child("url"), c.Audit.URL, "must be an absolute http or https url when audit.sink is http"))
+		}
+	}
+	allErrs = append(allErrs, validatePositiveDuration(auditPath.Child("timeout"), c.Audit.Timeout)...)
+	allErrs = append(allErrs, validatePositiveDuration(auditPath.Child("maxAge"), c.Audit.MaxAge)...)
+	if c.Audit.MaxRecords < 0 {
+		allErrs = append(allErrs, field.Invalid(auditPath.Child("maxRecords"), c.Audit.MaxRecords, "must not be negative"))
+	}
+
+	tracingPath := field.NewPath("tracing")
+	if strings.Contains(c.Tracing.Endpoint, "/") {
+		allErrs = append(allErrs, field.Invalid(tracingPath.Child("endpoint"), c.Tracing.Endpoint, "must be host:port without a scheme or path, use tracing.insecure for http"))
+	}
+	if ratio := c.Tracing.SamplingRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		allErrs = append(allErrs, field.Invalid(tracingPath.Child("samplingRatio"), *ratio, "must be between 0 and 1"))
+	}
+
+	if c.Admin.BindAddress != "" {
+		if _, port, err := net.SplitHostPort(c.Admin.BindAddress); err != nil || port == "" {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("admin", "bindAddress"), c.Admin.BindAddress, "must be host:port or :port"))
+		}
+	}
+
+	overridesPath := field.NewPath("templates", "overrides")
+	for name, path := range c.Templates.Overrides {
+		if !contains(overridableTemplates, name) {
+			allErrs = append(allErrs, field.NotSupported(overridesPath.Key(name), name, overridableTemplates))
+		} else if path == "" {
+			allErrs = append(allErrs, field.Required(overridesPath.Key(name), "must be the path to a template file"))
+		}
+	}
+
+	return allErrs
+}
+
+func (a *AquaConfig) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if a.URL != "" {
+		if u, err := url.Parse(a.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), a.URL, "must be an absolute http or https url"))
+		} else if strings.HasSuffix(a.URL, "/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), a.URL, "must not have a trailing slash"))
+		}
+	}
+
+	if a.AuthMethod != "" && !contains(supportedAuthMethods, a.AuthMethod) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("authMethod"), a.AuthMethod, supportedAuthMethods))
+	}
+
+	tlsPath := fldPath.Child("tls")
+	if a.TLS.MinVersion != "" && !contains(supportedTLSVersions, a.TLS.MinVersion) {
+		allErrs = append(allErrs, field.NotSupported(tlsPath.Child("minVersion"), a.TLS.MinVersion, supportedTLSVersions))
+	}
+	if a.TLS.ClientCertFile != "" && a.TLS.ClientKeyFile == "" {
+		allErrs = append(allErrs, field.Required(tlsPath.Child("clientKeyFile"), "is required when clientCertFile is set"))
+	}
+	if a.TLS.ClientKeyFile != "" && a.TLS.ClientCertFile == "" {
+		allErrs = append(allErrs, field.Required(tlsPath.Child("clientCertFile"), "is required when clientKeyFile is set"))
+	}
+
+	httpPath := fldPath.Child("http")
+	if a.HTTP.ProxyURL != "" {
+		if u, err := url.Parse(a.HTTP.ProxyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(httpPath.Child("proxyURL"), a.HTTP.ProxyURL, "must be an absolute url"))
+		}
+	}
+	allErrs = append(allErrs, validatePositiveDuration(httpPath.Child("connectTimeout"), a.HTTP.ConnectTimeout)...)
+	allErrs = append(allErrs, validatePositiveDuration(httpPath.Child("readTimeout"), a.HTTP.ReadTimeout)...)
+
+	return allErrs
+}
+
+func (p *PasswordsConfig) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if p.Length < MinPasswordLength || p.Length > MaxPasswordLength {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("length"), p.Length, fmt.Sprintf("must be between %v and %v", MinPasswordLength, MaxPasswordLength)))
+	}
+
+	seen := map[string]bool{}
+	for i, class := range p.RequiredClasses {
+		if !contains(passwordClasses, class) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("requiredClasses").Index(i), class, passwordClasses))
+		} else if seen[class] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("requiredClasses").Index(i), class))
+		}
+		seen[class] = true
+	}
+
+	if strings.ContainsAny(p.Symbols, unsafePasswordSymbols) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("symbols"), p.Symbols, "must not contain whitespace or any of "+strings.TrimSpace(unsafePasswordSymbols)))
+	}
+
+	if p.MinEntropyBits != nil {
+		alphabetSize := 0
+		for class := range seen {
+			alphabetSize += map[string]int{"lowercase": 26, "uppercase": 26, "digits": 10, "symbols": len([]rune(p.Symbols))}[class]
+		}
+		if *p.MinEntropyBits < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("minEntropyBits"), *p.MinEntropyBits, "must not be negative"))
+		} else if alphabetSize > 1 && float64(*p.MinEntropyBits) > MaxPasswordLength*math.Log2(float64(alphabetSize)) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("minEntropyBits"), *p.MinEntropyBits, fmt.Sprintf("can not be reached by a password of %v characters of the required classes", MaxPasswordLength)))
+		}
+	}
+
+	return allErrs
+}
+
+func (s *ScopeConfig) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, registry := range s.AllowedRegistries {
+		if strings.TrimSpace(registry) == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("allowedRegistries").Index(i), registry, "must be the name of a registry in aqua"))
+		}
+	}
+	for namespace, registries := range s.NamespaceRegistries {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaceRegistries").Key(namespace), namespace, msg))
+		}
+		for i, registry := range registries {
+			if strings.TrimSpace(registry) == "" {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaceRegistries").Key(namespace).Index(i), registry, "must be the name of a registry in aqua"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func (c *OperatorConfig) validateNamespaces(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	seen := map[string]bool{}
+	for i, namespace := range c.Namespaces.Watch {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("watch").Index(i), namespace, msg))
+		}
+		if seen[namespace] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("watch").Index(i), namespace))
+		}
+		seen[namespace] = true
+	}
+
+	if c.Namespaces.Selector != nil {
+		if len(c.Namespaces.Watch) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("selector"), "may not be used together with namespaces.watch"))
+		}
+		if _, err := metav1.LabelSelectorAsSelector(c.Namespaces.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("selector"), c.Namespaces.Selector, err.Error()))
+		}
+	}
+
+	if c.CacheNamespace != "" && (len(c.Namespaces.Watch) > 0 || c.Namespaces.Selector != nil) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("cacheNamespace"), "may not be used together with namespaces.watch or namespaces.selector"))
+	}
+
+	return allErrs
+}
+
+// Complete defaults and validates the config once it has been read from the file, returning the controller-runtime
+// settings the manager is started with
+func (c *OperatorConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	c.Default()
+	if errs := c.Validate(); len(errs) > 0 {
+		return c.ControllerManagerConfigurationSpec, errs.ToAggregate()
+	}
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+// AccountName renders the naming template for an AquaScannerAccount
+func (n NamingConfig) AccountName(data AccountNameData) (string, error) {
+	t, err := template.New("accountName").Option("missingkey=error").Parse(n.AccountNameTemplate)
+	if err != nil {
+		return "", err
+	}
+	var name bytes.Buffer
+	if err := t.Execute(&name, data); err != nil {
+		return "", err
+	}
+	return name.String(), nil
+}
+
+// returns namespaces.watch along with the extra namespaces the operator always needs, such as the namespace of the
+// credentials secret. Nil means the whole cluster is watched
+func (p NamespacePolicy) WatchedNamespaces(extra ...string) []string {
+	if len(p.Watch) == 0 {
+		return nil
+	}
+
+	namespaces := append([]string{}, p.Watch...)
+	for _, namespace := range extra {
+		if namespace != "" && !contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// returns the registries the AquaScannerAccounts of namespace can list in spec.scope.registries
+func (s ScopeConfig) RegistriesAllowedIn(namespace string) []string {
+	return append(append([]string{}, s.AllowedRegistries...), s.NamespaceRegistries[namespace]...)
+}
+
+// returns true unless the feature has been switched off, unset toggles are on
+func IsEnabled(toggle *bool) bool {
+	return toggle == nil || *toggle
+}
+
+func validatePositiveDuration(fldPath *field.Path, d *metav1.Duration) field.ErrorList {
+	if d != nil && d.Duration <= 0 {
+		return field.ErrorList{field.Invalid(fldPath, d.Duration.String(), "must be greater than 0")}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package v1alpha1
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+)
+
+func loadConfig(t *testing.T, content string) (*OperatorConfig, error) {
+	path := filepath.Join(t.TempDir(), "controller_manager_config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config file %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme %v", err)
+	}
+
+	operatorConfig := &OperatorConfig{}
+	loader := config.File().AtPath(path).OfKind(operatorConfig)
+	loader.InjectScheme(scheme)
+	_, err := loader.Complete()
+	return operatorConfig, err
+}
+
+func TestOperatorConfigDefaults(t *testing.T) {
+	operatorConfig, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+leaderElection:
+  leaderElect: true
+aqua:
+  url: https://aqua.apps.clab.devops.gov.bc.ca
+`)
+	if err != nil {
+		t.Fatalf("a minimal config was supposed to load but got %v", err)
+	}
+
+	if operatorConfig.LeaderElection.LeaderElect == nil || !*operatorConfig.LeaderElection.LeaderElect {
+		t.Errorf("the controller-runtime settings were supposed to be read from the file")
+	}
+
+	if *operatorConfig.Namespaces.RequiredSuffix != "-tools" || operatorConfig.Reconcile.MaxConcurrentReconciles != 4 || operatorConfig.Templates.Directory != "templates" {
+		t.Errorf("unset fields were supposed to be defaulted but got %+v", operatorConfig)
+	}
+
+	if operatorConfig.ImageScans.ScannerImage != DefaultScannerImage || operatorConfig.ImageScans.ReportImage != DefaultReportImage || operatorConfig.ImageScans.TTLAfterFinished.Duration != time.Hour {
+		t.Errorf("imageScans was supposed to be defaulted but got %+v", operatorConfig.ImageScans)
+	}
+
+	if registries := operatorConfig.Scope.RegistriesAllowedIn("abc123-tools"); len(registries) != 1 || registries[0] != DefaultAllowedRegistry {
+		t.Errorf("scope was supposed to only allow Docker Hub but got %v", registries)
+	}
+
+	if operatorConfig.Passwords.Length != 16 || len(operatorConfig.Passwords.RequiredClasses) != 4 || *operatorConfig.Passwords.MinEntropyBits != 80 || !IsEnabled(operatorConfig.Passwords.FollowAquaPolicy) {
+		t.Errorf("passwords was supposed to be defaulted but got %+v", operatorConfig.Passwords)
+	}
+
+	if operatorConfig.Tracing.Endpoint != "" || *operatorConfig.Tracing.SamplingRatio != 1 || operatorConfig.Tracing.ServiceName != DefaultTracingServiceName {
+		t.Errorf("tracing was supposed to be off with its other fields defaulted but got %+v", operatorConfig.Tracing)
+	}
+
+	if operatorConfig.Admin.BindAddress != "" || operatorConfig.Admin.CertDir != DefaultAdminCertDir {
+		t.Errorf("the admin api was supposed to be off with its cert dir defaulted but got %+v", operatorConfig.Admin)
+	}
+
+	if !IsEnabled(operatorConfig.Features.AquaInstances) || !IsEnabled(operatorConfig.Features.Webhooks) || !IsEnabled(operatorConfig.Features.ImageScans) {
+		t.Errorf("features were supposed to default to enabled")
+	}
+
+	name, _ := operatorConfig.Naming.AccountName(AccountNameData{Namespace: "abc123-tools", NamespacePrefix: "abc123"})
+	if name != "ScannerCLI_abc123" {
+		t.Errorf("the default account name was supposed to be ScannerCLI_abc123 but got %v", name)
+	}
+}
+
+func TestOperatorConfigFieldErrors(t *testing.T) {
+	_, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+aqua:
+  url: aqua.apps.clab.devops.gov.bc.ca/
+  authMethod: kerberos
+  tls:
+    minVersion: "2.0"
+    clientCertFile: /etc/aqua/tls.crt
+naming:
+  accountNameTemplate: ScannerCLI_{{ .Cluster }}
+reconcile:
+  maxConcurrentReconciles: -1
+  retry:
+    baseDelay: 10s
+    maxDelay: 1s
+templates:
+  overrides:
+    Group: /templates/Group.json.tmpl
+imageScans:
+  timeout: 0s
+  imagePullSecrets:
+  - Aqua_Registry
+scope:
+  allowedRegistries: [""]
+  namespaceRegistries:
+    ABC123-tools: [Quay]
+passwords:
+  length: 4
+  requiredClasses: [lowercase, emoji, lowercase]
+  symbols: "!<"
+audit:
+  sink: syslog
+  maxRecords: -1
+tracing:
+  endpoint: https://otel-collector:4318
+  samplingRatio: 1.5
+admin:
+  bindAddress: "8443"
+`)
+	if err == nil {
+		t.Fatalf("an invalid config was supposed to fail to load")
+	}
+
+	for _, fieldPath := range []string{
+		"aqua.url",
+		"aqua.authMethod",
+		"aqua.tls.minVersion",
+		"aqua.tls.clientKeyFile",
+		"naming.accountNameTemplate",
+		"reconcile.maxConcurrentReconciles",
+		"reconcile.retry.maxDelay",
+		"templates.overrides[Group]",
+		"imageScans.timeout",
+		"imageScans.imagePullSecrets[0]",
+		"scope.allowedRegistries[0]",
+		"scope.namespaceRegistries[ABC123-tools]",
+		"passwords.length",
+		"passwords.requiredClasses[1]",
+		"passwords.requiredClasses[2]",
+		"passwords.symbols",
+		"audit.sink",
+		"audit.maxRecords",
+		"tracing.endpoint",
+		"tracing.samplingRatio",
+		"admin.bindAddress",
+	} {
+		if !strings.Contains(err.Error(), fieldPath) {
+			t.Errorf("the error was supposed to name the field %v but got %v", fieldPath, err)
+		}
+	}
+}
+
+func TestOperatorConfigPasswordEntropy(t *testing.T) {
+	_, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+passwords:
+  requiredClasses: [digits]
+  minEntropyBits: 500
+`)
+	if err == nil || !strings.Contains(err.Error(), "passwords.minEntropyBits") {
+		t.Errorf("an entropy no password of digits can reach was supposed to be rejected but got %v", err)
+	}
+}
+
+func TestOperatorConfigAuditHTTPSink(t *testing.T) {
+	_, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+audit:
+  sink: http
+`)
+	if err == nil || !strings.Contains(err.Error(), "audit.url") {
+		t.Errorf("the http sink was supposed to need audit.url but got %v", err)
+	}
+
+	operatorConfig, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+audit:
+  sink: http
+  url: https://collector.example.com/audit
+`)
+	if err != nil {
+		t.Fatalf("an http sink with a url was supposed to be valid but got %v", err)
+	}
+	if operatorConfig.Audit.Timeout.Duration != DefaultAuditTimeout || operatorConfig.Audit.MaxRecords != DefaultAuditMaxRecords {
+		t.Errorf("audit was supposed to be defaulted but got %+v", operatorConfig.Audit)
+	}
+}
+
+func TestOperatorConfigAllowsAnyNamespace(t *testing.T) {
+	operatorConfig, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+namespaces:
+  requiredSuffix: ""
+`)
+	if err != nil {
+		t.Fatalf("an empty required suffix was supposed to be valid but got %v", err)
+	}
+
+	if *operatorConfig.Namespaces.RequiredSuffix != "" {
+		t.Errorf("an explicitly empty required suffix was not supposed to be defaulted")
+	}
+}
+
+func TestShippedOperatorConfigIsValid(t *testing.T) {
+	content, err := ioutil.ReadFile("../../../config/manager/controller_manager_config.yaml")
+	if err != nil {
+		t.Fatalf("failed to read the shipped config file %v", err)
+	}
+
+	if _, err := loadConfig(t, string(content)); err != nil {
+		t.Errorf("the config file shipped with the manager was supposed to be valid but got %v", err)
+	}
+}
+
+func TestOperatorConfigWatchNamespaces(t *testing.T) {
+	operatorConfig, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+namespaces:
+  watch:
+  - abc123-tools
+  - def456-tools
+`)
+	if err != nil {
+		t.Fatalf("a list of namespaces was supposed to be valid but got %v", err)
+	}
+
+	namespaces := operatorConfig.Namespaces.WatchedNamespaces("openshift-bcgov-aqua", "abc123-tools")
+	if strings.Join(namespaces, ",") != "abc123-tools,def456-tools,openshift-bcgov-aqua" {
+		t.Errorf("WatchedNamespaces was supposed to add the operator namespace once but got %v", namespaces)
+	}
+
+	if (NamespacePolicy{}).WatchedNamespaces("openshift-bcgov-aqua") != nil {
+		t.Errorf("WatchedNamespaces was supposed to return nil when the whole cluster is watched")
+	}
+
+	_, err = loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+cacheNamespace: abc123-tools
+namespaces:
+  watch:
+  - abc123-tools
+  - Not_A_Namespace
+  - abc123-tools
+  selector:
+    matchExpressions:
+    - key: tenant
+      operator: Sideways
+`)
+	if err == nil {
+		t.Fatalf("invalid namespace settings were supposed to fail to load")
+	}
+
+	for _, fieldPath := range []string{"namespaces.watch[1]", "namespaces.watch[2]", "namespaces.selector", "cacheNamespace"} {
+		if !strings.Contains(err.Error(), fieldPath) {
+			t.Errorf("the error was supposed to name the field %v but got %v", fieldPath, err)
+		}
+	}
+}
+
+func TestOperatorConfigScopeRegistries(t *testing.T) {
+	operatorConfig, err := loadConfig(t, `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+scope:
+  allowedRegistries: [Docker Hub, Quay]
+  namespaceRegistries:
+    abc123-tools: [Artifactory]
+`)
+	if err != nil {
+		t.Fatalf("the config was supposed to load but got %v", err)
+	}
+
+	if registries := operatorConfig.Scope.RegistriesAllowedIn("abc123-tools"); strings.Join(registries, ",") != "Docker Hub,Quay,Artifactory" {
+		t.Errorf("abc123-tools was supposed to be allowed the registries of every namespace and its own but got %v", registries)
+	}
+	if registries := operatorConfig.Scope.RegistriesAllowedIn("def456-tools"); strings.Join(registries, ",") != "Docker Hub,Quay" {
+		t.Errorf("def456-tools was supposed to be allowed the registries of every namespace but got %v", registries)
+	}
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+// names of the templates in the templates directory that can be overridden
+const (
+	ApplicationScopeTemplate = "ApplicationScope"
+	PermissionSetTemplate    = "PermissionSet"
+	RoleTemplate             = "Role"
+	UserTemplate             = "User"
+)
+
+// references the secret holding the operator's own aqua credentials
+type SecretReference struct {
+	// when set the secret is watched and credential changes are picked up without a restart
+	// +optional
+	Name string `json:"name,omitempty"`
+	// defaults to the namespace the manager runs in
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// TLS settings for the operator's own aqua instance. Files are expected to be mounted into the manager pod
+type AquaTLSConfig struct {
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// PEM encoded CA bundle trusted in addition to the system trust store
+	// +optional
+	CABundleFile string `json:"caBundleFile,omitempty"`
+	// PEM encoded client certificate and key presented to aqua for mTLS
+	// +optional
+	ClientCertFile string `json:"clientCertFile,omitempty"`
+	// +optional
+	ClientKeyFile string `json:"clientKeyFile,omitempty"`
+	// one of 1.0, 1.1, 1.2 or 1.3. Defaults to 1.2
+	// +optional
+	MinVersion string `json:"minVersion,omitempty"`
+}
+
+// http transport settings for the operator's own aqua instance
+type AquaHTTPConfig struct {
+	// when unset the manager's HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars are used
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+	// defaults to 10s
+	// +optional
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+	// defaults to 30s
+	// +optional
+	ReadTimeout *metav1.Duration `json:"readTimeout,omitempty"`
+}
+
+// the aqua instance used by AquaScannerAccounts that do not reference an AquaInstance
+type AquaConfig struct {
+	// the base url (no trailing slash) to aqua. Defaults to the AQUA_URL env var, an AQUA_URL key in the
+	// credentials secret takes precedence once the secret has been read
+	// +optional
+	URL string `json:"url,omitempty"`
+	// overrides the AQUA_AUTH_METHOD of the credentials, one of password, token or apiKey
+	// +optional
+	AuthMethod string `json:"authMethod,omitempty"`
+	// +optional
+	CredentialsSecretRef SecretReference `json:"credentialsSecretRef,omitempty"`
+	// +optional
+	TLS AquaTLSConfig `json:"tls,omitempty"`
+	// +optional
+	HTTP AquaHTTPConfig `json:"http,omitempty"`
+}
+
+// decides which namespaces AquaScannerAccounts are reconciled in
+type NamespacePolicy struct {
+	// AquaScannerAccounts are only reconciled in namespaces ending in this suffix, it is also trimmed from the
+	// namespace to give the prefix the application scope is restricted to. Defaults to -tools, an empty string
+	// allows every namespace
+	// +optional
+	RequiredSuffix *string `json:"requiredSuffix,omitempty"`
+	// namespaces the operator watches. When set the cache and controllers are limited to these namespaces, plus the
+	// namespace of the credentials secret, and the operator only needs namespaced RBAC in them. Empty watches the
+	// whole cluster
+	// +optional
+	Watch []string `json:"watch,omitempty"`
+	// only AquaScannerAccounts in namespaces with matching labels are reconciled. The cache stays cluster wide so the
+	// operator still needs cluster wide read access to AquaScannerAccounts and namespaces
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// decides the names of the objects created in aqua
+type NamingConfig struct {
+	// go template for the name shared by the application scope, permission set, role and user of an
+	// AquaScannerAccount. .Namespace and .NamespacePrefix are available. Defaults to ScannerCLI_{{ .NamespacePrefix }}
+	// +optional
+	AccountNameTemplate string `json:"accountNameTemplate,omitempty"`
+}
+
+// backoff used when a reconcile fails
+type RetryConfig struct {
+	// defaults to 5ms
+	// +optional
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+	// defaults to 1000s
+	// +optional
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+}
+
+// overall rate at which AquaScannerAccounts are reconciled, which bounds the requests made to aqua
+type RateLimitConfig struct {
+	// defaults to 10
+	// +optional
+	QPS int `json:"qps,omitempty"`
+	// defaults to 100
+	// +optional
+	Burst int `json:"burst,omitempty"`
+}
+
+type ReconcileConfig struct {
+	// defaults to 4
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+	// +optional
+	Retry RetryConfig `json:"retry,omitempty"`
+	// +optional
+	RateLimit RateLimitConfig `json:"rateLimit,omitempty"`
+	// reconciles every AquaScannerAccount without making changes in aqua. The changes that would be made are written
+	// to status.plan. A single account can be put in dry run mode with the mamoa.devops.gov.bc.ca/dry-run annotation
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// where the json templates posted to aqua are read from
+type TemplatesConfig struct {
+	// directory holding ApplicationScope.json.tmpl, PermissionSet.json.tmpl, Role.json.tmpl and User.json.tmpl.
+	// Relative paths are resolved against the manager's working directory. Defaults to templates
+	// +optional
+	Directory string `json:"directory,omitempty"`
+	// paths to individual templates that replace the one in the directory, keyed by template name
+	// (ApplicationScope, PermissionSet, Role or User)
+	// +optional
+	Overrides map[string]string `json:"overrides,omitempty"`
+}
+
+// how the Jobs of AquaImageScans and the Tekton Tasks of AquaScannerAccounts run scannercli
+type ImageScansConfig struct {
+	// image with scannercli, defaults to registry.aquasec.com/scanner:2022.4
+	// +optional
+	ScannerImage string `json:"scannerImage,omitempty"`
+	// image with kubectl-aqua that gates the report in the Tekton Tasks of AquaScannerAccounts, defaults to the
+	// operator's image
+	// +optional
+	ReportImage string `json:"reportImage,omitempty"`
+	// names of pull secrets for the scanner image, they have to exist in the namespace of every AquaImageScan
+	// +optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// how long a scan may run when the AquaImageScan does not set spec.timeout, defaults to 30m
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// how long the Job of a finished scan is kept when the AquaImageScan does not set spec.ttlAfterFinished,
+	// defaults to 1h
+	// +optional
+	TTLAfterFinished *metav1.Duration `json:"ttlAfterFinished,omitempty"`
+}
+
+// how the passwords of the aqua users of AquaScannerAccounts are generated
+type PasswordsConfig struct {
+	// defaults to 16
+	// +optional
+	Length int `json:"length,omitempty"`
+	// every password holds at least one character of each of these classes and is drawn from their characters alone.
+	// Any of lowercase, uppercase, digits and symbols, defaults to all four
+	// +optional
+	RequiredClasses []string `json:"requiredClasses,omitempty"`
+	// the characters of the symbols class, defaults to !@#$. Characters that have to be escaped in json or html
+	// can not be used
+	// +optional
+	Symbols string `json:"symbols,omitempty"`
+	// passwords are made longer than length until they hold this many bits of entropy, defaults to 80
+	// +optional
+	MinEntropyBits *int `json:"minEntropyBits,omitempty"`
+	// read the password policy of aqua and follow it where it is stricter, defaults to true
+	// +optional
+	FollowAquaPolicy *bool `json:"followAquaPolicy,omitempty"`
+}
+
+// where the POSTs, PUTs and DELETEs the operator sends to aqua are recorded
+type AuditConfig struct {
+	// one of file, stdout or http. Empty turns the audit trail off
+	// +optional
+	Sink string `json:"sink,omitempty"`
+	// the json lines file of the file sink, it should be on a persistent volume. Defaults to
+	// /var/log/aqua-scanner-operator/audit.jsonl
+	// +optional
+	File string `json:"file,omitempty"`
+	// the collector the http sink posts every record to as json
+	// +optional
+	URL string `json:"url,omitempty"`
+	// how long the http sink waits for the collector, defaults to 10s
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// records older than this are dropped from the file, defaults to 2160h (90 days)
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+	// the most records kept in the file, the oldest are dropped first. Defaults to 100000
+	// +optional
+	MaxRecords int `json:"maxRecords,omitempty"`
+}
+
+// where the spans of reconciles and aqua requests are exported to over OTLP/HTTP
+type TracingConfig struct {
+	// host:port of the OTLP/HTTP receiver, e.g. otel-collector.observability:4318. Empty turns tracing off
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// sends the spans over http instead of https
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+	// the fraction of reconciles that are traced, from 0 to 1. Requests that arrive with a sampled trace are always
+	// traced. Defaults to 1
+	// +optional
+	SamplingRatio *float64 `json:"samplingRatio,omitempty"`
+	// the service.name of the spans, defaults to aqua-scanner-operator
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+}
+
+// the read-only http api platform admins export the AquaScannerAccounts and their aqua objects from
+type AdminAPIConfig struct {
+	// address the api is served on over https, e.g. :8443. Empty turns the api off
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+	// directory holding the tls.crt and tls.key the api is served with, they are reloaded when they change.
+	// Defaults to /tmp/k8s-admin-server/serving-certs
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+}
+
+// the registries AquaScannerAccounts can list in spec.scope.registries, an account can scan any image of them
+type ScopeConfig struct {
+	// registries every namespace can list, defaults to Docker Hub, the registry of accounts without spec.scope
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// registries a namespace can list on top of allowedRegistries, by the name of the namespace
+	// +optional
+	NamespaceRegistries map[string][]string `json:"namespaceRegistries,omitempty"`
+}
+
+// switches for optional parts of the operator, all default to true
+type FeatureToggles struct {
+	// +optional
+	AquaInstances *bool `json:"aquaInstances,omitempty"`
+	// +optional
+	CredentialsReload *bool `json:"credentialsReload,omitempty"`
+	// turns off the validating webhook of AquaScannerAccounts, the conversion webhook is always served
+	// +optional
+	Webhooks *bool `json:"webhooks,omitempty"`
+	// +optional
+	ImageScans *bool `json:"imageScans,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OperatorConfig is the Schema for the manager's config file
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// the controller-runtime settings: health, metrics, webhook, leaderElection, syncPeriod and cacheNamespace
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// +optional
+	Aqua AquaConfig `json:"aqua,omitempty"`
+	// +optional
+	Namespaces NamespacePolicy `json:"namespaces,omitempty"`
+	// +optional
+	Naming NamingConfig `json:"naming,omitempty"`
+	// +optional
+	Reconcile ReconcileConfig `json:"reconcile,omitempty"`
+	// +optional
+	Templates TemplatesConfig `json:"templates,omitempty"`
+	// +optional
+	ImageScans ImageScansConfig `json:"imageScans,omitempty"`
+	// +optional
+	Scope ScopeConfig `json:"scope,omitempty"`
+	// +optional
+	Passwords PasswordsConfig `json:"passwords,omitempty"`
+	// +optional
+	Audit AuditConfig `json:"audit,omitempty"`
+	// +optional
+	Tracing TracingConfig `json:"tracing,omitempty"`
+	// +optional
+	Admin AdminAPIConfig `json:"admin,omitempty"`
+	// +optional
+	Features FeatureToggles `json:"features,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{})
+}
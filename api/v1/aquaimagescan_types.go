@@ -0,0 +1,166 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// phases of an AquaImageScan
+const (
+	// waiting for the scanner account or for the Job to start
+	ImageScanPending = "Pending"
+	ImageScanRunning = "Running"
+	// scannercli finished and the image passed
+	ImageScanSucceeded = "Succeeded"
+	// scannercli failed, the image did not pass or the scan timed out
+	ImageScanFailed = "Failed"
+)
+
+// policy results in the report of an AquaImageScan
+const (
+	ImageScanPolicyPass = "Pass"
+	ImageScanPolicyFail = "Fail"
+)
+
+// key of the full scannercli report in the report ConfigMap of an AquaImageScan
+const ImageScanReportKey = "report.json"
+
+// ReportConfigMapName returns the name of the ConfigMap holding the full report of the scan
+func (s *AquaImageScan) ReportConfigMapName() string {
+	return s.Name + "-report"
+}
+
+// AquaImageScanSpec defines the image scannercli scans
+type AquaImageScanSpec struct {
+	// the image to scan, e.g. image-registry.openshift-image-registry.svc:5000/abc123-tools/app:1.0
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// name of the registry the image is pulled from as it is configured in aqua. When unset aqua works the registry
+	// out from the image
+	// +optional
+	Registry string `json:"registry,omitempty"`
+	// the AquaScannerAccount whose credentials are used, defaults to the only AquaScannerAccount in the namespace
+	// +optional
+	AccountRef string `json:"accountRef,omitempty"`
+	// how long the scan may run before it is failed, defaults to imageScans.timeout of the operator config
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// how long the Job is kept after the scan finished, defaults to imageScans.ttlAfterFinished of the operator config
+	// +optional
+	TTLAfterFinished *metav1.Duration `json:"ttlAfterFinished,omitempty"`
+}
+
+// number of vulnerabilities found by severity
+type AquaImageScanSeverityCounts struct {
+	Critical   int `json:"critical"`
+	High       int `json:"high"`
+	Medium     int `json:"medium"`
+	Low        int `json:"low"`
+	Negligible int `json:"negligible"`
+}
+
+type AquaImageScanCVE struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	// the aqua or nvd score, e.g. 9.8
+	// +optional
+	Score string `json:"score,omitempty"`
+	// the package the vulnerability was found in
+	// +optional
+	Resource string `json:"resource,omitempty"`
+	// +optional
+	FixVersion string `json:"fixVersion,omitempty"`
+}
+
+// AquaImageScanReport is a summary of the json report of scannercli
+type AquaImageScanReport struct {
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// Pass or Fail, whether the image passed its image assurance policies
+	Policy string `json:"policy"`
+	// +optional
+	FailedPolicies  []string                    `json:"failedPolicies,omitempty"`
+	Vulnerabilities AquaImageScanSeverityCounts `json:"vulnerabilities"`
+	// the most severe vulnerabilities
+	// +optional
+	TopCVEs []AquaImageScanCVE `json:"topCVEs,omitempty"`
+	// the ConfigMap holding the full report under report.json
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+	// true when the least severe vulnerabilities were left out of the ConfigMap to respect its size limit
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// AquaImageScanStatus defines the observed state of AquaImageScan
+type AquaImageScanStatus struct {
+	// Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// the Job running scannercli
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// true once the Job of the finished scan was deleted after ttlAfterFinished
+	// +optional
+	JobDeleted bool `json:"jobDeleted,omitempty"`
+	// summary of the scannercli report, unset when scannercli did not write one
+	// +optional
+	Report *AquaImageScanReport `json:"report,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=ais
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Policy",type=string,JSONPath=`.status.report.policy`
+//+kubebuilder:printcolumn:name="Critical",type=integer,JSONPath=`.status.report.vulnerabilities.critical`
+//+kubebuilder:printcolumn:name="High",type=integer,JSONPath=`.status.report.vulnerabilities.high`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// AquaImageScan is the Schema for the aquaimagescans API
+type AquaImageScan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AquaImageScanSpec   `json:"spec,omitempty"`
+	Status AquaImageScanStatus `json:"status,omitempty"`
+}
+
+// IsFinished returns true once the scan succeeded or failed
+func (s *AquaImageScan) IsFinished() bool {
+	return s.Status.Phase == ImageScanSucceeded || s.Status.Phase == ImageScanFailed
+}
+
+//+kubebuilder:object:root=true
+
+// AquaImageScanList contains a list of AquaImageScan
+type AquaImageScanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AquaImageScan `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AquaImageScan{}, &AquaImageScanList{})
+}
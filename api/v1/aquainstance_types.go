@@ -0,0 +1,166 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// annotation that marks an AquaInstance as the one used by AquaScannerAccounts that do not set spec.instanceRef
+const DefaultAquaInstanceAnnotation = "mamoa.devops.gov.bc.ca/is-default-instance"
+
+// the condition of an AquaInstance that is True while the operator can not log in to it or does not support it
+const AquaInstanceConditionDegraded = "Degraded"
+
+// references a key within a secret or configmap. AquaInstances are cluster scoped so the namespace is required.
+// The operator only notices a change to the object before the next health check when it has the
+// mamoa.devops.gov.bc.ca/watch: "true" label
+type AquaInstanceKeyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// references a secret. AquaInstances are cluster scoped so the namespace of the secret is required. The operator
+// only notices a change to the secret before the next health check when it has the mamoa.devops.gov.bc.ca/watch: "true"
+// label
+type AquaInstanceSecretReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// TLS settings used when talking to the aqua api
+type AquaInstanceTLS struct {
+	// skips verification of the aqua server certificate, this should only be used for lab instances
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// a PEM encoded CA bundle in a secret that is trusted in addition to the system trust store. Key defaults to ca.crt
+	// +optional
+	CABundleSecretRef *AquaInstanceKeyReference `json:"caBundleSecretRef,omitempty"`
+	// a PEM encoded CA bundle in a configmap that is trusted in addition to the system trust store. Key defaults to ca.crt
+	// +optional
+	CABundleConfigMapRef *AquaInstanceKeyReference `json:"caBundleConfigMapRef,omitempty"`
+	// a kubernetes.io/tls secret holding the client certificate presented to aqua for mTLS
+	// +optional
+	ClientCertificateSecretRef *AquaInstanceSecretReference `json:"clientCertificateSecretRef,omitempty"`
+	// the minimum TLS version accepted from aqua, defaults to 1.2
+	// +kubebuilder:validation:Enum="1.0";"1.1";"1.2";"1.3"
+	// +optional
+	MinVersion string `json:"minVersion,omitempty"`
+}
+
+// http transport settings used when talking to the aqua api
+type AquaInstanceHTTP struct {
+	// proxy used for aqua requests. When unset the manager's HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars are used
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+	// comma separated hosts that bypass the proxy, same format as NO_PROXY
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+	// time allowed to connect to aqua including the TLS handshake, defaults to 10s
+	// +optional
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+	// time allowed for aqua to answer a request once connected, defaults to 30s
+	// +optional
+	ReadTimeout *metav1.Duration `json:"readTimeout,omitempty"`
+}
+
+// AquaInstanceSpec defines the aqua console an AquaScannerAccount is provisioned in
+type AquaInstanceSpec struct {
+	// the base url (no trailing slash) to the aqua instance
+	URL string `json:"url"`
+	// secret holding the same keys as the operator's aqua-scanner-operator-creds secret. AQUA_USER and AQUA_PASSWORD
+	// for password login, AQUA_TOKEN for token auth or AQUA_API_KEY and AQUA_API_SECRET for api key auth
+	CredentialsSecretRef AquaInstanceSecretReference `json:"credentialsSecretRef"`
+	// how the operator authenticates with aqua. Defaults to the AQUA_AUTH_METHOD key of the credentials secret and
+	// then to password
+	// +kubebuilder:validation:Enum=password;token;apiKey
+	// +optional
+	AuthMethod string `json:"authMethod,omitempty"`
+	// +optional
+	TLS AquaInstanceTLS `json:"tls,omitempty"`
+	// +optional
+	HTTP AquaInstanceHTTP `json:"http,omitempty"`
+	// the namespaces AquaScannerAccounts can use the instance from, matched against the labels of the namespace. Every
+	// namespace can use the instance when it is not set
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// AquaInstanceStatus defines the observed state of AquaInstance
+type AquaInstanceStatus struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// the version the aqua instance reported at the last check
+	// +optional
+	AquaVersion string `json:"aquaVersion,omitempty"`
+	// the generation of the aqua api the operator talks to the instance with, empty when its version is not supported
+	// +optional
+	APIGeneration string `json:"apiGeneration,omitempty"`
+	// the Degraded condition, for tools that wait on conditions such as kubectl wait
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=aqi
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+//+kubebuilder:printcolumn:name="Healthy",type=boolean,JSONPath=`.status.healthy`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.aquaVersion`
+// AquaInstance is the Schema for the aquainstances API
+type AquaInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AquaInstanceSpec   `json:"spec,omitempty"`
+	Status AquaInstanceStatus `json:"status,omitempty"`
+}
+
+// IsDefault returns true when the instance is annotated as the cluster default
+func (ai *AquaInstance) IsDefault() bool {
+	return ai.GetAnnotations()[DefaultAquaInstanceAnnotation] == "true"
+}
+
+// AllowsNamespace returns true when spec.namespaceSelector matches the labels of a namespace, or is not set
+func (ai *AquaInstance) AllowsNamespace(namespaceLabels map[string]string) (bool, error) {
+	if ai.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ai.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(namespaceLabels)), nil
+}
+
+//+kubebuilder:object:root=true
+
+// AquaInstanceList contains a list of AquaInstance
+type AquaInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AquaInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AquaInstance{}, &AquaInstanceList{})
+}
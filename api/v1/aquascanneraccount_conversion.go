@@ -0,0 +1,196 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+/*
+	Annotation v1 AquaScannerAccounts keep the fields of v2 in that v1 has no field for, so that an account read and
+	written back as v1 keeps them. It is removed again when the account is converted to v2.
+*/
+const V2ConversionDataAnnotation = "mamoa.devops.gov.bc.ca/v2-conversion-data"
+
+// the fields of a v2 AquaScannerAccount that v1 can not hold
+type v2ConversionData struct {
+	Scope            *v2.AquaScannerAccountScope    `json:"scope,omitempty"`
+	Profile          string                         `json:"profile,omitempty"`
+	Rotation         *v2.AquaScannerAccountRotation `json:"rotation,omitempty"`
+	DeletionPolicy   string                         `json:"deletionPolicy,omitempty"`
+	Conditions       []metav1.Condition             `json:"conditions,omitempty"`
+	LastRotationTime *metav1.Time                   `json:"lastRotationTime,omitempty"`
+	AquaVersion      string                         `json:"aquaVersion,omitempty"`
+}
+
+// ConvertTo converts this AquaScannerAccount to the Hub version (v2). status.accountSecret is not converted, v2 has
+// no field for passwords
+func (src *AquaScannerAccount) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v2.AquaScannerAccount)
+	src = src.DeepCopy()
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = v2.AquaScannerAccountSpec{
+		InstanceRef: src.Spec.InstanceRef,
+		Delivery: v2.AquaScannerAccountDelivery{
+			ServiceAccounts:   src.Spec.ServiceAccounts,
+			CredentialReaders: src.Spec.CredentialReaders,
+		},
+	}
+	if jenkins := src.Spec.Delivery.Jenkins; jenkins != nil {
+		dst.Spec.Delivery.Jenkins = (*v2.AquaScannerAccountJenkinsDelivery)(jenkins)
+	}
+	if tekton := src.Spec.Tekton; tekton != nil {
+		dst.Spec.Delivery.Tekton = (*v2.AquaScannerAccountTekton)(tekton)
+	}
+
+	dst.Status = v2.AquaScannerAccountStatus{
+		State:                 src.Status.State,
+		Message:               src.Status.Message,
+		CurrentState:          v2.AquaScannerAccountAquaObjectState(src.Status.CurrentState),
+		DesiredState:          v2.AquaScannerAccountAquaObjectState(src.Status.DesiredState),
+		AccountName:           src.Status.AccountName,
+		Instance:              src.Status.Instance,
+		LastRotation:          src.Status.LastRotation,
+		LastResync:            src.Status.LastResync,
+		JenkinsSecret:         src.Status.JenkinsSecret,
+		TektonTask:            src.Status.TektonTask,
+		ServiceAccounts:       src.Status.ServiceAccounts,
+		CredentialReadersRole: src.Status.CredentialReadersRole,
+		Timestamp:             src.Status.Timestamp,
+	}
+	if src.Status.CredentialsSecret != "" {
+		dst.Status.CredentialsSecretRef = &corev1.LocalObjectReference{Name: src.Status.CredentialsSecret}
+	}
+	if plan := src.Status.Plan; plan != nil {
+		dst.Status.Plan = &v2.AquaScannerAccountPlan{Error: plan.Error, Timestamp: plan.Timestamp}
+		for _, change := range plan.Changes {
+			converted := v2.AquaPlannedChange{Kind: change.Kind, Action: change.Action, Payload: change.Payload}
+			for _, diff := range change.Diff {
+				converted.Diff = append(converted.Diff, v2.AquaFieldDiff(diff))
+			}
+			dst.Status.Plan.Changes = append(dst.Status.Plan.Changes, converted)
+		}
+	}
+
+	data, ok := dst.Annotations[V2ConversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+
+	delete(dst.Annotations, V2ConversionDataAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	v2Data := v2ConversionData{}
+	if err := json.Unmarshal([]byte(data), &v2Data); err != nil {
+		return err
+	}
+
+	dst.Spec.Scope = v2Data.Scope
+	dst.Spec.Profile = v2Data.Profile
+	if v2Data.Rotation != nil {
+		dst.Spec.Rotation = *v2Data.Rotation
+	}
+	dst.Spec.DeletionPolicy = v2Data.DeletionPolicy
+	dst.Status.Conditions = v2Data.Conditions
+	dst.Status.LastRotationTime = v2Data.LastRotationTime
+	dst.Status.AquaVersion = v2Data.AquaVersion
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v2) to this version v1.
+func (dst *AquaScannerAccount) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v2.AquaScannerAccount).DeepCopy()
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = AquaScannerAccountSpec{
+		InstanceRef:       src.Spec.InstanceRef,
+		ServiceAccounts:   src.Spec.Delivery.ServiceAccounts,
+		CredentialReaders: src.Spec.Delivery.CredentialReaders,
+	}
+	if jenkins := src.Spec.Delivery.Jenkins; jenkins != nil {
+		dst.Spec.Delivery.Jenkins = (*AquaScannerAccountJenkinsDelivery)(jenkins)
+	}
+	if tekton := src.Spec.Delivery.Tekton; tekton != nil {
+		dst.Spec.Tekton = (*AquaScannerAccountTekton)(tekton)
+	}
+
+	dst.Status = AquaScannerAccountStatus{
+		State:                 src.Status.State,
+		Message:               src.Status.Message,
+		CurrentState:          AquaScannerAccountAquaObjectState(src.Status.CurrentState),
+		DesiredState:          AquaScannerAccountAquaObjectState(src.Status.DesiredState),
+		AccountName:           src.Status.AccountName,
+		Instance:              src.Status.Instance,
+		LastRotation:          src.Status.LastRotation,
+		LastResync:            src.Status.LastResync,
+		JenkinsSecret:         src.Status.JenkinsSecret,
+		TektonTask:            src.Status.TektonTask,
+		ServiceAccounts:       src.Status.ServiceAccounts,
+		CredentialReadersRole: src.Status.CredentialReadersRole,
+		Timestamp:             src.Status.Timestamp,
+	}
+	if src.Status.CredentialsSecretRef != nil {
+		dst.Status.CredentialsSecret = src.Status.CredentialsSecretRef.Name
+	}
+	if plan := src.Status.Plan; plan != nil {
+		dst.Status.Plan = &AquaScannerAccountPlan{Error: plan.Error, Timestamp: plan.Timestamp}
+		for _, change := range plan.Changes {
+			converted := AquaPlannedChange{Kind: change.Kind, Action: change.Action, Payload: change.Payload}
+			for _, diff := range change.Diff {
+				converted.Diff = append(converted.Diff, AquaFieldDiff(diff))
+			}
+			dst.Status.Plan.Changes = append(dst.Status.Plan.Changes, converted)
+		}
+	}
+
+	v2Data := v2ConversionData{
+		Scope:            src.Spec.Scope,
+		Profile:          src.Spec.Profile,
+		DeletionPolicy:   src.Spec.DeletionPolicy,
+		Conditions:       src.Status.Conditions,
+		LastRotationTime: src.Status.LastRotationTime,
+		AquaVersion:      src.Status.AquaVersion,
+	}
+	if src.Spec.Rotation.Interval != nil {
+		v2Data.Rotation = &src.Spec.Rotation
+	}
+
+	data, err := json.Marshal(v2Data)
+	if err != nil {
+		return err
+	}
+	if string(data) == "{}" {
+		return nil
+	}
+
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[V2ConversionDataAnnotation] = string(data)
+	return nil
+}
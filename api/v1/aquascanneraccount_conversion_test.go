@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+func TestConvertV2RoundTrip(t *testing.T) {
+	created := v2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", Role: "Created", User: "Created"}
+	rotated := metav1.NewTime(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	account := &v2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools", Annotations: map[string]string{v2.DryRunAnnotation: "true"}},
+		Spec: v2.AquaScannerAccountSpec{
+			InstanceRef: "lab",
+			Scope:       &v2.AquaScannerAccountScope{Registries: []v2.ScopeRegistry{"Quay"}},
+			Profile:     v2.ProfileScanOnly,
+			Rotation:    v2.AquaScannerAccountRotation{Interval: &metav1.Duration{Duration: 720 * time.Hour}},
+			Delivery: v2.AquaScannerAccountDelivery{
+				ServiceAccounts:   []string{"pipeline"},
+				CredentialReaders: []rbacv1.Subject{{Kind: "User", Name: "jane@github"}},
+				Jenkins:           &v2.AquaScannerAccountJenkinsDelivery{CredentialID: "aqua"},
+				Tekton:            &v2.AquaScannerAccountTekton{TaskName: "scan"},
+			},
+			DeletionPolicy: v2.DeletionPolicyRetain,
+		},
+		Status: v2.AquaScannerAccountStatus{
+			State:                "Complete",
+			Conditions:           []metav1.Condition{{Type: v2.ConditionReady, Status: metav1.ConditionTrue, Reason: "Complete", LastTransitionTime: rotated}},
+			CurrentState:         created,
+			DesiredState:         created,
+			AccountName:          "ScannerCLI_abc123",
+			CredentialsSecretRef: &corev1.LocalObjectReference{Name: "scanner-aqua-credentials"},
+			Plan: &v2.AquaScannerAccountPlan{Changes: []v2.AquaPlannedChange{
+				{Kind: "User", Action: "Update", Diff: []v2.AquaFieldDiff{{Field: "role", Current: "a", Desired: "b"}}},
+			}},
+			LastRotation:     "1",
+			LastRotationTime: &rotated,
+			ServiceAccounts:  []string{"pipeline"},
+		},
+	}
+
+	v1Account := &AquaScannerAccount{}
+	if err := v1Account.ConvertFrom(account.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	if v1Account.Spec.Tekton == nil || v1Account.Spec.ServiceAccounts[0] != "pipeline" || v1Account.Status.CredentialsSecret != "scanner-aqua-credentials" {
+		t.Errorf("the fields v1 has were supposed to be converted but got %+v", v1Account)
+	}
+
+	converted := &v2.AquaScannerAccount{}
+	if err := v1Account.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(account, converted) {
+		t.Errorf("converting to v1 and back was supposed to keep the account\n%+v\nbut got\n%+v", account, converted)
+	}
+}
+
+func TestConvertV1RoundTrip(t *testing.T) {
+	account := &AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Spec: AquaScannerAccountSpec{
+			InstanceRef:     "lab",
+			ServiceAccounts: []string{"pipeline"},
+			Tekton:          &AquaScannerAccountTekton{},
+		},
+		Status: AquaScannerAccountStatus{
+			State:             "Complete",
+			AccountName:       "ScannerCLI_abc123",
+			AccountSecret:     "hunter2",
+			CredentialsSecret: "scanner-aqua-credentials",
+			JenkinsSecret:     "scanner-aqua-jenkins",
+		},
+	}
+
+	v2Account := &v2.AquaScannerAccount{}
+	if err := account.DeepCopy().ConvertTo(v2Account); err != nil {
+		t.Fatal(err)
+	}
+	if v2Account.Annotations != nil {
+		t.Errorf("an account without v2 fields was not supposed to be annotated but got %v", v2Account.Annotations)
+	}
+
+	converted := &AquaScannerAccount{}
+	if err := converted.ConvertFrom(v2Account); err != nil {
+		t.Fatal(err)
+	}
+
+	// v2 has no field for the password
+	account.Status.AccountSecret = ""
+	if !equality.Semantic.DeepEqual(account, converted) {
+		t.Errorf("converting to v2 and back was supposed to keep the account\n%+v\nbut got\n%+v", account, converted)
+	}
+}
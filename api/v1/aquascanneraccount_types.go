@@ -17,18 +17,64 @@ limitations under the License.
 package v1
 
 import (
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// writes the scanner credentials to a Secret the OpenShift Jenkins sync plugin turns into a username/password credential
+type AquaScannerAccountJenkinsDelivery struct {
+	// name of the Secret, defaults to <account name>-aqua-jenkins
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// id of the Jenkins credential, the sync plugin uses <namespace>-<secret name> when unset
+	// +optional
+	CredentialID string `json:"credentialID,omitempty"`
+	// description of the credential, defaults to one naming the account and the aqua url
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// where the operator delivers the credentials of the account besides its credentials Secret
+type AquaScannerAccountDelivery struct {
+	// +optional
+	Jenkins *AquaScannerAccountJenkinsDelivery `json:"jenkins,omitempty"`
+}
+
+// creates a Tekton Task in the namespace that scans an image with the account's credentials
+type AquaScannerAccountTekton struct {
+	// name of the Task, defaults to aqua-scan
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	TaskName string `json:"taskName,omitempty"`
+}
+
 // AquaScannerAccountSpec defines the desired state of AquaScannerAccount
 type AquaScannerAccountSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Foo is an example field of AquaScannerAccount. Edit aquascanneraccount_types.go to remove/update
+	// name of the cluster scoped AquaInstance the account is provisioned in. When unset the AquaInstance annotated
+	// as the cluster default is used and if there is no default the operator's own AQUA_URL is used
+	// +optional
+	InstanceRef string `json:"instanceRef,omitempty"`
+	// +optional
+	Delivery AquaScannerAccountDelivery `json:"delivery,omitempty"`
+	// +optional
+	Tekton *AquaScannerAccountTekton `json:"tekton,omitempty"`
+	// ServiceAccounts in the namespace, e.g. pipeline, the credentials Secret is linked to. ServiceAccounts that do not
+	// exist yet are linked once they are created
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// users, groups and ServiceAccounts allowed to read the credentials Secret. The admins of the namespace can read
+	// it whether or not they are listed. The namespace of a ServiceAccount defaults to the account's namespace
+	// +optional
+	CredentialReaders []rbacv1.Subject `json:"credentialReaders,omitempty"`
 }
 
 type AquaObjectState int
@@ -51,23 +97,113 @@ type AquaScannerAccountAquaObjectState struct {
 	Role             string `json:"role"`
 }
 
+// returns the state of the aqua object kind, e.g. Role, or an empty string for an unknown kind
+func (s AquaScannerAccountAquaObjectState) Get(kind string) string {
+	switch kind {
+	case "ApplicationScope":
+		return s.ApplicationScope
+	case "PermissionSet":
+		return s.PermissionSet
+	case "User":
+		return s.User
+	case "Role":
+		return s.Role
+	}
+	return ""
+}
+
+// sets the state of the aqua object kind, unknown kinds are ignored
+func (s *AquaScannerAccountAquaObjectState) Set(kind string, state string) {
+	switch kind {
+	case "ApplicationScope":
+		s.ApplicationScope = state
+	case "PermissionSet":
+		s.PermissionSet = state
+	case "User":
+		s.User = state
+	case "Role":
+		s.Role = state
+	}
+}
+
+// a field of an aqua object that differs from what the operator would send
+type AquaFieldDiff struct {
+	Field string `json:"field"`
+	// json value of the field in aqua
+	Current string `json:"current"`
+	// json value of the field the operator would send
+	Desired string `json:"desired"`
+}
+
+// a change the operator would make to an aqua object
+type AquaPlannedChange struct {
+	// the kind of aqua object, e.g. Role
+	Kind string `json:"kind"`
+	// Create, Update or Delete
+	Action string `json:"action"`
+	// the payload that would be sent for a Create, secrets are redacted
+	// +optional
+	Payload string `json:"payload,omitempty"`
+	// the fields that would change for an Update, fields aqua does not return such as the password are not compared
+	// +optional
+	Diff []AquaFieldDiff `json:"diff,omitempty"`
+}
+
+// the changes the operator would make in aqua if the account was not reconciled in dry run mode
+type AquaScannerAccountPlan struct {
+	// +optional
+	Changes []AquaPlannedChange `json:"changes,omitempty"`
+	// set when the current state of an aqua object could not be read, the plan is incomplete
+	// +optional
+	Error            string `json:"error,omitempty"`
+	metav1.Timestamp `json:"timestamp"`
+}
+
 // AquaScannerAccountStatus defines the observed state of AquaScannerAccount
 type AquaScannerAccountStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	CurrentState     AquaScannerAccountAquaObjectState `json:"currentState"`
-	State            string                            `json:"State"`
-	AccountName      string                            `json:"accountName"`
-	AccountSecret    string                            `json:"accountSecret"`
+	CurrentState AquaScannerAccountAquaObjectState `json:"currentState"`
+	State        string                            `json:"State"`
+	AccountName  string                            `json:"accountName"`
+	// the password of the account as kept by older versions of the operator. It is not converted to v2, the
+	// operator moves it to the credentials Secret
+	// +optional
+	AccountSecret    string `json:"accountSecret,omitempty"`
 	metav1.Timestamp `json:"timestamp"`
 	Message          string                            `json:"message"`
 	DesiredState     AquaScannerAccountAquaObjectState `json:"desiredState"`
+	// the AquaInstance the aqua objects were created in, empty when the operator's own AQUA_URL was used
+	Instance string `json:"instance,omitempty"`
+	// only set while the account is reconciled in dry run mode, nothing in the plan has been applied to aqua
+	// +optional
+	Plan *AquaScannerAccountPlan `json:"plan,omitempty"`
+	// the Secret in the account's namespace holding the aqua url and the credentials of the account
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// the value of the rotate-credentials annotation the current password was generated for
+	// +optional
+	LastRotation string `json:"lastRotation,omitempty"`
+	// the value of the resync annotation that was handled last
+	// +optional
+	LastResync string `json:"lastResync,omitempty"`
+	// the Secret delivered to the OpenShift Jenkins sync plugin, empty when spec.delivery.jenkins is unset
+	// +optional
+	JenkinsSecret string `json:"jenkinsSecret,omitempty"`
+	// the Tekton Task scanning with the account's credentials, empty when spec.tekton is unset
+	// +optional
+	TektonTask string `json:"tektonTask,omitempty"`
+	// the ServiceAccounts the credentials Secret is linked to
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// the Role granting spec.credentialReaders read access to the credentials, empty when there are no readers
+	// +optional
+	CredentialReadersRole string `json:"credentialReadersRole,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:shortName=asa
-//+kubebuilder:storageversion
 // AquaScannerAccount is the Schema for the aquascanneraccounts API
 type AquaScannerAccount struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -89,6 +225,3 @@ type AquaScannerAccountList struct {
 func init() {
 	SchemeBuilder.Register(&AquaScannerAccount{}, &AquaScannerAccountList{})
 }
-
-// Hub marks this type as a conversion hub.
-func (*AquaScannerAccount) Hub() {}
@@ -21,16 +21,414 @@ limitations under the License.
 package v1
 
 import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaFieldDiff) DeepCopyInto(out *AquaFieldDiff) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaFieldDiff.
+func (in *AquaFieldDiff) DeepCopy() *AquaFieldDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaFieldDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScan) DeepCopyInto(out *AquaImageScan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScan.
+func (in *AquaImageScan) DeepCopy() *AquaImageScan {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AquaImageScan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScanCVE) DeepCopyInto(out *AquaImageScanCVE) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScanCVE.
+func (in *AquaImageScanCVE) DeepCopy() *AquaImageScanCVE {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScanCVE)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScanList) DeepCopyInto(out *AquaImageScanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AquaImageScan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScanList.
+func (in *AquaImageScanList) DeepCopy() *AquaImageScanList {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AquaImageScanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScanReport) DeepCopyInto(out *AquaImageScanReport) {
+	*out = *in
+	if in.FailedPolicies != nil {
+		in, out := &in.FailedPolicies, &out.FailedPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Vulnerabilities = in.Vulnerabilities
+	if in.TopCVEs != nil {
+		in, out := &in.TopCVEs, &out.TopCVEs
+		*out = make([]AquaImageScanCVE, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScanReport.
+func (in *AquaImageScanReport) DeepCopy() *AquaImageScanReport {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScanReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScanSeverityCounts) DeepCopyInto(out *AquaImageScanSeverityCounts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScanSeverityCounts.
+func (in *AquaImageScanSeverityCounts) DeepCopy() *AquaImageScanSeverityCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScanSeverityCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScanSpec) DeepCopyInto(out *AquaImageScanSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TTLAfterFinished != nil {
+		in, out := &in.TTLAfterFinished, &out.TTLAfterFinished
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScanSpec.
+func (in *AquaImageScanSpec) DeepCopy() *AquaImageScanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaImageScanStatus) DeepCopyInto(out *AquaImageScanStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Report != nil {
+		in, out := &in.Report, &out.Report
+		*out = new(AquaImageScanReport)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaImageScanStatus.
+func (in *AquaImageScanStatus) DeepCopy() *AquaImageScanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaImageScanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstance) DeepCopyInto(out *AquaInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstance.
+func (in *AquaInstance) DeepCopy() *AquaInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AquaInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceHTTP) DeepCopyInto(out *AquaInstanceHTTP) {
+	*out = *in
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ReadTimeout != nil {
+		in, out := &in.ReadTimeout, &out.ReadTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceHTTP.
+func (in *AquaInstanceHTTP) DeepCopy() *AquaInstanceHTTP {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceHTTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceKeyReference) DeepCopyInto(out *AquaInstanceKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceKeyReference.
+func (in *AquaInstanceKeyReference) DeepCopy() *AquaInstanceKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceList) DeepCopyInto(out *AquaInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AquaInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceList.
+func (in *AquaInstanceList) DeepCopy() *AquaInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AquaInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceSecretReference) DeepCopyInto(out *AquaInstanceSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceSecretReference.
+func (in *AquaInstanceSecretReference) DeepCopy() *AquaInstanceSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceSpec) DeepCopyInto(out *AquaInstanceSpec) {
+	*out = *in
+	out.CredentialsSecretRef = in.CredentialsSecretRef
+	in.TLS.DeepCopyInto(&out.TLS)
+	in.HTTP.DeepCopyInto(&out.HTTP)
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceSpec.
+func (in *AquaInstanceSpec) DeepCopy() *AquaInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceStatus) DeepCopyInto(out *AquaInstanceStatus) {
+	*out = *in
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceStatus.
+func (in *AquaInstanceStatus) DeepCopy() *AquaInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaInstanceTLS) DeepCopyInto(out *AquaInstanceTLS) {
+	*out = *in
+	if in.CABundleSecretRef != nil {
+		in, out := &in.CABundleSecretRef, &out.CABundleSecretRef
+		*out = new(AquaInstanceKeyReference)
+		**out = **in
+	}
+	if in.CABundleConfigMapRef != nil {
+		in, out := &in.CABundleConfigMapRef, &out.CABundleConfigMapRef
+		*out = new(AquaInstanceKeyReference)
+		**out = **in
+	}
+	if in.ClientCertificateSecretRef != nil {
+		in, out := &in.ClientCertificateSecretRef, &out.ClientCertificateSecretRef
+		*out = new(AquaInstanceSecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaInstanceTLS.
+func (in *AquaInstanceTLS) DeepCopy() *AquaInstanceTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaInstanceTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaPlannedChange) DeepCopyInto(out *AquaPlannedChange) {
+	*out = *in
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = make([]AquaFieldDiff, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaPlannedChange.
+func (in *AquaPlannedChange) DeepCopy() *AquaPlannedChange {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaPlannedChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AquaScannerAccount) DeepCopyInto(out *AquaScannerAccount) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccount.
@@ -66,6 +464,41 @@ func (in *AquaScannerAccountAquaObjectState) DeepCopy() *AquaScannerAccountAquaO
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountDelivery) DeepCopyInto(out *AquaScannerAccountDelivery) {
+	*out = *in
+	if in.Jenkins != nil {
+		in, out := &in.Jenkins, &out.Jenkins
+		*out = new(AquaScannerAccountJenkinsDelivery)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountDelivery.
+func (in *AquaScannerAccountDelivery) DeepCopy() *AquaScannerAccountDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountJenkinsDelivery) DeepCopyInto(out *AquaScannerAccountJenkinsDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountJenkinsDelivery.
+func (in *AquaScannerAccountJenkinsDelivery) DeepCopy() *AquaScannerAccountJenkinsDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountJenkinsDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AquaScannerAccountList) DeepCopyInto(out *AquaScannerAccountList) {
 	*out = *in
@@ -98,9 +531,48 @@ func (in *AquaScannerAccountList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountPlan) DeepCopyInto(out *AquaScannerAccountPlan) {
+	*out = *in
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]AquaPlannedChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.Timestamp = in.Timestamp
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountPlan.
+func (in *AquaScannerAccountPlan) DeepCopy() *AquaScannerAccountPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AquaScannerAccountSpec) DeepCopyInto(out *AquaScannerAccountSpec) {
 	*out = *in
+	in.Delivery.DeepCopyInto(&out.Delivery)
+	if in.Tekton != nil {
+		in, out := &in.Tekton, &out.Tekton
+		*out = new(AquaScannerAccountTekton)
+		**out = **in
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialReaders != nil {
+		in, out := &in.CredentialReaders, &out.CredentialReaders
+		*out = make([]rbacv1.Subject, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountSpec.
@@ -119,6 +591,16 @@ func (in *AquaScannerAccountStatus) DeepCopyInto(out *AquaScannerAccountStatus)
 	out.CurrentState = in.CurrentState
 	out.Timestamp = in.Timestamp
 	out.DesiredState = in.DesiredState
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(AquaScannerAccountPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountStatus.
@@ -130,3 +612,18 @@ func (in *AquaScannerAccountStatus) DeepCopy() *AquaScannerAccountStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountTekton) DeepCopyInto(out *AquaScannerAccountTekton) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountTekton.
+func (in *AquaScannerAccountTekton) DeepCopy() *AquaScannerAccountTekton {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountTekton)
+	in.DeepCopyInto(out)
+	return out
+}
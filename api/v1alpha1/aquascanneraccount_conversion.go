@@ -14,22 +14,75 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+
 	v1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 )
 
-// ConvertTo converts this AquaScannerAccount to the Hub version (v1).
+/*
+	Annotation v1alpha1 AquaScannerAccounts keep the spec and status of v1 in, so that an account read and written back
+	as v1alpha1 keeps them. It is removed again when the account is converted to v1. The password is left out, v1alpha1
+	has its own field for it.
+*/
+const V1ConversionDataAnnotation = "mamoa.devops.gov.bc.ca/v1-conversion-data"
+
+// the fields of a v1 AquaScannerAccount that v1alpha1 can not hold
+type v1ConversionData struct {
+	Spec   v1.AquaScannerAccountSpec   `json:"spec"`
+	Status v1.AquaScannerAccountStatus `json:"status"`
+}
+
+// ConvertTo converts this AquaScannerAccount to the Hub version (v2) by way of v1.
+func (src *AquaScannerAccount) ConvertTo(dstRaw conversion.Hub) error {
+	v1Account := &v1.AquaScannerAccount{}
+	if err := src.convertToV1(v1Account); err != nil {
+		return err
+	}
+	return v1Account.ConvertTo(dstRaw)
+}
+
+// ConvertFrom converts from the Hub version (v2) to this version v1alpha1 by way of v1.
+func (dst *AquaScannerAccount) ConvertFrom(srcRaw conversion.Hub) error {
+	v1Account := &v1.AquaScannerAccount{}
+	if err := v1Account.ConvertFrom(srcRaw); err != nil {
+		return err
+	}
+	return dst.convertFromV1(v1Account)
+}
+
 // src = v1alpha1
 // dst = v1
-func (src *AquaScannerAccount) ConvertTo(dstRaw conversion.Hub) error {
-	dst := dstRaw.(*v1.AquaScannerAccount)
-	dst.Name = src.Name
-	dst.Namespace = src.Namespace
-	dst.UID = src.UID
+func (src *AquaScannerAccount) convertToV1(dst *v1.AquaScannerAccount) error {
+	// the metadata carries the annotation holding the fields of v2
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	data, restored := dst.Annotations[V1ConversionDataAnnotation]
+	if restored {
+		delete(dst.Annotations, V1ConversionDataAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+
+		v1Data := v1ConversionData{}
+		if err := json.Unmarshal([]byte(data), &v1Data); err != nil {
+			return err
+		}
+		dst.Spec = v1Data.Spec
+		dst.Status = v1Data.Status
+	}
+
 	state := src.Status.CurrentState
 	dst.Status.Timestamp = src.Status.Timestamp
 	dst.Status.AccountName = src.Status.AccountName
 	dst.Status.AccountSecret = src.Status.AccountSecret
+
+	// the state of the objects is restored as it was, unless a v1alpha1 client changed the state in the meantime
+	if restored && dst.Status.State == state {
+		return nil
+	}
+
 	dst.Status.State = state
 	// ***not setting DesiredState as controller handles that and updates accordingly
 
@@ -46,19 +99,32 @@ func (src *AquaScannerAccount) ConvertTo(dstRaw conversion.Hub) error {
 		dst.Status.Message = "Reconcilliation not complete"
 		dst.Status.CurrentState = v1.AquaScannerAccountAquaObjectState{ApplicationScope: v1.NotCreated.String(), Role: v1.NotCreated.String(), PermissionSet: v1.NotCreated.String(), User: v1.NotCreated.String()}
 	}
-
 	return nil
 }
 
-// ConvertFrom converts from the Hub version (v1) to this version v1alpha1.
-func (dst *AquaScannerAccount) ConvertFrom(srcRaw conversion.Hub) error {
-	src := srcRaw.(*v1.AquaScannerAccount)
-	dst.Name = src.Name
-	dst.Namespace = src.Namespace
-	dst.UID = src.UID
+// src = v1
+// dst = v1alpha1
+func (dst *AquaScannerAccount) convertFromV1(src *v1.AquaScannerAccount) error {
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
 	dst.Status.Timestamp = src.Status.Timestamp
 	dst.Status.AccountName = src.Status.AccountName
 	dst.Status.AccountSecret = src.Status.AccountSecret
 	dst.Status.CurrentState = src.Status.State
+
+	v1Data := v1ConversionData{Spec: *src.Spec.DeepCopy(), Status: *src.Status.DeepCopy()}
+	// the fields v1alpha1 holds are not kept twice, so the password is never written to the annotation
+	v1Data.Status.Timestamp = metav1.Timestamp{}
+	v1Data.Status.AccountName = ""
+	v1Data.Status.AccountSecret = ""
+
+	data, err := json.Marshal(v1Data)
+	if err != nil {
+		return err
+	}
+
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[V1ConversionDataAnnotation] = string(data)
 	return nil
 }
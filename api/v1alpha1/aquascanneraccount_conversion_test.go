@@ -0,0 +1,147 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	v2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// converts account to v1alpha1 and back, failing the test when either conversion fails
+func roundTripV1(t *testing.T, account *v1.AquaScannerAccount) *v1.AquaScannerAccount {
+	t.Helper()
+
+	v1alpha1Account := &AquaScannerAccount{}
+	if err := v1alpha1Account.convertFromV1(account.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+
+	converted := &v1.AquaScannerAccount{}
+	if err := v1alpha1Account.convertToV1(converted); err != nil {
+		t.Fatal(err)
+	}
+	return converted
+}
+
+func TestConvertV1RoundTripEveryCurrentState(t *testing.T) {
+	objectStates := []string{"", v1.Created.String(), v1.NotCreated.String()}
+	created := v1.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", Role: "Created", User: "Created"}
+
+	for _, applicationScope := range objectStates {
+		for _, permissionSet := range objectStates {
+			for _, role := range objectStates {
+				for _, user := range objectStates {
+					for _, state := range []string{"", "Running", "Complete", "Failed"} {
+						account := &v1.AquaScannerAccount{
+							ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+							Spec:       v1.AquaScannerAccountSpec{InstanceRef: "lab", ServiceAccounts: []string{"pipeline"}},
+							Status: v1.AquaScannerAccountStatus{
+								State:        state,
+								Message:      "Reconcilliation failed. aqua returned 500. Will re-attempt.",
+								CurrentState: v1.AquaScannerAccountAquaObjectState{ApplicationScope: applicationScope, PermissionSet: permissionSet, Role: role, User: user},
+								DesiredState: created,
+								AccountName:  "ScannerCLI_abc123",
+							},
+						}
+
+						if converted := roundTripV1(t, account); !equality.Semantic.DeepEqual(account, converted) {
+							t.Errorf("converting to v1alpha1 and back was supposed to keep the account\n%+v\nbut got\n%+v", account.Status, converted.Status)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConvertV1RoundTripFuzz(t *testing.T) {
+	fuzzer := fuzz.New().NilChance(0.2).Funcs(
+		// only the metadata the conversion reads and writes
+		func(meta *metav1.ObjectMeta, c fuzz.Continue) {
+			c.Fuzz(&meta.Name)
+			c.Fuzz(&meta.Namespace)
+			c.Fuzz(&meta.Annotations)
+		},
+	)
+
+	for i := 0; i < 1000; i++ {
+		account := &v1.AquaScannerAccount{}
+		fuzzer.Fuzz(account)
+		account.TypeMeta = metav1.TypeMeta{}
+
+		if converted := roundTripV1(t, account); !equality.Semantic.DeepEqual(account, converted) {
+			t.Fatalf("converting to v1alpha1 and back was supposed to keep the account\n%+v\nbut got\n%+v", account, converted)
+		}
+	}
+}
+
+func TestConvertV2RoundTripThroughV1alpha1(t *testing.T) {
+	account := &v2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Spec: v2.AquaScannerAccountSpec{
+			InstanceRef:    "lab",
+			Profile:        v2.ProfileScanOnly,
+			Delivery:       v2.AquaScannerAccountDelivery{ServiceAccounts: []string{"pipeline"}, Tekton: &v2.AquaScannerAccountTekton{}},
+			DeletionPolicy: v2.DeletionPolicyRetain,
+		},
+		Status: v2.AquaScannerAccountStatus{
+			State:        "Failed",
+			Message:      "Reconcilliation failed. aqua returned 500. Will re-attempt.",
+			CurrentState: v2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", Role: "NotCreated", User: "NotCreated"},
+			DesiredState: v2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", Role: "Created", User: "Created"},
+			AccountName:  "ScannerCLI_abc123",
+		},
+	}
+
+	v1alpha1Account := &AquaScannerAccount{}
+	if err := v1alpha1Account.ConvertFrom(account.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+
+	converted := &v2.AquaScannerAccount{}
+	if err := v1alpha1Account.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(account, converted) {
+		t.Errorf("converting to v1alpha1 and back was supposed to keep the account\n%+v\nbut got\n%+v", account, converted)
+	}
+}
+
+func TestConvertV1ChangedState(t *testing.T) {
+	account := &v1.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Status: v1.AquaScannerAccountStatus{
+			State:         "Complete",
+			Message:       "Reconcilliation Successful!",
+			CurrentState:  v1.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", Role: "Created", User: "Created"},
+			AccountSecret: "hunter2",
+		},
+	}
+
+	v1alpha1Account := &AquaScannerAccount{}
+	if err := v1alpha1Account.convertFromV1(account); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(v1alpha1Account.Annotations[V1ConversionDataAnnotation], "hunter2") {
+		t.Errorf("the password was not supposed to be written to the annotation but got %v", v1alpha1Account.Annotations)
+	}
+
+	// a v1alpha1 client that changes the state has the rest of the status follow it
+	v1alpha1Account.Status.CurrentState = "Failed"
+
+	converted := &v1.AquaScannerAccount{}
+	if err := v1alpha1Account.convertToV1(converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Status.State != "Failed" || converted.Status.Message != "Reconcilliation Failed" || converted.Status.CurrentState.User != v1.NotCreated.String() {
+		t.Errorf("the status was supposed to follow the changed state but got %+v", converted.Status)
+	}
+	if converted.Status.AccountSecret != "hunter2" || converted.Annotations != nil {
+		t.Errorf("the password was supposed to be kept and the annotation removed but got %+v", converted)
+	}
+}
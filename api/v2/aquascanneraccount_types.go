@@ -0,0 +1,442 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// annotation that puts a single AquaScannerAccount in dry run mode, the changes to aqua are planned in status.plan
+// but not made
+const DryRunAnnotation = "mamoa.devops.gov.bc.ca/dry-run"
+
+/*
+	Annotations a team sets, for example with kubectl aqua rotate, to have the operator generate a new password for the
+	account or re-apply its aqua objects. A request is handled once per value, a timestamp is used so it can be repeated.
+*/
+const RotateCredentialsAnnotation = "mamoa.devops.gov.bc.ca/rotate-credentials"
+const ResyncAnnotation = "mamoa.devops.gov.bc.ca/resync"
+
+// keys of the Secret the operator keeps the credentials of an AquaScannerAccount in
+const (
+	CredentialsSecretURLKey      = "AQUA_URL"
+	CredentialsSecretUserKey     = "SCANNER_USER"
+	CredentialsSecretPasswordKey = "SCANNER_PASSWORD"
+)
+
+// annotation on the ServiceAccounts linked to the credentials Secret of an AquaScannerAccount, it holds the Secret's name
+const ServiceAccountCredentialsAnnotation = "mamoa.devops.gov.bc.ca/aqua-credentials"
+
+// label the OpenShift Jenkins sync plugin looks for on Secrets it turns into Jenkins credentials
+const JenkinsCredentialSyncLabel = "credential.sync.jenkins.openshift.io"
+
+// annotation the OpenShift Jenkins sync plugin reads the id of the Jenkins credential from, <namespace>-<secret> otherwise
+const JenkinsCredentialNameAnnotation = "jenkins.openshift.io/secret.name"
+
+// the permissions an account gets in aqua
+const (
+	// the aqua UI and scanning, the default
+	ProfileScanner = "Scanner"
+	// scanning with scannercli only, without the aqua UI
+	ProfileScanOnly = "ScanOnly"
+)
+
+// what happens to the aqua objects of an account when it is deleted
+const (
+	// the aqua objects are deleted with the account, the default
+	DeletionPolicyDelete = "Delete"
+	// the aqua objects are left in aqua
+	DeletionPolicyRetain = "Retain"
+)
+
+// the registry, by its name in aqua, an account can scan any image of when spec.scope is unset
+const DefaultScopeRegistry = "Docker Hub"
+
+// the shortest spec.rotation.interval that is accepted
+const MinRotationInterval = time.Hour
+
+// condition types of AquaScannerAccounts
+const (
+	// the aqua objects exist and the credentials Secret holds a working password
+	ConditionReady = "Ready"
+)
+
+// the images an account can scan besides the repositories of its namespace's project in the cluster's registries
+type AquaScannerAccountScope struct {
+	// names in aqua of registries the account can scan any image of
+	// +optional
+	Registries []ScopeRegistry `json:"registries,omitempty"`
+}
+
+// name of a registry in aqua, e.g. Docker Hub. Characters the aqua templates would have to escape are not allowed
+// +kubebuilder:validation:MinLength=1
+// +kubebuilder:validation:MaxLength=253
+// +kubebuilder:validation:Pattern=`^[^"\\<>&']+$`
+type ScopeRegistry string
+
+// generating new passwords for the account
+type AquaScannerAccountRotation struct {
+	// how often a new password is generated, e.g. 720h. The password is only rotated on request when unset
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// writes the scanner credentials to a Secret the OpenShift Jenkins sync plugin turns into a username/password credential
+type AquaScannerAccountJenkinsDelivery struct {
+	// name of the Secret, defaults to <account name>-aqua-jenkins
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// id of the Jenkins credential, the sync plugin uses <namespace>-<secret name> when unset
+	// +optional
+	CredentialID string `json:"credentialID,omitempty"`
+	// description of the credential, defaults to one naming the account and the aqua url
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// creates a Tekton Task in the namespace that scans an image with the account's credentials
+type AquaScannerAccountTekton struct {
+	// name of the Task, defaults to aqua-scan
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	TaskName string `json:"taskName,omitempty"`
+}
+
+// who gets the credentials of the account besides the admins of the namespace, who can read the credentials Secret
+type AquaScannerAccountDelivery struct {
+	// ServiceAccounts in the namespace, e.g. pipeline, the credentials Secret is linked to. ServiceAccounts that do not
+	// exist yet are linked once they are created
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// users, groups and ServiceAccounts allowed to read the credentials Secret. The namespace of a ServiceAccount
+	// defaults to the account's namespace
+	// +optional
+	CredentialReaders []rbacv1.Subject `json:"credentialReaders,omitempty"`
+	// +optional
+	Jenkins *AquaScannerAccountJenkinsDelivery `json:"jenkins,omitempty"`
+	// +optional
+	Tekton *AquaScannerAccountTekton `json:"tekton,omitempty"`
+}
+
+// AquaScannerAccountSpec defines the desired state of AquaScannerAccount
+type AquaScannerAccountSpec struct {
+	// name of the cluster scoped AquaInstance the account is provisioned in. When unset the AquaInstance annotated
+	// as the cluster default is used and if there is no default the operator's own AQUA_URL is used
+	// +optional
+	InstanceRef string `json:"instanceRef,omitempty"`
+	// the images the account can scan, Docker Hub is in scope when unset
+	// +optional
+	Scope *AquaScannerAccountScope `json:"scope,omitempty"`
+	// the permissions of the account in aqua, defaults to Scanner
+	// +kubebuilder:validation:Enum=Scanner;ScanOnly
+	// +optional
+	Profile string `json:"profile,omitempty"`
+	// +optional
+	Rotation AquaScannerAccountRotation `json:"rotation,omitempty"`
+	// +optional
+	Delivery AquaScannerAccountDelivery `json:"delivery,omitempty"`
+	// whether the aqua objects are deleted with the account, defaults to Delete
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+type AquaObjectState int
+
+const (
+	Created AquaObjectState = iota
+	NotCreated
+)
+
+func (aosm AquaObjectState) String() string {
+	return []string{"Created", "Not Created"}[aosm]
+}
+
+// defines a more finely grained desired state for the CR when interacting with aqua api
+// values of these properties should be like "Created" "Not Created"
+type AquaScannerAccountAquaObjectState struct {
+	ApplicationScope string `json:"applicationScope"`
+	PermissionSet    string `json:"permissionSet"`
+	User             string `json:"user"`
+	Role             string `json:"role"`
+}
+
+// returns the state of the aqua object kind, e.g. Role, or an empty string for an unknown kind
+func (s AquaScannerAccountAquaObjectState) Get(kind string) string {
+	switch kind {
+	case "ApplicationScope":
+		return s.ApplicationScope
+	case "PermissionSet":
+		return s.PermissionSet
+	case "User":
+		return s.User
+	case "Role":
+		return s.Role
+	}
+	return ""
+}
+
+// sets the state of the aqua object kind, unknown kinds are ignored
+func (s *AquaScannerAccountAquaObjectState) Set(kind string, state string) {
+	switch kind {
+	case "ApplicationScope":
+		s.ApplicationScope = state
+	case "PermissionSet":
+		s.PermissionSet = state
+	case "User":
+		s.User = state
+	case "Role":
+		s.Role = state
+	}
+}
+
+// a field of an aqua object that differs from what the operator would send
+type AquaFieldDiff struct {
+	Field string `json:"field"`
+	// json value of the field in aqua
+	Current string `json:"current"`
+	// json value of the field the operator would send
+	Desired string `json:"desired"`
+}
+
+// a change the operator would make to an aqua object
+type AquaPlannedChange struct {
+	// the kind of aqua object, e.g. Role
+	Kind string `json:"kind"`
+	// Create, Update or Delete
+	Action string `json:"action"`
+	// the payload that would be sent for a Create, secrets are redacted
+	// +optional
+	Payload string `json:"payload,omitempty"`
+	// the fields that would change for an Update, fields aqua does not return such as the password are not compared
+	// +optional
+	Diff []AquaFieldDiff `json:"diff,omitempty"`
+}
+
+// the changes the operator would make in aqua if the account was not reconciled in dry run mode
+type AquaScannerAccountPlan struct {
+	// +optional
+	Changes []AquaPlannedChange `json:"changes,omitempty"`
+	// set when the current state of an aqua object could not be read, the plan is incomplete
+	// +optional
+	Error            string `json:"error,omitempty"`
+	metav1.Timestamp `json:"timestamp"`
+}
+
+// AquaScannerAccountStatus defines the observed state of AquaScannerAccount
+type AquaScannerAccountStatus struct {
+	// Running, Complete or Failed
+	// +optional
+	State string `json:"state,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// +optional
+	CurrentState AquaScannerAccountAquaObjectState `json:"currentState,omitempty"`
+	// +optional
+	DesiredState AquaScannerAccountAquaObjectState `json:"desiredState,omitempty"`
+	// the name of the user in aqua
+	// +optional
+	AccountName string `json:"accountName,omitempty"`
+	// the Secret in the account's namespace holding the aqua url and the credentials of the account
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+	// the AquaInstance the aqua objects were created in, empty when the operator's own AQUA_URL was used
+	// +optional
+	Instance string `json:"instance,omitempty"`
+	// the version of aqua the aqua objects were last reconciled in
+	// +optional
+	AquaVersion string `json:"aquaVersion,omitempty"`
+	// only set while the account is reconciled in dry run mode, nothing in the plan has been applied to aqua
+	// +optional
+	Plan *AquaScannerAccountPlan `json:"plan,omitempty"`
+	// the value of the rotate-credentials annotation the current password was generated for
+	// +optional
+	LastRotation string `json:"lastRotation,omitempty"`
+	// when the current password was generated
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// the value of the resync annotation that was handled last
+	// +optional
+	LastResync string `json:"lastResync,omitempty"`
+	// the Secret delivered to the OpenShift Jenkins sync plugin, empty when spec.delivery.jenkins is unset
+	// +optional
+	JenkinsSecret string `json:"jenkinsSecret,omitempty"`
+	// the Tekton Task scanning with the account's credentials, empty when spec.delivery.tekton is unset
+	// +optional
+	TektonTask string `json:"tektonTask,omitempty"`
+	// the ServiceAccounts the credentials Secret is linked to
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// the Role granting spec.delivery.credentialReaders read access to the credentials, empty when there are no readers
+	// +optional
+	CredentialReadersRole string `json:"credentialReadersRole,omitempty"`
+	// when the status was last written
+	metav1.Timestamp `json:"timestamp"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=asa
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Account",type=string,JSONPath=`.status.accountName`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// AquaScannerAccount is the Schema for the aquascanneraccounts API
+type AquaScannerAccount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AquaScannerAccountSpec   `json:"spec,omitempty"`
+	Status AquaScannerAccountStatus `json:"status,omitempty"`
+}
+
+// IsDryRun returns true when the account is annotated to be reconciled in dry run mode
+func (a *AquaScannerAccount) IsDryRun() bool {
+	return a.GetAnnotations()[DryRunAnnotation] == "true"
+}
+
+// IsReady returns true when every aqua object of the account has been created
+func (a *AquaScannerAccount) IsReady() bool {
+	return a.Status.State == "Complete" && a.Status.CurrentState == a.Status.DesiredState
+}
+
+/*
+	SpecChanged returns true when the spec changed since the account was last reconciled, which the Ready condition
+	records in its observedGeneration. Accounts that have no Ready condition yet are not taken as changed.
+*/
+func (a *AquaScannerAccount) SpecChanged() bool {
+	ready := meta.FindStatusCondition(a.Status.Conditions, ConditionReady)
+	return ready != nil && ready.ObservedGeneration != a.Generation
+}
+
+// CredentialsSecretName returns the name of the Secret the operator keeps the account's credentials in
+func (a *AquaScannerAccount) CredentialsSecretName() string {
+	return a.Name + "-aqua-credentials"
+}
+
+// CredentialReadersRoleName returns the name of the Role and RoleBinding granting the credential readers read access
+func (a *AquaScannerAccount) CredentialReadersRoleName() string {
+	return a.Name + "-aqua-credentials-reader"
+}
+
+// JenkinsSecretName returns the name of the Secret delivered to Jenkins, or an empty string when Jenkins delivery is off
+func (a *AquaScannerAccount) JenkinsSecretName() string {
+	jenkins := a.Spec.Delivery.Jenkins
+	if jenkins == nil {
+		return ""
+	}
+	if jenkins.SecretName != "" {
+		return jenkins.SecretName
+	}
+	return a.Name + "-aqua-jenkins"
+}
+
+// TektonTaskName returns the name of the account's Tekton Task, or an empty string when spec.delivery.tekton is unset
+func (a *AquaScannerAccount) TektonTaskName() string {
+	tekton := a.Spec.Delivery.Tekton
+	if tekton == nil {
+		return ""
+	}
+	if tekton.TaskName != "" {
+		return tekton.TaskName
+	}
+	return "aqua-scan"
+}
+
+// ScopeRegistries returns the registries the account can scan any image of
+func (a *AquaScannerAccount) ScopeRegistries() []string {
+	if a.Spec.Scope == nil {
+		return []string{DefaultScopeRegistry}
+	}
+
+	registries := []string{}
+	for _, registry := range a.Spec.Scope.Registries {
+		registries = append(registries, string(registry))
+	}
+	return registries
+}
+
+// ProfileOrDefault returns spec.profile, or Scanner when it is unset
+func (a *AquaScannerAccount) ProfileOrDefault() string {
+	if a.Spec.Profile == "" {
+		return ProfileScanner
+	}
+	return a.Spec.Profile
+}
+
+// RetainsAquaObjects returns true when the aqua objects are left in aqua when the account is deleted
+func (a *AquaScannerAccount) RetainsAquaObjects() bool {
+	return a.Spec.DeletionPolicy == DeletionPolicyRetain
+}
+
+// RotationRequested returns true when the rotate-credentials annotation has a value that was not handled yet
+func (a *AquaScannerAccount) RotationRequested() bool {
+	rotation := a.GetAnnotations()[RotateCredentialsAnnotation]
+	return rotation != "" && rotation != a.Status.LastRotation
+}
+
+/*
+	Returns how long until spec.rotation.interval is up for the current password, counted from its generation or from
+	the creation of the account when that is unknown. The second return is false when no interval is set.
+*/
+func (a *AquaScannerAccount) RotationDueIn(now time.Time) (time.Duration, bool) {
+	interval := a.Spec.Rotation.Interval
+	if interval == nil {
+		return 0, false
+	}
+
+	generated := a.CreationTimestamp.Time
+	if a.Status.LastRotationTime != nil {
+		generated = a.Status.LastRotationTime.Time
+	}
+	return generated.Add(interval.Duration).Sub(now), true
+}
+
+// ResyncRequested returns true when the resync annotation has a value that was not handled yet
+func (a *AquaScannerAccount) ResyncRequested() bool {
+	resync := a.GetAnnotations()[ResyncAnnotation]
+	return resync != "" && resync != a.Status.LastResync
+}
+
+//+kubebuilder:object:root=true
+
+// AquaScannerAccountList contains a list of AquaScannerAccount
+type AquaScannerAccountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AquaScannerAccount `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AquaScannerAccount{}, &AquaScannerAccountList{})
+}
+
+// Hub marks this type as a conversion hub.
+func (*AquaScannerAccount) Hub() {}
@@ -0,0 +1,71 @@
+package v2
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRotationDueIn(t *testing.T) {
+	created := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	account := &AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", CreationTimestamp: metav1.Time{Time: created}}}
+
+	if _, rotates := account.RotationDueIn(created); rotates {
+		t.Errorf("an account without spec.rotation.interval was not supposed to rotate")
+	}
+
+	account.Spec.Rotation.Interval = &metav1.Duration{Duration: 24 * time.Hour}
+
+	// the creation of the account counts when the password's generation is unknown
+	if dueIn, _ := account.RotationDueIn(created.Add(time.Hour)); dueIn != 23*time.Hour {
+		t.Errorf("the rotation was supposed to be due in 23h but got %v", dueIn)
+	}
+
+	account.Status.LastRotationTime = &metav1.Time{Time: created.Add(48 * time.Hour)}
+
+	if dueIn, _ := account.RotationDueIn(created.Add(60 * time.Hour)); dueIn != 12*time.Hour {
+		t.Errorf("the rotation was supposed to be due 12h after the last one but got %v", dueIn)
+	}
+	if dueIn, _ := account.RotationDueIn(created.Add(73 * time.Hour)); dueIn > 0 {
+		t.Errorf("the rotation was supposed to be overdue but got %v", dueIn)
+	}
+}
+
+func TestScopeRegistries(t *testing.T) {
+	account := &AquaScannerAccount{}
+
+	if registries := account.ScopeRegistries(); len(registries) != 1 || registries[0] != DefaultScopeRegistry {
+		t.Errorf("an account without spec.scope was supposed to scan Docker Hub but got %v", registries)
+	}
+
+	account.Spec.Scope = &AquaScannerAccountScope{}
+	if registries := account.ScopeRegistries(); len(registries) != 0 {
+		t.Errorf("an empty scope was supposed to have no registries but got %v", registries)
+	}
+
+	account.Spec.Scope.Registries = []ScopeRegistry{"Docker Hub", "Quay"}
+	if registries := account.ScopeRegistries(); len(registries) != 2 || registries[1] != "Quay" {
+		t.Errorf("the registries of spec.scope were supposed to be used but got %v", registries)
+	}
+}
+
+func TestSpecChanged(t *testing.T) {
+	account := &AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Generation: 2}}
+
+	if account.SpecChanged() {
+		t.Errorf("an account without a Ready condition was not supposed to be taken as changed")
+	}
+
+	account.Status.Conditions = []metav1.Condition{{Type: ConditionReady, Status: metav1.ConditionTrue, ObservedGeneration: 2}}
+	if account.SpecChanged() {
+		t.Errorf("an account reconciled at its generation was not supposed to be taken as changed")
+	}
+
+	// spec.scope was changed after the account was Complete
+	account.Generation = 3
+	account.Spec.Scope = &AquaScannerAccountScope{Registries: []ScopeRegistry{"Quay"}}
+	if !account.SpecChanged() {
+		t.Errorf("an account whose generation is ahead of its Ready condition was supposed to be taken as changed")
+	}
+}
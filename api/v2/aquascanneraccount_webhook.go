@@ -0,0 +1,339 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+)
+
+// log is for logging in this package.
+var aquascanneraccountlog = logf.Log.WithName("aquascanneraccount-resource")
+
+// the paths the webhooks are served at, see the kubebuilder:webhook marker below and the webhook patch of the CRD
+const (
+	validatingWebhookPath = "/validate-mamoa-devops-gov-bc-ca-v2-aquascanneraccount"
+	conversionWebhookPath = "/convert"
+)
+
+/*
+	SetupWebhookWithManager registers the validating webhook and the conversion webhook of every served version. The
+	validating webhook is served by validator, which checks the account against what the operator config allows on
+	top of the checks of the account itself. Without a validator only the conversion webhook is registered, the API
+	server can not serve v1 and v1alpha1 without it.
+*/
+func (r *AquaScannerAccount) SetupWebhookWithManager(mgr ctrl.Manager, validator *AquaScannerAccountValidator) error {
+	if validator == nil {
+		mgr.GetWebhookServer().Register(conversionWebhookPath, &conversion.Webhook{})
+		return nil
+	}
+
+	// registered first, the builder then leaves the path to it and only registers the conversion webhook
+	mgr.GetWebhookServer().Register(validatingWebhookPath, &webhook.Admission{Handler: validator})
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-mamoa-devops-gov-bc-ca-v2-aquascanneraccount,mutating=false,failurePolicy=fail,sideEffects=None,groups=mamoa.devops.gov.bc.ca,resources=aquascanneraccounts,verbs=create;update,versions=v2,name=vaquascanneraccount.kb.io,admissionReviewVersions=v1
+
+/*
+	Requests for v1 and v1alpha1 are converted to v2 before they are validated. Admission webhooks are not called for
+	reads, so the credentials are kept out of the AquaScannerAccount instead: the password lives in the credentials
+	Secret, which only the admins of the namespace and spec.delivery.credentialReaders can read, and v2 has no field
+	to hold it.
+*/
+var _ webhook.Validator = &AquaScannerAccount{}
+
+// ValidateCreate implements webhook.Validator
+func (r *AquaScannerAccount) ValidateCreate() error {
+	aquascanneraccountlog.V(1).Info("validate create", "name", r.Name, "namespace", r.Namespace)
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *AquaScannerAccount) ValidateUpdate(old runtime.Object) error {
+	aquascanneraccountlog.V(1).Info("validate update", "name", r.Name, "namespace", r.Namespace)
+	return r.toInvalid(r.validateSpec())
+}
+
+// ValidateDelete implements webhook.Validator, deletes are not validated
+func (r *AquaScannerAccount) ValidateDelete() error {
+	return nil
+}
+
+func (r *AquaScannerAccount) validateSpec() field.ErrorList {
+	allErrs := r.validateCredentialReaders()
+
+	if interval := r.Spec.Rotation.Interval; interval != nil && interval.Duration < MinRotationInterval {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "rotation", "interval"), interval.Duration.String(), "must be at least "+MinRotationInterval.String()))
+	}
+	return allErrs
+}
+
+// the subjects of spec.delivery.credentialReaders have to be valid RoleBinding subjects
+func (r *AquaScannerAccount) validateCredentialReaders() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, subject := range r.Spec.Delivery.CredentialReaders {
+		path := field.NewPath("spec", "delivery", "credentialReaders").Index(i)
+
+		if subject.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+		}
+
+		switch subject.Kind {
+		case rbacv1.ServiceAccountKind:
+			if subject.APIGroup != "" {
+				allErrs = append(allErrs, field.Invalid(path.Child("apiGroup"), subject.APIGroup, "ServiceAccounts are in the core api group"))
+			}
+		case rbacv1.UserKind, rbacv1.GroupKind:
+			if subject.APIGroup != "" && subject.APIGroup != rbacv1.GroupName {
+				allErrs = append(allErrs, field.Invalid(path.Child("apiGroup"), subject.APIGroup, "users and groups are in the "+rbacv1.GroupName+" api group"))
+			}
+			if subject.Namespace != "" {
+				allErrs = append(allErrs, field.Invalid(path.Child("namespace"), subject.Namespace, "users and groups are not namespaced"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(path.Child("kind"), subject.Kind, []string{rbacv1.UserKind, rbacv1.GroupKind, rbacv1.ServiceAccountKind}))
+		}
+	}
+	return allErrs
+}
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:object:generate=false
+
+// AquaScannerAccountValidator validates AquaScannerAccounts against the operator config and the access of the user
+type AquaScannerAccountValidator struct {
+	// reviews the access of the user changing spec.delivery.credentialReaders
+	Client client.Client
+	// returns the registries the accounts of a namespace can list in spec.scope.registries
+	AllowedRegistries func(namespace string) []string
+	// returns true when the AquaInstance allows accounts in the namespace, a missing instance is a NotFound error
+	InstanceAllowsNamespace func(ctx context.Context, instance string, namespace string) (bool, error)
+
+	decoder *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &AquaScannerAccountValidator{}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *AquaScannerAccountValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle runs the checks of the account itself, then the checks of the operator config
+func (v *AquaScannerAccountValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	account := &AquaScannerAccount{}
+	if err := v.decoder.DecodeRaw(req.Object, account); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var err error
+	old := &AquaScannerAccount{}
+	if req.Operation == admissionv1.Create {
+		err = account.ValidateCreate()
+	} else {
+		if decodeErr := v.decoder.DecodeRaw(req.OldObject, old); decodeErr != nil {
+			return admission.Errored(http.StatusBadRequest, decodeErr)
+		}
+		err = account.ValidateUpdate(old)
+	}
+	if err == nil {
+		allErrs := v.validateRegistries(account, old)
+
+		instanceErrs, instanceErr := v.validateInstanceRef(ctx, account, old)
+		if instanceErr != nil {
+			return admission.Errored(http.StatusInternalServerError, instanceErr)
+		}
+		allErrs = append(allErrs, instanceErrs...)
+
+		readerErrs, reviewErr := v.reviewCredentialReaders(ctx, req.UserInfo, account, old)
+		if reviewErr != nil {
+			return admission.Errored(http.StatusInternalServerError, reviewErr)
+		}
+		err = account.toInvalid(append(allErrs, readerErrs...))
+	}
+
+	if err != nil {
+		var apiStatus apierrors.APIStatus
+		if goerrors.As(err, &apiStatus) {
+			status := apiStatus.Status()
+			return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+		}
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+/*
+	The registries of spec.scope have to be allowed in the namespace of the account, the account can scan any image
+	of them. Registries the account listed before they were disallowed are kept, so that the operator can still update
+	the account, e.g. to add its finalizer.
+*/
+func (v *AquaScannerAccountValidator) validateRegistries(account *AquaScannerAccount, old *AquaScannerAccount) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if v.AllowedRegistries == nil || account.Spec.Scope == nil {
+		return allErrs
+	}
+
+	allowed := map[string]bool{}
+	for _, registry := range v.AllowedRegistries(account.Namespace) {
+		allowed[registry] = true
+	}
+	if old.Spec.Scope != nil {
+		for _, registry := range old.Spec.Scope.Registries {
+			allowed[string(registry)] = true
+		}
+	}
+
+	for i, registry := range account.Spec.Scope.Registries {
+		if !allowed[string(registry)] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "scope", "registries").Index(i), "the registry "+string(registry)+" is not allowed in the namespace "+account.Namespace+" by the operator config"))
+		}
+	}
+	return allErrs
+}
+
+/*
+	The AquaInstance of spec.instanceRef has to allow accounts in the namespace of the account, so a namespace can only
+	use the aqua consoles meant for it. An instance that does not exist yet is left to the operator, which reports it in
+	the status, and an instanceRef that did not change is not checked again so the operator can still update the account.
+*/
+func (v *AquaScannerAccountValidator) validateInstanceRef(ctx context.Context, account *AquaScannerAccount, old *AquaScannerAccount) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	instance := account.Spec.InstanceRef
+	if v.InstanceAllowsNamespace == nil || instance == "" || instance == old.Spec.InstanceRef {
+		return allErrs, nil
+	}
+
+	allowed, err := v.InstanceAllowsNamespace(ctx, instance, account.Namespace)
+	if apierrors.IsNotFound(err) {
+		return allErrs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "instanceRef"), "the AquaInstance "+instance+" does not allow AquaScannerAccounts in the namespace "+account.Namespace))
+	}
+	return allErrs, nil
+}
+
+/*
+	The operator binds spec.delivery.credentialReaders to a Role reading the credentials Secret, so whoever adds a
+	reader grants it that access. As with a RoleBinding of their own, this is only allowed to users who can read the
+	Secret themselves and create RoleBindings in the namespace, which are the admins of the namespace by default.
+	Readers that were listed before are not reviewed again, so the operator can still update the account.
+*/
+func (v *AquaScannerAccountValidator) reviewCredentialReaders(ctx context.Context, user authenticationv1.UserInfo, account *AquaScannerAccount, old *AquaScannerAccount) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	if v.Client == nil {
+		return allErrs, nil
+	}
+
+	granted := []int{}
+	for i, reader := range account.Spec.Delivery.CredentialReaders {
+		if !containsSubject(old.Spec.Delivery.CredentialReaders, reader) {
+			granted = append(granted, i)
+		}
+	}
+	if len(granted) == 0 {
+		return allErrs, nil
+	}
+
+	for _, attributes := range []authorizationv1.ResourceAttributes{
+		{Namespace: account.Namespace, Verb: "get", Resource: "secrets", Name: account.CredentialsSecretName()},
+		{Namespace: account.Namespace, Verb: "create", Group: rbacv1.GroupName, Resource: "rolebindings"},
+	} {
+		allowed, err := v.reviewAccess(ctx, user, attributes)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			continue
+		}
+		for _, i := range granted {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "delivery", "credentialReaders").Index(i),
+				user.Username+" can not "+attributes.Verb+" "+attributes.Resource+" in the namespace "+account.Namespace+", only those who can read the credentials Secret and create RoleBindings can add credential readers"))
+		}
+		break
+	}
+	return allErrs, nil
+}
+
+// returns true when user is allowed what attributes describe
+func (v *AquaScannerAccountValidator) reviewAccess(ctx context.Context, user authenticationv1.UserInfo, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               user.Username,
+			UID:                user.UID,
+			Groups:             user.Groups,
+			Extra:              extra,
+			ResourceAttributes: &attributes,
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+func containsSubject(subjects []rbacv1.Subject, subject rbacv1.Subject) bool {
+	for _, s := range subjects {
+		if s == subject {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *AquaScannerAccount) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AquaScannerAccount").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,240 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidateCredentialReaders(t *testing.T) {
+	account := &AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Spec: AquaScannerAccountSpec{Delivery: AquaScannerAccountDelivery{CredentialReaders: []rbacv1.Subject{
+			{Kind: "User", Name: "jane@github"},
+			{Kind: "Group", APIGroup: rbacv1.GroupName, Name: "abc123-admins"},
+			{Kind: "ServiceAccount", Name: "pipeline"},
+		}}},
+	}
+	if err := account.ValidateCreate(); err != nil {
+		t.Errorf("valid readers were supposed to be accepted but got %v", err)
+	}
+
+	account.Spec.Delivery.CredentialReaders = []rbacv1.Subject{
+		{Kind: "Team", Name: "abc123"},
+		{Kind: "ServiceAccount", APIGroup: rbacv1.GroupName, Name: "pipeline"},
+		{Kind: "User", Namespace: "abc123-tools"},
+	}
+	err := account.ValidateCreate()
+	if err == nil {
+		t.Fatalf("invalid readers were supposed to be denied")
+	}
+	for _, fieldPath := range []string{"spec.delivery.credentialReaders[0].kind", "spec.delivery.credentialReaders[1].apiGroup", "spec.delivery.credentialReaders[2].name", "spec.delivery.credentialReaders[2].namespace"} {
+		if !strings.Contains(err.Error(), fieldPath) {
+			t.Errorf("the error was supposed to name the field %v but got %v", fieldPath, err)
+		}
+	}
+}
+
+func TestValidateRotationInterval(t *testing.T) {
+	old := &AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"}}
+
+	updated := old.DeepCopy()
+	updated.Spec.Rotation.Interval = &metav1.Duration{Duration: 720 * time.Hour}
+	if err := updated.ValidateUpdate(old); err != nil {
+		t.Errorf("a monthly rotation was supposed to be accepted but got %v", err)
+	}
+
+	updated.Spec.Rotation.Interval = &metav1.Duration{Duration: time.Minute}
+	if err := updated.ValidateUpdate(old); err == nil || !strings.Contains(err.Error(), "spec.rotation.interval") {
+		t.Errorf("rotating every minute was supposed to be denied but got %v", err)
+	}
+}
+
+// an admission request for account, old is the account before an update
+func admissionRequest(t *testing.T, operation admissionv1.Operation, account *AquaScannerAccount, old *AquaScannerAccount) admission.Request {
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: operation, Namespace: account.Namespace}}
+	raw, err := json.Marshal(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Object.Raw = raw
+	if old != nil {
+		if req.OldObject.Raw, err = json.Marshal(old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return req
+}
+
+func newTestValidator(t *testing.T, allowed map[string][]string) *AquaScannerAccountValidator {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := &AquaScannerAccountValidator{AllowedRegistries: func(namespace string) []string {
+		return append([]string{DefaultScopeRegistry}, allowed[namespace]...)
+	}}
+	if err := validator.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+	return validator
+}
+
+func TestValidateScopeRegistries(t *testing.T) {
+	validator := newTestValidator(t, map[string][]string{"abc123-tools": {"Quay"}})
+
+	account := &AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"}}
+	account.Spec.Scope = &AquaScannerAccountScope{Registries: []ScopeRegistry{DefaultScopeRegistry, "Quay"}}
+	if response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, account, nil)); !response.Allowed {
+		t.Errorf("the registries allowed in the namespace were supposed to be accepted but got %+v", response.Result)
+	}
+
+	other := account.DeepCopy()
+	other.Namespace = "def456-tools"
+	response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, other, nil))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.scope.registries[1]") {
+		t.Errorf("a registry that is not allowed in the namespace was supposed to be denied but got %+v", response.Result)
+	}
+
+	// a registry that was disallowed after the account listed it is kept, so the account can still be updated
+	updated := other.DeepCopy()
+	updated.Finalizers = []string{"mamoa.devops.gov.bc.ca/finalizer"}
+	if response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, other)); !response.Allowed {
+		t.Errorf("a registry the account listed before was supposed to be kept but got %+v", response.Result)
+	}
+
+	updated.Spec.Scope.Registries = append(updated.Spec.Scope.Registries, "GHCR")
+	response = validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, other))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.scope.registries[2]") {
+		t.Errorf("a registry added by an update was supposed to be checked but got %+v", response.Result)
+	}
+
+	// the checks of the account itself are run as well
+	invalid := account.DeepCopy()
+	invalid.Spec.Rotation.Interval = &metav1.Duration{Duration: time.Minute}
+	response = validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, invalid, nil))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.rotation.interval") {
+		t.Errorf("an invalid account was supposed to be denied but got %+v", response.Result)
+	}
+}
+
+// answers SubjectAccessReviews from the "user verb resource" entries it allows
+type fakeAccessReviewer struct {
+	client.Client
+	allowed map[string]bool
+	reviews int
+}
+
+func (r *fakeAccessReviewer) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review := obj.(*authorizationv1.SubjectAccessReview)
+	attributes := review.Spec.ResourceAttributes
+	review.Status.Allowed = r.allowed[review.Spec.User+" "+attributes.Verb+" "+attributes.Resource]
+	r.reviews++
+	return nil
+}
+
+func TestValidateCredentialReadersGrant(t *testing.T) {
+	reviewer := &fakeAccessReviewer{allowed: map[string]bool{
+		"admin get secrets": true, "admin create rolebindings": true,
+		"developer get secrets": true,
+	}}
+	validator := newTestValidator(t, nil)
+	validator.Client = reviewer
+
+	account := &AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"}}
+	account.Spec.Delivery.CredentialReaders = []rbacv1.Subject{{Kind: "Group", Name: "system:authenticated"}}
+
+	request := func(operation admissionv1.Operation, account *AquaScannerAccount, old *AquaScannerAccount, username string) admission.Request {
+		req := admissionRequest(t, operation, account, old)
+		req.UserInfo = authenticationv1.UserInfo{Username: username}
+		return req
+	}
+
+	if response := validator.Handle(context.Background(), request(admissionv1.Create, account, nil, "admin")); !response.Allowed {
+		t.Errorf("an admin of the namespace was supposed to be allowed to add readers but got %+v", response.Result)
+	}
+
+	// the developer can read the secret but not bind roles, so can not give others access to it
+	response := validator.Handle(context.Background(), request(admissionv1.Create, account, nil, "developer"))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.delivery.credentialReaders[0]") {
+		t.Errorf("a user who can not create RoleBindings was supposed to be denied adding readers but got %+v", response.Result)
+	}
+
+	// readers listed before are not reviewed again, so the operator can still update the account
+	updated := account.DeepCopy()
+	updated.Finalizers = []string{"mamoa.devops.gov.bc.ca/finalizer"}
+	reviewer.reviews = 0
+	if response := validator.Handle(context.Background(), request(admissionv1.Update, updated, account, "system:serviceaccount:aqua:operator")); !response.Allowed || reviewer.reviews != 0 {
+		t.Errorf("an update that adds no readers was supposed to be allowed without a review but got %+v after %v reviews", response.Result, reviewer.reviews)
+	}
+
+	updated.Spec.Delivery.CredentialReaders = append(updated.Spec.Delivery.CredentialReaders, rbacv1.Subject{Kind: "User", Name: "mallory"})
+	response = validator.Handle(context.Background(), request(admissionv1.Update, updated, account, "developer"))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.delivery.credentialReaders[1]") || strings.Contains(response.Result.Message, "credentialReaders[0]") {
+		t.Errorf("only the reader added by the update was supposed to be denied but got %+v", response.Result)
+	}
+}
+
+func TestValidateInstanceRef(t *testing.T) {
+	validator := newTestValidator(t, nil)
+	validator.InstanceAllowsNamespace = func(ctx context.Context, instance string, namespace string) (bool, error) {
+		switch instance {
+		case "lab":
+			return namespace == "abc123-tools", nil
+		case "production":
+			return true, nil
+		}
+		return false, apierrors.NewNotFound(GroupVersion.WithResource("aquainstances").GroupResource(), instance)
+	}
+
+	account := &AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"}}
+	account.Spec.InstanceRef = "lab"
+	if response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, account, nil)); !response.Allowed {
+		t.Errorf("an instance that allows the namespace was supposed to be accepted but got %+v", response.Result)
+	}
+
+	other := account.DeepCopy()
+	other.Namespace = "def456-tools"
+	response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, other, nil))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.instanceRef") {
+		t.Errorf("an instance that does not allow the namespace was supposed to be denied but got %+v", response.Result)
+	}
+
+	// the operator reports an instance that does not exist in the status of the account
+	missing := other.DeepCopy()
+	missing.Spec.InstanceRef = "staging"
+	if response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, missing, nil)); !response.Allowed {
+		t.Errorf("an instance that does not exist was supposed to be left to the operator but got %+v", response.Result)
+	}
+
+	// an instanceRef that did not change is not checked again, so the operator can still update the account
+	updated := other.DeepCopy()
+	updated.Finalizers = []string{"mamoa.devops.gov.bc.ca/finalizer"}
+	if response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, other)); !response.Allowed {
+		t.Errorf("an update that keeps the instanceRef was supposed to be allowed but got %+v", response.Result)
+	}
+
+	previous := other.DeepCopy()
+	previous.Spec.InstanceRef = "production"
+	response = validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, other, previous))
+	if response.Allowed || !strings.Contains(response.Result.Message, "spec.instanceRef") {
+		t.Errorf("moving to an instance that does not allow the namespace was supposed to be denied but got %+v", response.Result)
+	}
+}
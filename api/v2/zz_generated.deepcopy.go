@@ -0,0 +1,331 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaFieldDiff) DeepCopyInto(out *AquaFieldDiff) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaFieldDiff.
+func (in *AquaFieldDiff) DeepCopy() *AquaFieldDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaFieldDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaPlannedChange) DeepCopyInto(out *AquaPlannedChange) {
+	*out = *in
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = make([]AquaFieldDiff, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaPlannedChange.
+func (in *AquaPlannedChange) DeepCopy() *AquaPlannedChange {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaPlannedChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccount) DeepCopyInto(out *AquaScannerAccount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccount.
+func (in *AquaScannerAccount) DeepCopy() *AquaScannerAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AquaScannerAccount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountAquaObjectState) DeepCopyInto(out *AquaScannerAccountAquaObjectState) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountAquaObjectState.
+func (in *AquaScannerAccountAquaObjectState) DeepCopy() *AquaScannerAccountAquaObjectState {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountAquaObjectState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountDelivery) DeepCopyInto(out *AquaScannerAccountDelivery) {
+	*out = *in
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialReaders != nil {
+		in, out := &in.CredentialReaders, &out.CredentialReaders
+		*out = make([]rbacv1.Subject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Jenkins != nil {
+		in, out := &in.Jenkins, &out.Jenkins
+		*out = new(AquaScannerAccountJenkinsDelivery)
+		**out = **in
+	}
+	if in.Tekton != nil {
+		in, out := &in.Tekton, &out.Tekton
+		*out = new(AquaScannerAccountTekton)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountDelivery.
+func (in *AquaScannerAccountDelivery) DeepCopy() *AquaScannerAccountDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountJenkinsDelivery) DeepCopyInto(out *AquaScannerAccountJenkinsDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountJenkinsDelivery.
+func (in *AquaScannerAccountJenkinsDelivery) DeepCopy() *AquaScannerAccountJenkinsDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountJenkinsDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountList) DeepCopyInto(out *AquaScannerAccountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AquaScannerAccount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountList.
+func (in *AquaScannerAccountList) DeepCopy() *AquaScannerAccountList {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AquaScannerAccountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountPlan) DeepCopyInto(out *AquaScannerAccountPlan) {
+	*out = *in
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]AquaPlannedChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.Timestamp = in.Timestamp
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountPlan.
+func (in *AquaScannerAccountPlan) DeepCopy() *AquaScannerAccountPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountRotation) DeepCopyInto(out *AquaScannerAccountRotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountRotation.
+func (in *AquaScannerAccountRotation) DeepCopy() *AquaScannerAccountRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountScope) DeepCopyInto(out *AquaScannerAccountScope) {
+	*out = *in
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]ScopeRegistry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountScope.
+func (in *AquaScannerAccountScope) DeepCopy() *AquaScannerAccountScope {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountSpec) DeepCopyInto(out *AquaScannerAccountSpec) {
+	*out = *in
+	if in.Scope != nil {
+		in, out := &in.Scope, &out.Scope
+		*out = new(AquaScannerAccountScope)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Rotation.DeepCopyInto(&out.Rotation)
+	in.Delivery.DeepCopyInto(&out.Delivery)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountSpec.
+func (in *AquaScannerAccountSpec) DeepCopy() *AquaScannerAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountStatus) DeepCopyInto(out *AquaScannerAccountStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.CurrentState = in.CurrentState
+	out.DesiredState = in.DesiredState
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(AquaScannerAccountPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Timestamp = in.Timestamp
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountStatus.
+func (in *AquaScannerAccountStatus) DeepCopy() *AquaScannerAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AquaScannerAccountTekton) DeepCopyInto(out *AquaScannerAccountTekton) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AquaScannerAccountTekton.
+func (in *AquaScannerAccountTekton) DeepCopy() *AquaScannerAccountTekton {
+	if in == nil {
+		return nil
+	}
+	out := new(AquaScannerAccountTekton)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// rotate and resync ask the operator to act on an account by setting one of its request annotations to the time now
+type annotateCommand struct {
+	name        string
+	annotation  string
+	description string
+	done        string
+}
+
+func newRotateCommand() *annotateCommand {
+	return &annotateCommand{
+		name:        "rotate",
+		annotation:  asav2.RotateCredentialsAnnotation,
+		description: "Have the operator generate a new password for an AquaScannerAccount",
+		done:        "password rotation requested",
+	}
+}
+
+func newResyncCommand() *annotateCommand {
+	return &annotateCommand{
+		name:        "resync",
+		annotation:  asav2.ResyncAnnotation,
+		description: "Have the operator re-apply the aqua objects of an AquaScannerAccount",
+		done:        "resync requested",
+	}
+}
+
+func (c *annotateCommand) Usage() string { return c.name + " [NAME]" }
+
+func (c *annotateCommand) Description() string { return c.description }
+
+func (c *annotateCommand) AddFlags(flags *pflag.FlagSet) {}
+
+func (c *annotateCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	account, err := p.getAccount(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{c.annotation: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+
+	if err := p.client.Patch(ctx, account, client.RawPatch(client.Merge.Type(), patch)); err != nil {
+		return err
+	}
+
+	return p.print(summarize(account), func(w io.Writer) {
+		fmt.Fprintf(w, "aquascanneraccount/%v %v, follow it with kubectl aqua events %v --watch\n", account.Name, c.done, account.Name)
+	})
+}
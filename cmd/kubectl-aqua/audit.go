@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+/*
+	audit queries the json lines audit trail the operator writes with audit.sink set to file, e.g. after copying it
+	out of the manager pod with kubectl cp, or piped from the logs of the stdout sink.
+*/
+type auditCommand struct {
+	account     string
+	since       time.Duration
+	reconcileID string
+	object      string
+	method      string
+	outcome     string
+}
+
+func (c *auditCommand) Usage() string { return "audit FILE|- [--account NS[/NAME]]" }
+
+func (c *auditCommand) Description() string {
+	return "Query the audit trail of the changes the operator made in aqua"
+}
+
+func (c *auditCommand) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.account, "account", "", "Only changes made for the AquaScannerAccounts in this namespace, or for NAMESPACE/NAME.")
+	flags.DurationVar(&c.since, "since", 0, "Only changes made within this duration, e.g. 24h.")
+	flags.StringVar(&c.reconcileID, "reconcile-id", "", "Only changes made by this reconcile.")
+	flags.StringVar(&c.object, "object", "", "Only changes to this kind of aqua object: ApplicationScope, PermissionSet, Role or User.")
+	flags.StringVar(&c.method, "method", "", "Only POST, PUT or DELETE requests.")
+	flags.StringVar(&c.outcome, "outcome", "", "Only changes with this outcome: Succeeded, Failed or Error.")
+}
+
+// the audit trail is a file, the cluster is not needed
+func (c *auditCommand) NeedsCluster(args []string) bool { return false }
+
+func (c *auditCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected the audit trail file or - for stdin but got %v", args)
+	}
+
+	query := utils.AuditQuery{ReconcileID: c.reconcileID, AquaObject: c.object, Method: strings.ToUpper(c.method), Outcome: c.outcome}
+	if c.account != "" {
+		parts := strings.SplitN(c.account, "/", 2)
+		query.Namespace = parts[0]
+		if len(parts) == 2 {
+			query.Name = parts[1]
+		}
+	}
+	if c.since > 0 {
+		query.Since = time.Now().Add(-c.since)
+	}
+
+	in := p.in
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	records, err := utils.QueryAuditRecords(in, query)
+	if err != nil {
+		return err
+	}
+
+	return p.print(records, func(w io.Writer) {
+		fmt.Fprintln(w, "AGE\tACCOUNT\tREASON\tMETHOD\tENDPOINT\tSTATUS\tOUTCOME")
+		for _, record := range records {
+			fmt.Fprintf(w, "%v\t%v/%v\t%v\t%v\t%v\t%v\t%v\n", age(metav1.NewTime(record.Time)), record.Namespace, record.Name, orNone(record.Reason), record.Method, record.Endpoint, orNone(statusCode(record.StatusCode)), record.Outcome)
+		}
+	})
+}
+
+// the status code aqua answered with, empty when aqua was not reached
+func statusCode(code int) string {
+	if code == 0 {
+		return ""
+	}
+	return fmt.Sprint(code)
+}
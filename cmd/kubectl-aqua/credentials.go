@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+const (
+	formatEnv        = "env"
+	formatScannerCLI = "scannercli"
+)
+
+type credentialsCommand struct {
+	format  string
+	aquaURL string
+}
+
+// the credentials of an account as printed with -o json and -o yaml
+type scannerCredentials struct {
+	Host     string `json:"host,omitempty"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func (c *credentialsCommand) Usage() string { return "credentials [NAME] [--format env|scannercli]" }
+
+func (c *credentialsCommand) Description() string {
+	return "Print the scanner credentials of an AquaScannerAccount"
+}
+
+func (c *credentialsCommand) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.format, "format", formatEnv, "How the credentials are printed with -o table. env prints export statements for eval, scannercli prints the --host, --user and --password arguments of scannercli.")
+	flags.StringVar(&c.aquaURL, "aqua-url", os.Getenv("AQUA_URL"), "URL of the aqua console. Defaults to $AQUA_URL or the url of the account's AquaInstance when it can be read.")
+}
+
+func (c *credentialsCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	if c.format != formatEnv && c.format != formatScannerCLI {
+		return fmt.Errorf("unknown credentials format %q, use one of env or scannercli", c.format)
+	}
+
+	account, err := p.getAccount(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	password, err := accountPassword(ctx, p, account)
+	if err != nil {
+		return err
+	}
+
+	if account.Status.AccountName == "" || password == "" {
+		return fmt.Errorf("AquaScannerAccount %v has no credentials yet, its state is %v", account.Name, orNone(account.Status.State))
+	}
+	if !account.IsReady() {
+		fmt.Fprintf(p.errOut, "Warning: AquaScannerAccount %v is not ready, the credentials may not work yet: %v\n", account.Name, account.Status.Message)
+	}
+
+	credentials := scannerCredentials{Host: c.aquaURL, User: account.Status.AccountName, Password: password}
+	if credentials.Host == "" {
+		credentials.Host = instanceURL(ctx, p, account)
+	}
+
+	return p.print(credentials, func(w io.Writer) {
+		if c.format == formatScannerCLI {
+			arguments := []string{}
+			if credentials.Host != "" {
+				arguments = append(arguments, "--host "+shellQuote(credentials.Host))
+			}
+			arguments = append(arguments, "--user "+shellQuote(credentials.User), "--password "+shellQuote(credentials.Password))
+			fmt.Fprintln(w, strings.Join(arguments, " "))
+			return
+		}
+
+		if credentials.Host != "" {
+			fmt.Fprintf(w, "export SCANNER_HOST=%v\n", shellQuote(credentials.Host))
+		} else {
+			fmt.Fprintln(w, "# the aqua url is unknown, pass --aqua-url to include SCANNER_HOST")
+		}
+		fmt.Fprintf(w, "export SCANNER_USER=%v\n", shellQuote(credentials.User))
+		fmt.Fprintf(w, "export SCANNER_PASSWORD=%v\n", shellQuote(credentials.Password))
+	})
+}
+
+// returns the password of the account from its credentials Secret, empty when the account has no credentials yet
+func accountPassword(ctx context.Context, p *plugin, account *asav2.AquaScannerAccount) (string, error) {
+	if account.Status.CredentialsSecretRef == nil {
+		return "", nil
+	}
+
+	secretName := account.Status.CredentialsSecretRef.Name
+	secret := &corev1.Secret{}
+	err := p.client.Get(ctx, client.ObjectKey{Namespace: account.Namespace, Name: secretName}, secret)
+	if apierrors.IsForbidden(err) {
+		return "", fmt.Errorf("you are not allowed to read the credentials secret %v of AquaScannerAccount %v, an admin of the namespace can add you to spec.delivery.credentialReaders", secretName, account.Name)
+	}
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(secret.Data[asav2.CredentialsSecretPasswordKey]), nil
+}
+
+// the url of the AquaInstance the account was provisioned in, empty when it is unknown or can not be read
+func instanceURL(ctx context.Context, p *plugin, account *asav2.AquaScannerAccount) string {
+	if account.Status.Instance == "" {
+		return ""
+	}
+
+	// teams are not usually allowed to read the cluster scoped AquaInstances
+	aquaInstance := &asa.AquaInstance{}
+	if err := p.client.Get(ctx, client.ObjectKey{Name: account.Status.Instance}, aquaInstance); err != nil {
+		return ""
+	}
+	return aquaInstance.Spec.URL
+}
+
+// quotes value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+type eventsCommand struct {
+	watch bool
+}
+
+// what events prints for an event
+type accountEvent struct {
+	LastSeen metav1.Time `json:"lastSeen"`
+	Type     string      `json:"type"`
+	Reason   string      `json:"reason"`
+	Message  string      `json:"message"`
+	Count    int32       `json:"count,omitempty"`
+}
+
+func (c *eventsCommand) Usage() string { return "events [NAME] [--watch]" }
+
+func (c *eventsCommand) Description() string {
+	return "Show the events of an AquaScannerAccount"
+}
+
+func (c *eventsCommand) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&c.watch, "watch", "w", false, "After listing the events, keep printing new ones until interrupted.")
+}
+
+func (c *eventsCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	account, err := p.getAccount(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	events := &corev1.EventList{}
+	if err := p.client.List(ctx, events, client.InNamespace(account.Namespace)); err != nil {
+		return err
+	}
+
+	accountEvents := []accountEvent{}
+	for i := range events.Items {
+		if isAccountEvent(&events.Items[i], account) {
+			accountEvents = append(accountEvents, toAccountEvent(&events.Items[i]))
+		}
+	}
+	sort.SliceStable(accountEvents, func(i, j int) bool { return accountEvents[i].LastSeen.Before(&accountEvents[j].LastSeen) })
+
+	if !c.watch {
+		return p.print(accountEvents, func(w io.Writer) {
+			if len(accountEvents) == 0 {
+				fmt.Fprintf(p.errOut, "No events found for AquaScannerAccount %v.\n", account.Name)
+				return
+			}
+			fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tMESSAGE")
+			for _, event := range accountEvents {
+				printEventRow(w, event)
+			}
+		})
+	}
+
+	// while watching every event is printed as it arrives, one json object per line or one yaml document each
+	w := tabwriter.NewWriter(p.out, 0, 8, 3, ' ', 0)
+	if p.output == outputTable {
+		fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tMESSAGE")
+	}
+	for _, event := range accountEvents {
+		if err := c.printWatched(p, w, event); err != nil {
+			return err
+		}
+	}
+
+	watcher, err := p.client.Watch(ctx, &corev1.EventList{}, client.InNamespace(account.Namespace), &client.ListOptions{Raw: &metav1.ListOptions{ResourceVersion: events.ResourceVersion}})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case watchEvent, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			event, isEvent := watchEvent.Object.(*corev1.Event)
+			if !isEvent || watchEvent.Type == watch.Deleted || !isAccountEvent(event, account) {
+				continue
+			}
+			if err := c.printWatched(p, w, toAccountEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *eventsCommand) printWatched(p *plugin, w *tabwriter.Writer, event accountEvent) error {
+	switch p.output {
+	case outputJson:
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.out, string(b))
+		return err
+	case outputYaml:
+		b, err := yaml.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.out, "---\n%v", string(b))
+		return err
+	}
+	printEventRow(w, event)
+	return w.Flush()
+}
+
+func printEventRow(w io.Writer, event accountEvent) {
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", age(event.LastSeen), event.Type, event.Reason, strings.TrimSpace(event.Message))
+}
+
+func isAccountEvent(event *corev1.Event, account *asav2.AquaScannerAccount) bool {
+	return event.InvolvedObject.Kind == "AquaScannerAccount" && event.InvolvedObject.Name == account.Name
+}
+
+func toAccountEvent(event *corev1.Event) accountEvent {
+	// events recorded through the newer events api only set eventTime
+	lastSeen := event.LastTimestamp
+	if lastSeen.IsZero() {
+		lastSeen = metav1.NewTime(event.EventTime.Time)
+	}
+	if lastSeen.IsZero() {
+		lastSeen = event.CreationTimestamp
+	}
+	return accountEvent{LastSeen: lastSeen, Type: event.Type, Reason: event.Reason, Message: event.Message, Count: event.Count}
+}
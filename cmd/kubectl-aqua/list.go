@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+type listCommand struct {
+	allNamespaces bool
+}
+
+// what list prints for an account, never the password
+type accountSummary struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Account   string `json:"account,omitempty"`
+	Ready     bool   `json:"ready"`
+	State     string `json:"state,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	DryRun    bool   `json:"dryRun,omitempty"`
+}
+
+func (c *listCommand) Usage() string { return "list [-A]" }
+
+func (c *listCommand) Description() string {
+	return "List AquaScannerAccounts and whether their aqua account is ready"
+}
+
+func (c *listCommand) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&c.allNamespaces, "all-namespaces", "A", false, "List the AquaScannerAccounts in every namespace.")
+}
+
+func (c *listCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("list does not take arguments but got %v", args)
+	}
+
+	options := []client.ListOption{}
+	if !c.allNamespaces {
+		options = append(options, client.InNamespace(p.namespace))
+	}
+
+	accounts := &asav2.AquaScannerAccountList{}
+	if err := p.client.List(ctx, accounts, options...); err != nil {
+		return err
+	}
+
+	summaries := []accountSummary{}
+	for i := range accounts.Items {
+		summaries = append(summaries, summarize(&accounts.Items[i]))
+	}
+
+	return p.print(summaries, func(w io.Writer) {
+		if len(summaries) == 0 {
+			fmt.Fprintf(p.errOut, "No AquaScannerAccounts found in namespace %v.\n", p.namespace)
+			return
+		}
+
+		if c.allNamespaces {
+			fmt.Fprint(w, "NAMESPACE\t")
+		}
+		fmt.Fprintln(w, "NAME\tACCOUNT\tREADY\tSTATE\tINSTANCE\tAGE")
+
+		for i, summary := range summaries {
+			if c.allNamespaces {
+				fmt.Fprintf(w, "%v\t", summary.Namespace)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", summary.Name, orNone(summary.Account), summary.Ready, orNone(summary.State), orDefault(summary.Instance), age(accounts.Items[i].CreationTimestamp))
+		}
+	})
+}
+
+func summarize(account *asav2.AquaScannerAccount) accountSummary {
+	return accountSummary{
+		Namespace: account.Namespace,
+		Name:      account.Name,
+		Account:   account.Status.AccountName,
+		Ready:     account.IsReady(),
+		State:     account.Status.State,
+		Message:   account.Status.Message,
+		Instance:  account.Status.Instance,
+		DryRun:    account.IsDryRun(),
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+// an empty instance means the operator's own aqua instance
+func orDefault(instance string) string {
+	if instance == "" {
+		return "<default>"
+	}
+	return instance
+}
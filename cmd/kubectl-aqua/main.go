@@ -0,0 +1,208 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// kubectl-aqua lets teams look after their AquaScannerAccounts without digging through the CR's yaml. Installed on the
+// PATH it is run as kubectl aqua <command> (or oc aqua <command>).
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// a kubectl aqua subcommand
+type command interface {
+	// the arguments shown in the usage, e.g. "credentials [NAME]"
+	Usage() string
+	// one line shown in the list of commands
+	Description() string
+	AddFlags(flags *pflag.FlagSet)
+	Run(ctx context.Context, p *plugin, args []string) error
+}
+
+// a command that only talks to the cluster for some arguments, the kubeconfig is not loaded when it does not
+type localCommand interface {
+	NeedsCluster(args []string) bool
+}
+
+// an error that exits with its own code, e.g. a failed quality gate
+type exitCoder interface {
+	ExitCode() int
+}
+
+// what every command needs to talk to the cluster and print its result
+type plugin struct {
+	client    client.WithWatch
+	namespace string
+	// table, json or yaml
+	output string
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+}
+
+var commands = map[string]func() command{
+	"list":        func() command { return &listCommand{} },
+	"credentials": func() command { return &credentialsCommand{} },
+	"rotate":      func() command { return newRotateCommand() },
+	"resync":      func() command { return newResyncCommand() },
+	"objects":     func() command { return &objectsCommand{} },
+	"events":      func() command { return &eventsCommand{} },
+	"report":      func() command { return &reportCommand{} },
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		var exitErr exitCoder
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer, errOut io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(out)
+		return nil
+	}
+
+	newCommand, ok := commands[args[0]]
+	if !ok {
+		printUsage(errOut)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	cmd := newCommand()
+
+	flags := pflag.NewFlagSet("kubectl aqua "+args[0], pflag.ContinueOnError)
+	flags.SetOutput(errOut)
+	flags.Usage = func() {
+		fmt.Fprintf(errOut, "%v\n\nUsage:\n  kubectl aqua %v [flags]\n\nFlags:\n%v", cmd.Description(), cmd.Usage(), flags.FlagUsages())
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+	flags.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file to use for CLI requests.")
+	clientcmd.BindOverrideFlags(overrides, flags, clientcmd.RecommendedConfigOverrideFlags(""))
+
+	p := &plugin{in: os.Stdin, out: out, errOut: errOut}
+	flags.StringVarP(&p.output, "output", "o", outputTable, "Output format. One of table, json or yaml.")
+	cmd.AddFlags(flags)
+
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if p.output != outputTable && p.output != outputJson && p.output != outputYaml {
+		return fmt.Errorf("unknown output format %q, use one of table, json or yaml", p.output)
+	}
+
+	if local, ok := cmd.(localCommand); ok && !local.NeedsCluster(flags.Args()) {
+		return cmd.Run(ctx, p, flags.Args())
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+
+	p.namespace, _, err = clientConfig.Namespace()
+	if err != nil {
+		return err
+	}
+
+	p.client, err = client.NewWithWatch(restConfig, client.Options{Scheme: newScheme()})
+	if err != nil {
+		return err
+	}
+
+	return cmd.Run(ctx, p, flags.Args())
+}
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = asa.AddToScheme(scheme)
+	_ = asav2.AddToScheme(scheme)
+	return scheme
+}
+
+func printUsage(out io.Writer) {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "Self service for AquaScannerAccounts.\n\nUsage:\n  kubectl aqua <command> [flags]\n\nCommands:")
+	for _, name := range names {
+		cmd := commands[name]()
+		fmt.Fprintf(out, "  %-46v %v\n", cmd.Usage(), cmd.Description())
+	}
+	fmt.Fprintln(out, "\nEvery command takes -o table|json|yaml and the usual kubeconfig flags such as -n, --context and --kubeconfig.")
+}
+
+/*
+	Returns the account called name, or the only account in the namespace when no name is given, which is the common
+	case of one AquaScannerAccount per tools namespace.
+*/
+func (p *plugin) getAccount(ctx context.Context, args []string) (*asav2.AquaScannerAccount, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("expected at most one AquaScannerAccount name but got %v", args)
+	}
+
+	if len(args) == 1 {
+		account := &asav2.AquaScannerAccount{}
+		err := p.client.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: args[0]}, account)
+		return account, err
+	}
+
+	accounts := &asav2.AquaScannerAccountList{}
+	if err := p.client.List(ctx, accounts, client.InNamespace(p.namespace)); err != nil {
+		return nil, err
+	}
+
+	switch len(accounts.Items) {
+	case 0:
+		return nil, fmt.Errorf("there is no AquaScannerAccount in namespace %v", p.namespace)
+	case 1:
+		return &accounts.Items[0], nil
+	}
+	return nil, fmt.Errorf("there are %v AquaScannerAccounts in namespace %v, pass the name of one of them", len(accounts.Items), p.namespace)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/pflag"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// the aqua objects the operator manages for an account, in the order they are created
+var aquaObjectKinds = []string{"ApplicationScope", "PermissionSet", "Role", "User"}
+
+type objectsCommand struct{}
+
+type aquaObject struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// the change planned for the object while the account is in dry run mode
+	Planned string `json:"planned,omitempty"`
+}
+
+// what objects prints for an account
+type accountObjects struct {
+	Namespace string       `json:"namespace"`
+	Name      string       `json:"name"`
+	Instance  string       `json:"instance,omitempty"`
+	Objects   []aquaObject `json:"objects"`
+}
+
+func (c *objectsCommand) Usage() string { return "objects [NAME]" }
+
+func (c *objectsCommand) Description() string {
+	return "Show the aqua objects behind an AquaScannerAccount"
+}
+
+func (c *objectsCommand) AddFlags(flags *pflag.FlagSet) {}
+
+func (c *objectsCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	account, err := p.getAccount(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	result := accountObjects{Namespace: account.Namespace, Name: account.Name, Instance: account.Status.Instance, Objects: aquaObjects(account)}
+
+	return p.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Instance:\t%v\n\n", orDefault(result.Instance))
+		fmt.Fprintln(w, "KIND\tNAME\tSTATE\tPLANNED")
+		for _, object := range result.Objects {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", object.Kind, orNone(object.Name), orNone(object.State), orNone(object.Planned))
+		}
+	})
+}
+
+// every aqua object of the account is named after the account
+func aquaObjects(account *asav2.AquaScannerAccount) []aquaObject {
+	planned := map[string]string{}
+	if account.Status.Plan != nil {
+		for _, change := range account.Status.Plan.Changes {
+			planned[change.Kind] = change.Action
+		}
+	}
+
+	objects := []aquaObject{}
+	for _, kind := range aquaObjectKinds {
+		objects = append(objects, aquaObject{Kind: kind, Name: account.Status.AccountName, State: account.Status.CurrentState.Get(kind), Planned: planned[kind]})
+	}
+	return objects
+}
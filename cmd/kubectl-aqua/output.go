@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJson  = "json"
+	outputYaml  = "yaml"
+)
+
+// prints value as json or yaml, the table output is written by table
+func (p *plugin) print(value interface{}, table func(w io.Writer)) error {
+	switch p.output {
+	case outputJson:
+		b, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.out, string(b))
+		return err
+	case outputYaml:
+		b, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = p.out.Write(b)
+		return err
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 8, 3, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// the age of a timestamp the way kubectl get shows it, e.g. 5d or 3h2m
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
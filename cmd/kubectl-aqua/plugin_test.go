@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+func newTestPlugin(output string, objects ...client.Object) (*plugin, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &plugin{
+		client:    fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(objects...).Build(),
+		namespace: "abc123-tools",
+		output:    output,
+		out:       out,
+		errOut:    &bytes.Buffer{},
+	}, out
+}
+
+func readyAccount() *asav2.AquaScannerAccount {
+	created := asav2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", Role: "Created", User: "Created"}
+	return &asav2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Status: asav2.AquaScannerAccountStatus{
+			State:                "Complete",
+			AccountName:          "ScannerCLI_abc123",
+			CredentialsSecretRef: &corev1.LocalObjectReference{Name: "scanner-aqua-credentials"},
+			CurrentState:         created,
+			DesiredState:         created,
+			Instance:             "lab",
+		},
+	}
+}
+
+func credentialsSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner-aqua-credentials", Namespace: "abc123-tools"},
+		Data:       map[string][]byte{asav2.CredentialsSecretPasswordKey: []byte("it's-secret")},
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	p, out := newTestPlugin(outputTable, readyAccount())
+
+	if err := (&listCommand{}).Run(context.Background(), p, nil); err != nil {
+		t.Fatalf("list was not supposed to return an error but got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[1], "ScannerCLI_abc123") || !strings.Contains(lines[1], "true") {
+		t.Errorf("list was supposed to print a ready account but got\n%v", out.String())
+	}
+
+	p, out = newTestPlugin(outputJson, readyAccount())
+
+	if err := (&listCommand{}).Run(context.Background(), p, nil); err != nil {
+		t.Fatalf("list was not supposed to return an error but got %v", err)
+	}
+
+	summaries := []accountSummary{}
+	if err := json.Unmarshal(out.Bytes(), &summaries); err != nil || len(summaries) != 1 || !summaries[0].Ready {
+		t.Errorf("list was supposed to print the account as json but got %v (%v)", out.String(), err)
+	}
+
+	if strings.Contains(out.String(), "it's-secret") {
+		t.Errorf("list was not supposed to print the password but got %v", out.String())
+	}
+}
+
+func TestCredentialsCommand(t *testing.T) {
+	aquaInstance := &asa.AquaInstance{ObjectMeta: metav1.ObjectMeta{Name: "lab"}, Spec: asa.AquaInstanceSpec{URL: "https://aqua.example.com"}}
+
+	p, out := newTestPlugin(outputTable, readyAccount(), credentialsSecret(), aquaInstance)
+
+	if err := (&credentialsCommand{format: formatEnv}).Run(context.Background(), p, nil); err != nil {
+		t.Fatalf("credentials was not supposed to return an error but got %v", err)
+	}
+
+	expected := `export SCANNER_HOST='https://aqua.example.com'
+export SCANNER_USER='ScannerCLI_abc123'
+export SCANNER_PASSWORD='it'"'"'s-secret'
+`
+	if out.String() != expected {
+		t.Errorf("credentials was supposed to print\n%v\nbut got\n%v", expected, out.String())
+	}
+
+	p, out = newTestPlugin(outputTable, readyAccount(), credentialsSecret())
+
+	if err := (&credentialsCommand{format: formatScannerCLI, aquaURL: "https://aqua.internal"}).Run(context.Background(), p, []string{"scanner"}); err != nil {
+		t.Fatalf("credentials was not supposed to return an error but got %v", err)
+	}
+
+	expected = `--host 'https://aqua.internal' --user 'ScannerCLI_abc123' --password 'it'"'"'s-secret'` + "\n"
+	if out.String() != expected {
+		t.Errorf("credentials was supposed to print %v but got %v", expected, out.String())
+	}
+
+	notReady := readyAccount()
+	notReady.Status = asav2.AquaScannerAccountStatus{State: "Running"}
+	p, _ = newTestPlugin(outputTable, notReady)
+
+	if err := (&credentialsCommand{format: formatEnv}).Run(context.Background(), p, nil); err == nil {
+		t.Errorf("credentials was supposed to return an error for an account without credentials")
+	}
+
+	p, _ = newTestPlugin(outputTable, readyAccount())
+
+	if err := (&credentialsCommand{format: formatEnv}).Run(context.Background(), p, nil); err == nil {
+		t.Errorf("credentials was supposed to return an error when the credentials secret is missing")
+	}
+}
+
+func TestRotateCommand(t *testing.T) {
+	p, _ := newTestPlugin(outputTable, readyAccount())
+
+	if err := newRotateCommand().Run(context.Background(), p, nil); err != nil {
+		t.Fatalf("rotate was not supposed to return an error but got %v", err)
+	}
+
+	account := &asav2.AquaScannerAccount{}
+	if err := p.client.Get(context.Background(), client.ObjectKey{Namespace: "abc123-tools", Name: "scanner"}, account); err != nil {
+		t.Fatal(err)
+	}
+
+	if !account.RotationRequested() || account.ResyncRequested() {
+		t.Errorf("rotate was supposed to request a rotation only but got the annotations %v", account.GetAnnotations())
+	}
+}
+
+func TestObjectsCommand(t *testing.T) {
+	account := readyAccount()
+	account.Status.Plan = &asav2.AquaScannerAccountPlan{Changes: []asav2.AquaPlannedChange{{Kind: "Role", Action: "Update"}}}
+
+	p, out := newTestPlugin(outputYaml, account)
+
+	if err := (&objectsCommand{}).Run(context.Background(), p, nil); err != nil {
+		t.Fatalf("objects was not supposed to return an error but got %v", err)
+	}
+
+	for _, expected := range []string{"kind: ApplicationScope", "name: ScannerCLI_abc123", "planned: Update", "instance: lab"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("objects was supposed to print %v but got\n%v", expected, out.String())
+		}
+	}
+}
+
+func TestEventsCommand(t *testing.T) {
+	event := func(name string, involvedName string, reason string, lastSeen time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "abc123-tools"},
+			InvolvedObject: corev1.ObjectReference{Kind: "AquaScannerAccount", Name: involvedName, Namespace: "abc123-tools"},
+			Reason:         reason,
+			Type:           corev1.EventTypeNormal,
+			LastTimestamp:  metav1.NewTime(lastSeen),
+		}
+	}
+
+	p, out := newTestPlugin(outputTable,
+		readyAccount(),
+		event("second", "scanner", "DryRunPlanned", time.Now()),
+		event("first", "scanner", "CredentialsRotated", time.Now().Add(-time.Hour)),
+		event("other", "another-account", "Ignored", time.Now()),
+	)
+
+	if err := (&eventsCommand{}).Run(context.Background(), p, nil); err != nil {
+		t.Fatalf("events was not supposed to return an error but got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "CredentialsRotated") || !strings.Contains(lines[2], "DryRunPlanned") {
+		t.Errorf("events was supposed to print the account's events oldest first but got\n%v", out.String())
+	}
+}
+
+func TestReportCommand(t *testing.T) {
+	dir := t.TempDir()
+	scannerOutput := `{"image": "app:1.0", "disallowed": true, "resources": [{"resource": {"name": "musl", "version": "1.1.22-r2"}, "vulnerabilities": [{"name": "CVE-2019-14697", "aqua_severity": "critical", "aqua_score": 9.8}]}]}`
+	file := filepath.Join(dir, "scan.json")
+	if err := ioutil.WriteFile(file, []byte(scannerOutput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// no client, a local file is read without a cluster
+	out := &bytes.Buffer{}
+	p := &plugin{output: outputTable, out: out, errOut: &bytes.Buffer{}}
+	cmd := &reportCommand{sarifFile: filepath.Join(dir, "scan.sarif"), junitFile: filepath.Join(dir, "scan.xml"), failOn: "high"}
+
+	err := cmd.Run(context.Background(), p, []string{file})
+	var gateErr *gateFailedError
+	if !errors.As(err, &gateErr) || gateErr.ExitCode() != gateFailedExitCode {
+		t.Fatalf("report was supposed to fail the gate on a critical vulnerability but got %v", err)
+	}
+	if !strings.Contains(out.String(), "app:1.0") || !strings.Contains(out.String(), "1 critical vulnerabilities found") {
+		t.Errorf("report was supposed to print the counts and gate but got\n%v", out.String())
+	}
+
+	for _, written := range []string{"scan.sarif", "scan.xml"} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, written)); err != nil || !strings.Contains(string(data), "CVE-2019-14697") {
+			t.Errorf("report was supposed to write %v but got %v", written, err)
+		}
+	}
+
+	if err := (&reportCommand{failOn: "critical=1"}).Run(context.Background(), p, []string{file}); err != nil {
+		t.Errorf("report was supposed to pass a gate allowing one critical but got %v", err)
+	}
+
+	p.in = strings.NewReader(scannerOutput)
+	if err := (&reportCommand{}).Run(context.Background(), p, []string{"-"}); err != nil {
+		t.Errorf("report was supposed to read stdin but got %v", err)
+	}
+}
+
+func TestReportCommandReadsScan(t *testing.T) {
+	scan := &asa.AquaImageScan{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1.0", Namespace: "abc123-tools"},
+		Status:     asa.AquaImageScanStatus{Phase: asa.ImageScanFailed, Report: &asa.AquaImageScanReport{ConfigMap: "app-1.0-report"}},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1.0-report", Namespace: "abc123-tools"},
+		Data:       map[string]string{asa.ImageScanReportKey: `{"image": "app:1.0", "policyPassed": true, "counts": {"critical": 0, "high": 2, "medium": 0, "low": 0, "negligible": 0}}`},
+	}
+	p, out := newTestPlugin(outputJson, scan, configMap)
+
+	cmd := &reportCommand{scan: "app-1.0", failOn: "high=1"}
+	if !cmd.NeedsCluster(nil) {
+		t.Errorf("report --scan was supposed to need the cluster")
+	}
+
+	if err := cmd.Run(context.Background(), p, nil); err == nil {
+		t.Errorf("report was supposed to fail the gate on 2 high vulnerabilities")
+	}
+
+	summary := reportSummary{}
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil || summary.Counts.High != 2 || len(summary.Gate) != 1 || summary.Gate[0].Passed {
+		t.Errorf("report was supposed to print the scan's counts and failed gate as json but got %v (%v)", out.String(), err)
+	}
+}
+
+func TestAuditCommand(t *testing.T) {
+	trail := `{"time":"` + time.Now().Format(time.RFC3339) + `","namespace":"abc123-tools","name":"scanner","reason":"RotationRequested","aquaObject":"User","method":"PUT","endpoint":"/api/v1/users/ScannerCLI_abc123","statusCode":204,"outcome":"Succeeded"}
+{"time":"` + time.Now().Add(-48*time.Hour).Format(time.RFC3339) + `","namespace":"def456-tools","name":"scanner","aquaObject":"Role","method":"DELETE","endpoint":"/api/v2/access_management/roles/ScannerCLI_def456","outcome":"Error","error":"connection refused"}
+`
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := ioutil.WriteFile(file, []byte(trail), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// no client, the audit trail is read without a cluster
+	out := &bytes.Buffer{}
+	p := &plugin{output: outputTable, out: out, errOut: &bytes.Buffer{}}
+
+	if err := (&auditCommand{account: "abc123-tools/scanner"}).Run(context.Background(), p, []string{file}); err != nil {
+		t.Fatalf("audit was not supposed to return an error but got %v", err)
+	}
+	if !strings.Contains(out.String(), "abc123-tools/scanner") || !strings.Contains(out.String(), "RotationRequested") || strings.Contains(out.String(), "def456") {
+		t.Errorf("audit was supposed to print the changes of abc123-tools/scanner only but got\n%v", out.String())
+	}
+
+	out.Reset()
+	p.output = outputJson
+	p.in = strings.NewReader(trail)
+	if err := (&auditCommand{since: 24 * time.Hour}).Run(context.Background(), p, []string{"-"}); err != nil {
+		t.Fatalf("audit was supposed to read stdin but got %v", err)
+	}
+	if strings.Contains(out.String(), "def456") || !strings.Contains(out.String(), `"method": "PUT"`) {
+		t.Errorf("audit was supposed to leave out the changes older than --since but got\n%v", out.String())
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils/scanreport"
+)
+
+// exit code of report when the quality gate failed, errors exit with 1
+const gateFailedExitCode = 2
+
+/*
+	report turns a scannercli json report into SARIF and JUnit for CI and fails when it is over the severity
+	thresholds. The report is read from a file, from stdin or from the report ConfigMap of an AquaImageScan.
+*/
+type reportCommand struct {
+	scan         string
+	sarifFile    string
+	junitFile    string
+	failOn       string
+	failOnPolicy bool
+}
+
+// what report prints
+type reportSummary struct {
+	Image        string                    `json:"image"`
+	Digest       string                    `json:"digest,omitempty"`
+	PolicyPassed bool                      `json:"policyPassed"`
+	Counts       scanreport.SeverityCounts `json:"counts"`
+	Gate         []scanreport.GateResult   `json:"gate,omitempty"`
+}
+
+// returned by report when the quality gate failed so the plugin exits with gateFailedExitCode
+type gateFailedError struct {
+	failed []string
+}
+
+func (e *gateFailedError) Error() string {
+	return "the quality gate failed: " + strings.Join(e.failed, "; ")
+}
+
+func (e *gateFailedError) ExitCode() int { return gateFailedExitCode }
+
+func (c *reportCommand) Usage() string { return "report [FILE|-] [--scan NAME]" }
+
+func (c *reportCommand) Description() string {
+	return "Convert a scan report to SARIF or JUnit and gate it on severity thresholds"
+}
+
+func (c *reportCommand) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.scan, "scan", "", "Read the report of this AquaImageScan instead of a scannercli json file.")
+	flags.StringVar(&c.sarifFile, "sarif", "", "Write the report as SARIF 2.1.0 to this file.")
+	flags.StringVar(&c.junitFile, "junit", "", "Write the report and the quality gate as JUnit xml to this file.")
+	flags.StringVar(&c.failOn, "fail-on", "", "Severity thresholds, e.g. critical=0,high=5, or high to fail on any high or critical vulnerability.")
+	flags.BoolVar(&c.failOnPolicy, "fail-on-policy", false, "Fail when the image did not pass its aqua image assurance policies.")
+}
+
+// a local file or stdin is read without a cluster so pipelines can run report next to scannercli
+func (c *reportCommand) NeedsCluster(args []string) bool { return c.scan != "" }
+
+func (c *reportCommand) Run(ctx context.Context, p *plugin, args []string) error {
+	gate, err := scanreport.ParseGate(c.failOn)
+	if err != nil {
+		return err
+	}
+	gate.Policy = c.failOnPolicy
+
+	report, err := c.load(ctx, p, args)
+	if err != nil {
+		return err
+	}
+
+	results := gate.Evaluate(report)
+
+	if c.sarifFile != "" {
+		sarif, err := scanreport.SARIF(report)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(c.sarifFile, sarif, 0644); err != nil {
+			return err
+		}
+	}
+
+	if c.junitFile != "" {
+		junit, err := scanreport.JUnit(report, results)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(c.junitFile, junit, 0644); err != nil {
+			return err
+		}
+	}
+
+	summary := reportSummary{Image: report.Image, Digest: report.Digest, PolicyPassed: report.PolicyPassed, Counts: report.Counts, Gate: results}
+
+	err = p.print(summary, func(w io.Writer) {
+		counts := report.Counts
+		fmt.Fprintln(w, "IMAGE\tPOLICY\tCRITICAL\tHIGH\tMEDIUM\tLOW\tNEGLIGIBLE")
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", report.Image, policyResult(report.PolicyPassed), counts.Critical, counts.High, counts.Medium, counts.Low, counts.Negligible)
+
+		if len(results) > 0 {
+			fmt.Fprintln(w, "\nGATE\tRESULT\tMESSAGE")
+			for _, result := range results {
+				fmt.Fprintf(w, "%v\t%v\t%v\n", result.Name, policyResult(result.Passed), result.Message)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result.Message)
+		}
+	}
+	if len(failed) > 0 {
+		return &gateFailedError{failed: failed}
+	}
+	return nil
+}
+
+func (c *reportCommand) load(ctx context.Context, p *plugin, args []string) (*scanreport.Report, error) {
+	if c.scan != "" {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("pass either a file or --scan but got both %v and --scan %v", args, c.scan)
+		}
+		return c.loadScan(ctx, p)
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected the scannercli json file, - for stdin, or --scan but got %v", args)
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = ioutil.ReadAll(p.in)
+	} else {
+		data, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the json between the markers of a scan Job's log is accepted too
+	if extracted, found := scanreport.Extract(data); found {
+		data = extracted
+	}
+	return scanreport.Parse(data)
+}
+
+// reads the full report the operator stored in the report ConfigMap of an AquaImageScan
+func (c *reportCommand) loadScan(ctx context.Context, p *plugin) (*scanreport.Report, error) {
+	scan := &asa.AquaImageScan{}
+	if err := p.client.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: c.scan}, scan); err != nil {
+		return nil, err
+	}
+
+	if scan.Status.Report == nil || scan.Status.Report.ConfigMap == "" {
+		return nil, fmt.Errorf("AquaImageScan %v has no report yet, it is %v", scan.Name, orNone(scan.Status.Phase))
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := p.client.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: scan.Status.Report.ConfigMap}, configMap); err != nil {
+		return nil, err
+	}
+
+	report := &scanreport.Report{}
+	if err := json.Unmarshal([]byte(configMap.Data[asa.ImageScanReportKey]), report); err != nil {
+		return nil, fmt.Errorf("the report in ConfigMap %v is not valid json: %v", configMap.Name, err)
+	}
+	if report.OmittedVulnerabilities > 0 {
+		fmt.Fprintf(p.errOut, "The report of AquaImageScan %v left out its %v least severe vulnerabilities.\n", scan.Name, report.OmittedVulnerabilities)
+	}
+	return report, nil
+}
+
+func policyResult(passed bool) string {
+	if passed {
+		return "Pass"
+	}
+	return "Fail"
+}
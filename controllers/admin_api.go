@@ -0,0 +1,342 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+// where the admin api serves the AquaScannerAccounts
+const adminAccountsPath = "/api/v1/aquascanneraccounts"
+
+// the formats the admin api answers in, picked with the format query parameter or the Accept header
+const (
+	adminFormatJson = "json"
+	adminFormatCsv  = "csv"
+)
+
+// logs the admin api, its requests included
+var adminAPILog = componentLogger("admin-api")
+
+/*
+	AdminAPI serves a read-only https api listing every AquaScannerAccount with its owners, conditions, drift and aqua
+	objects, so platform admins can match accounts to aqua objects and export them for audits without access to
+	the cluster and the aqua console. Callers authenticate with a kubernetes bearer token, checked with a TokenReview,
+	and need to be allowed to list AquaScannerAccounts in the namespace they ask for, or in every namespace, which is
+	checked with a SubjectAccessReview.
+*/
+type AdminAPI struct {
+	client.Client
+	// host:port the api is served on
+	BindAddress string
+	// directory holding tls.crt and tls.key
+	CertDir string
+}
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// SetupWithManager adds the api to the Manager, it is served once the manager starts.
+func (a *AdminAPI) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(a)
+}
+
+// NeedLeaderElection is false so every replica answers
+func (a *AdminAPI) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the api until the manager stops, the certificate is reloaded when it is renewed
+func (a *AdminAPI) Start(ctx context.Context) error {
+	certWatcher, err := certwatcher.New(filepath.Join(a.CertDir, "tls.crt"), filepath.Join(a.CertDir, "tls.key"))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := certWatcher.Start(ctx); err != nil {
+			adminAPILog.Error(err, "Failed to watch the certificate of the admin api", "certDir", a.CertDir)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(adminAccountsPath, a)
+
+	tlsConfig := &tls.Config{GetCertificate: certWatcher.GetCertificate, MinVersion: tls.VersionTLS12}
+	listener, err := tls.Listen("tcp", a.BindAddress, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			adminAPILog.Error(err, "Failed to shut down the admin api")
+		}
+	}()
+
+	adminAPILog.Info("Serving the admin api", "address", a.BindAddress, "path", adminAccountsPath)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP answers a GET of the AquaScannerAccounts matching the query parameters as json or csv
+func (a *AdminAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := ctrl.LoggerInto(r.Context(), adminAPILog)
+	logger := adminAPILog
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "the admin api is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, authenticated, authErr := a.authenticate(ctx, r)
+	if authErr != nil {
+		logger.Error(authErr, "Failed to review the token of an admin api request")
+		http.Error(w, "unable to review the token", http.StatusInternalServerError)
+		return
+	}
+	if !authenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="aqua-scanner-operator"`)
+		http.Error(w, "a valid kubernetes bearer token is required", http.StatusUnauthorized)
+		return
+	}
+
+	// the query is only read for callers that authenticated, so the api tells nothing to anyone else
+	query, format, queryErr := parseAdminQuery(r)
+	if queryErr != nil {
+		http.Error(w, queryErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allowed, reason, reviewErr := a.authorize(ctx, user, query.Namespace)
+	if reviewErr != nil {
+		logger.Error(reviewErr, "Failed to review the access of an admin api request", "user", user.Username)
+		http.Error(w, "unable to review the access of "+user.Username, http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		logger.Info("Denied an admin api request", "user", user.Username, "namespace", query.Namespace, "reason", reason)
+		http.Error(w, forbiddenMessage(user.Username, query.Namespace), http.StatusForbidden)
+		return
+	}
+
+	reports, listErr := a.accountReports(ctx, query)
+	if listErr != nil {
+		logger.Error(listErr, "Failed to list the AquaScannerAccounts for the admin api")
+		http.Error(w, "unable to list the AquaScannerAccounts", http.StatusInternalServerError)
+		return
+	}
+
+	// the exports are audits themselves
+	logger.Info("Exported AquaScannerAccounts", "user", user.Username, "query", r.URL.RawQuery, "format", format, "accounts", len(reports))
+
+	var writeErr error
+	switch format {
+	case adminFormatCsv:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="aquascanneraccounts.csv"`)
+		writeErr = utils.WriteAccountReportsCsv(w, reports)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		writeErr = json.NewEncoder(w).Encode(map[string]interface{}{"items": reports})
+	}
+	if writeErr != nil {
+		logger.Error(writeErr, "Failed to write the admin api response", "user", user.Username)
+	}
+}
+
+// reads the filters and format of a request, see the README for the parameters
+func parseAdminQuery(r *http.Request) (utils.AccountReportQuery, string, error) {
+	values := r.URL.Query()
+
+	query := utils.AccountReportQuery{
+		Namespace:  values.Get("namespace"),
+		Name:       values.Get("name"),
+		Instance:   values.Get("instance"),
+		State:      values.Get("state"),
+		Ready:      values.Get("ready"),
+		Drift:      values.Get("drift"),
+		Owner:      values.Get("owner"),
+		AquaObject: values.Get("aquaObject"),
+	}
+
+	if query.Ready != "" && !containsString([]string{"True", "False", "Unknown"}, query.Ready) {
+		return query, "", fmt.Errorf("ready must be True, False or Unknown but got %q", query.Ready)
+	}
+	if query.Drift != "" && !containsString([]string{utils.DriftInSync, utils.DriftDrifted, utils.DriftUnknown}, query.Drift) {
+		return query, "", fmt.Errorf("drift must be %v, %v or %v but got %q", utils.DriftInSync, utils.DriftDrifted, utils.DriftUnknown, query.Drift)
+	}
+	if rotatedBefore := values.Get("rotatedBefore"); rotatedBefore != "" {
+		t, err := time.Parse(time.RFC3339, rotatedBefore)
+		if err != nil {
+			return query, "", fmt.Errorf("rotatedBefore must be an RFC 3339 time, e.g. 2026-01-02T15:04:05Z, but got %q", rotatedBefore)
+		}
+		query.RotatedBefore = t
+	}
+
+	format := values.Get("format")
+	switch {
+	case format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv"):
+		format = adminFormatCsv
+	case format == "":
+		format = adminFormatJson
+	case format != adminFormatJson && format != adminFormatCsv:
+		return query, "", fmt.Errorf("format must be %v or %v but got %q", adminFormatJson, adminFormatCsv, format)
+	}
+	return query, format, nil
+}
+
+// reviews the bearer token of the request, false when there is none or kubernetes does not accept it
+func (a *AdminAPI) authenticate(ctx context.Context, r *http.Request) (authenticationv1.UserInfo, bool, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return authenticationv1.UserInfo{}, false, nil
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if token == "" {
+		return authenticationv1.UserInfo{}, false, nil
+	}
+
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := a.Create(ctx, review); err != nil {
+		return authenticationv1.UserInfo{}, false, err
+	}
+	return review.Status.User, review.Status.Authenticated, nil
+}
+
+// asks kubernetes whether user may list AquaScannerAccounts in namespace, or in every namespace when it is empty
+func (a *AdminAPI) authorize(ctx context.Context, user authenticationv1.UserInfo, namespace string) (bool, string, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Group:     asav2.GroupVersion.Group,
+				Resource:  "aquascanneraccounts",
+			},
+		},
+	}
+	if err := a.Create(ctx, review); err != nil {
+		return false, "", err
+	}
+	return review.Status.Allowed, review.Status.Reason, nil
+}
+
+func forbiddenMessage(username string, namespace string) string {
+	if namespace == "" {
+		return username + " can not list AquaScannerAccounts in every namespace, ask for a namespace with ?namespace="
+	}
+	return username + " can not list AquaScannerAccounts in namespace " + namespace
+}
+
+// the reports of the accounts matching query, sorted by namespace and name
+func (a *AdminAPI) accountReports(ctx context.Context, query utils.AccountReportQuery) ([]utils.AccountReport, error) {
+	options := []client.ListOption{}
+	if query.Namespace != "" {
+		options = append(options, client.InNamespace(query.Namespace))
+	}
+
+	aquaScannerAccounts := &asav2.AquaScannerAccountList{}
+	if err := a.List(ctx, aquaScannerAccounts, options...); err != nil {
+		return nil, err
+	}
+
+	owners := map[string][]utils.Contact{}
+	reports := []utils.AccountReport{}
+	for i := range aquaScannerAccounts.Items {
+		aquaScannerAccount := &aquaScannerAccounts.Items[i]
+
+		namespaceOwners, read := owners[aquaScannerAccount.Namespace]
+		if !read {
+			namespaceOwners = a.namespaceOwners(ctx, aquaScannerAccount.Namespace)
+			owners[aquaScannerAccount.Namespace] = namespaceOwners
+		}
+
+		if report := utils.NewAccountReport(aquaScannerAccount, namespaceOwners); query.Matches(report) {
+			reports = append(reports, report)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Namespace != reports[j].Namespace {
+			return reports[i].Namespace < reports[j].Namespace
+		}
+		return reports[i].Name < reports[j].Name
+	})
+	return reports, nil
+}
+
+/*
+	The contacts of the namespace, read through the cache once per request. None when the namespace can not be read so
+	the export still lists its accounts, a namespace the operator may not read is not an error.
+*/
+func (a *AdminAPI) namespaceOwners(ctx context.Context, name string) []utils.Contact {
+	namespace := &corev1.Namespace{}
+	if err := a.Get(ctx, types.NamespacedName{Name: name}, namespace); err != nil {
+		if !errors.IsForbidden(err) {
+			ctrl.LoggerFrom(ctx).Error(err, "Failed to read the contacts of the namespace", "namespace", name)
+		}
+		return nil
+	}
+	return utils.GetContactsFromAnnotation(namespace.GetAnnotations()[utils.ContactsAnnotation])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
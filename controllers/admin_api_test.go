@@ -0,0 +1,81 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+// counts the namespace reads and forbids the ones in forbidden
+type namespaceReader struct {
+	client.Client
+	forbidden map[string]bool
+	reads     int
+}
+
+func (c *namespaceReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if _, ok := obj.(*corev1.Namespace); ok {
+		c.reads++
+		if c.forbidden[key.Name] {
+			return errors.NewForbidden(corev1.Resource("namespaces"), key.Name, nil)
+		}
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestAdminAPIAccountReportsReadNamespacesOnce(t *testing.T) {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	asav2.AddToScheme(scheme)
+
+	objects := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "abc123-tools", Annotations: map[string]string{utils.ContactsAnnotation: "- role: Technical Lead\n  email: patrick.simonian@gov.bc.ca\n"}}},
+		&asav2.AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"}},
+		&asav2.AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "release-scanner", Namespace: "abc123-tools"}},
+		&asav2.AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "def456-tools"}},
+	}
+	reader := &namespaceReader{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(), forbidden: map[string]bool{"def456-tools": true}}
+
+	reports, err := (&AdminAPI{Client: reader}).accountReports(context.Background(), utils.AccountReportQuery{})
+	if err != nil {
+		t.Fatalf("accountReports was not supposed to fail when a namespace can not be read but got %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("accountReports was supposed to list every account but got %v", len(reports))
+	}
+	if len(reports[0].Owners) != 1 || len(reports[1].Owners) != 1 {
+		t.Errorf("accountReports was supposed to list the contacts of the namespace but got %+v", reports[:2])
+	}
+	if reports[2].Namespace != "def456-tools" || len(reports[2].Owners) != 0 {
+		t.Errorf("accountReports was supposed to list an account in a forbidden namespace without contacts but got %+v", reports[2])
+	}
+	if reader.reads != 2 {
+		t.Errorf("accountReports was supposed to read each namespace once but read %v times", reader.reads)
+	}
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	configv1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/config/v1alpha1"
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils/scanreport"
+)
+
+const (
+	// configmaps are limited to 1MiB, this leaves room for the metadata
+	imageScanReportLimit = 1000 * 1024
+	// the most of a scan pod's log that is read looking for the report
+	imageScanLogLimit = int64(32 * 1024 * 1024)
+)
+
+// logs what happens outside of a reconcile of an AquaImageScan
+var aquaImageScanLog = componentLogger("aquaimagescan")
+
+// AquaImageScanReconciler runs scannercli in a Job for every AquaImageScan
+type AquaImageScanReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// the manager's config file after defaulting and validation, a defaulted config is used when nil
+	Config *configv1alpha1.OperatorConfig
+	// lists scan pods and reads their logs straight from the API so pods are not cached, built from the manager's
+	// rest config when nil
+	Pods corev1client.PodsGetter
+}
+
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquaimagescans,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquaimagescans/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquaimagescans/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+
+// Reconcile creates the Job of the scan once the namespace's AquaScannerAccount is ready, reflects the Job in the
+// status of the scan and deletes the Job ttlAfterFinished after the scan finished
+func (r *AquaImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	aquaImageScan := &asa.AquaImageScan{}
+
+	if err := r.Get(ctx, req.NamespacedName, aquaImageScan); err != nil {
+		if errors.IsNotFound(err) {
+			// the Job is garbage collected with the scan
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get AquaImageScan")
+		return ctrl.Result{}, err
+	}
+
+	original := aquaImageScan.DeepCopy()
+
+	result, reconcileErr := r.reconcileAquaImageScan(ctx, aquaImageScan)
+
+	if !equality.Semantic.DeepEqual(original.Status, aquaImageScan.Status) {
+		if err := r.Status().Patch(ctx, aquaImageScan, client.MergeFrom(original)); err != nil {
+			if errors.IsNotFound(err) {
+				return ctrl.Result{}, nil
+			}
+			logger.Error(err, "Failed to patch AquaImageScan status")
+			return ctrl.Result{Requeue: true}, err
+		}
+	}
+
+	return result, reconcileErr
+}
+
+func (r *AquaImageScanReconciler) reconcileAquaImageScan(ctx context.Context, aquaImageScan *asa.AquaImageScan) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if aquaImageScan.IsFinished() {
+		return r.deleteFinishedJob(ctx, aquaImageScan)
+	}
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: utils.ImageScanJobName(aquaImageScan), Namespace: aquaImageScan.Namespace}, job)
+
+	if errors.IsNotFound(err) {
+		if aquaImageScan.Status.JobName != "" {
+			setImageScanFinished(aquaImageScan, asa.ImageScanFailed, "Job "+aquaImageScan.Status.JobName+" was deleted before the scan finished", nil)
+			return ctrl.Result{}, nil
+		}
+		return r.createJob(ctx, aquaImageScan)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to get the Job of AquaImageScan")
+		return ctrl.Result{}, err
+	}
+
+	phase, message := utils.ImageScanPhase(job)
+
+	if phase == asa.ImageScanSucceeded || phase == asa.ImageScanFailed {
+		if err := r.collectReport(ctx, aquaImageScan, job); err != nil {
+			logger.Error(err, "Failed to read the report of AquaImageScan")
+			message += ". The scan report could not be read: " + err.Error()
+		} else if report := aquaImageScan.Status.Report; phase == asa.ImageScanFailed && report.Policy == asa.ImageScanPolicyFail {
+			message = "The image did not pass its image assurance policies"
+			if len(report.FailedPolicies) > 0 {
+				message += ": " + strings.Join(report.FailedPolicies, ", ")
+			}
+		}
+		setImageScanFinished(aquaImageScan, phase, message, job.Status.CompletionTime)
+		return r.deleteFinishedJob(ctx, aquaImageScan)
+	}
+
+	aquaImageScan.Status.Phase = phase
+	aquaImageScan.Status.Message = message
+	aquaImageScan.Status.JobName = job.Name
+	return ctrl.Result{}, nil
+}
+
+// creates the Job of the scan, the scan is left Pending until the namespace's AquaScannerAccount is ready
+func (r *AquaImageScanReconciler) createJob(ctx context.Context, aquaImageScan *asa.AquaImageScan) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	credentialsSecret, waitingFor, err := r.credentialsSecret(ctx, aquaImageScan)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if credentialsSecret == "" {
+		// the scan is reconciled again when the AquaScannerAccounts in the namespace change
+		aquaImageScan.Status.Phase = asa.ImageScanPending
+		aquaImageScan.Status.Message = waitingFor
+		return ctrl.Result{}, nil
+	}
+
+	timeout := r.Config.ImageScans.Timeout.Duration
+	if aquaImageScan.Spec.Timeout != nil {
+		timeout = aquaImageScan.Spec.Timeout.Duration
+	}
+
+	job := utils.NewImageScanJob(aquaImageScan, utils.ImageScanJobOptions{
+		ScannerImage:      r.Config.ImageScans.ScannerImage,
+		ImagePullSecrets:  r.Config.ImageScans.ImagePullSecrets,
+		CredentialsSecret: credentialsSecret,
+		Timeout:           timeout,
+	})
+
+	if err := controllerutil.SetControllerReference(aquaImageScan, job, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+		logger.Error(err, "Failed to create the Job of AquaImageScan")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Created scan Job for AquaImageScan", "job", job.Name)
+
+	now := metav1.Now()
+	aquaImageScan.Status.Phase = asa.ImageScanPending
+	aquaImageScan.Status.Message = "Waiting for the scan Job to start"
+	aquaImageScan.Status.JobName = job.Name
+	aquaImageScan.Status.StartTime = &now
+	return ctrl.Result{}, nil
+}
+
+/*
+	Returns the credentials Secret of the AquaScannerAccount named by spec.accountRef, or of the only account in the
+	namespace. When the account is not ready yet the Secret is empty and the string says what the scan is waiting for.
+*/
+func (r *AquaImageScanReconciler) credentialsSecret(ctx context.Context, aquaImageScan *asa.AquaImageScan) (string, string, error) {
+	aquaScannerAccount := &asav2.AquaScannerAccount{}
+
+	if aquaImageScan.Spec.AccountRef != "" {
+		err := r.Get(ctx, types.NamespacedName{Name: aquaImageScan.Spec.AccountRef, Namespace: aquaImageScan.Namespace}, aquaScannerAccount)
+		if errors.IsNotFound(err) {
+			return "", "Waiting for AquaScannerAccount " + aquaImageScan.Spec.AccountRef + " to be created", nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		aquaScannerAccounts := &asav2.AquaScannerAccountList{}
+		if err := r.List(ctx, aquaScannerAccounts, client.InNamespace(aquaImageScan.Namespace)); err != nil {
+			return "", "", err
+		}
+
+		switch len(aquaScannerAccounts.Items) {
+		case 0:
+			return "", "Waiting for an AquaScannerAccount to be created in namespace " + aquaImageScan.Namespace, nil
+		case 1:
+			aquaScannerAccount = &aquaScannerAccounts.Items[0]
+		default:
+			return "", fmt.Sprintf("There are %v AquaScannerAccounts in namespace %v, set spec.accountRef to the one to scan with", len(aquaScannerAccounts.Items), aquaImageScan.Namespace), nil
+		}
+	}
+
+	if aquaScannerAccount.Status.CredentialsSecretRef == nil {
+		return "", "Waiting for AquaScannerAccount " + aquaScannerAccount.Name + " to be ready", nil
+	}
+	return aquaScannerAccount.Status.CredentialsSecretRef.Name, "", nil
+}
+
+/*
+	Reads the json report scannercli printed in the log of the scan's pod, keeps a summary of it in the status and the
+	full report in the scan's report ConfigMap. Pods are gone once the Job is deleted so this is only tried once, when
+	the Job finishes.
+*/
+func (r *AquaImageScanReconciler) collectReport(ctx context.Context, aquaImageScan *asa.AquaImageScan, job *batchv1.Job) error {
+	pods, err := r.Pods.Pods(aquaImageScan.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job.Name})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("the pod of Job %v is gone", job.Name)
+	}
+
+	// the Job is not retried so there is normally one pod, the last one has the report otherwise
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	pod := pods.Items[len(pods.Items)-1]
+
+	limit := imageScanLogLimit
+	log, err := r.Pods.Pods(aquaImageScan.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: utils.ImageScanContainer, LimitBytes: &limit}).DoRaw(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, found := scanreport.Extract(log)
+	if !found {
+		return fmt.Errorf("scannercli did not write a report, see the logs of Job %v", job.Name)
+	}
+
+	report, err := scanreport.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	fullReport, omitted, err := report.Marshal(imageScanReportLimit)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: aquaImageScan.ReportConfigMapName(), Namespace: aquaImageScan.Namespace}}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Labels = map[string]string{utils.ImageScanLabel: aquaImageScan.Name}
+		utils.SetWatchLabel(configMap)
+		configMap.Data = map[string]string{asa.ImageScanReportKey: string(fullReport)}
+		return controllerutil.SetControllerReference(aquaImageScan, configMap, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := utils.ImageScanReportSummary(report)
+	summary.ConfigMap = configMap.Name
+	summary.Truncated = omitted > 0
+	aquaImageScan.Status.Report = summary
+	return nil
+}
+
+// deletes the Job of a finished scan once ttlAfterFinished has passed
+func (r *AquaImageScanReconciler) deleteFinishedJob(ctx context.Context, aquaImageScan *asa.AquaImageScan) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if aquaImageScan.Status.JobDeleted || aquaImageScan.Status.JobName == "" {
+		return ctrl.Result{}, nil
+	}
+
+	ttl := r.Config.ImageScans.TTLAfterFinished.Duration
+	if aquaImageScan.Spec.TTLAfterFinished != nil {
+		ttl = aquaImageScan.Spec.TTLAfterFinished.Duration
+	}
+
+	if remaining := time.Until(aquaImageScan.Status.CompletionTime.Add(ttl)); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: aquaImageScan.Status.JobName, Namespace: aquaImageScan.Namespace}}
+
+	// jobs orphan their pods unless told otherwise
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to delete the Job of finished AquaImageScan")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted the Job of finished AquaImageScan", "job", job.Name)
+	aquaImageScan.Status.JobDeleted = true
+	return ctrl.Result{}, nil
+}
+
+func setImageScanFinished(aquaImageScan *asa.AquaImageScan, phase string, message string, completionTime *metav1.Time) {
+	if completionTime == nil {
+		now := metav1.Now()
+		completionTime = &now
+	}
+	aquaImageScan.Status.Phase = phase
+	aquaImageScan.Status.Message = message
+	aquaImageScan.Status.CompletionTime = completionTime
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AquaImageScanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Client = newTracingClient(r.Client)
+	if r.Config == nil {
+		r.Config = &configv1alpha1.OperatorConfig{}
+		r.Config.Default()
+	}
+	if r.Pods == nil {
+		pods, err := corev1client.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+		r.Pods = pods
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&asa.AquaImageScan{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.ConfigMap{}).
+		// scans waiting for an account start when it becomes ready
+		Watches(&source.Kind{Type: &asav2.AquaScannerAccount{}}, handler.EnqueueRequestsFromMapFunc(r.pendingScansInNamespace)).
+		Complete(traced("AquaImageScan", r))
+}
+
+// maps an AquaScannerAccount to the AquaImageScans in its namespace that have not started yet
+func (r *AquaImageScanReconciler) pendingScansInNamespace(object client.Object) []reconcile.Request {
+	aquaImageScans := &asa.AquaImageScanList{}
+
+	if err := r.List(context.Background(), aquaImageScans, client.InNamespace(object.GetNamespace())); err != nil {
+		aquaImageScanLog.Error(err, "Failed to list AquaImageScans in namespace", "namespace", object.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, aquaImageScan := range aquaImageScans.Items {
+		if aquaImageScan.Status.JobName == "" {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: aquaImageScan.Name, Namespace: aquaImageScan.Namespace}})
+		}
+	}
+	return requests
+}
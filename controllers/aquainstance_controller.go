@@ -0,0 +1,297 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+// how often the health of an aqua instance is re-checked
+const aquaInstanceHealthCheckInterval = 5 * time.Minute
+
+const defaultCABundleKey = "ca.crt"
+
+// logs what happens outside of a reconcile of an AquaInstance
+var aquaInstanceLog = componentLogger("aquainstance")
+
+// AquaInstanceReconciler reconciles a AquaInstance object
+type AquaInstanceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquainstances/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Reconcile registers the connection details of an AquaInstance so AquaScannerAccounts can use it and
+// periodically checks that the operator is able to authenticate with it
+func (r *AquaInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	aquaInstance := &asa.AquaInstance{}
+
+	err := r.Get(ctx, req.NamespacedName, aquaInstance)
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("AquaInstance not found. Removing its auth cache", "instance", req.Name)
+			utils.RemoveAquaInstanceAuth(req.Name)
+			aquaInstanceHealthy.DeleteLabelValues(req.Name)
+			aquaInstanceLoginFailures.DeleteLabelValues(req.Name)
+			forgetAquaVersion(req.Name)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get AquaInstance", "instance", req.Name)
+		return ctrl.Result{}, err
+	}
+
+	checkErr := r.checkInstance(ctx, aquaInstance)
+
+	if updateErr := r.updateHealth(ctx, aquaInstance, checkErr); updateErr != nil {
+		return ctrl.Result{Requeue: true}, updateErr
+	}
+
+	return ctrl.Result{RequeueAfter: aquaInstanceHealthCheckInterval}, nil
+}
+
+// registers the connection details of the AquaInstance and checks the operator can login with them
+func (r *AquaInstanceReconciler) checkInstance(ctx context.Context, aquaInstance *asa.AquaInstance) error {
+	logger := log.FromContext(ctx)
+
+	config, configErr := r.instanceConfig(ctx, aquaInstance)
+
+	if configErr != nil {
+		logger.Error(configErr, "Failed to build configuration for AquaInstance", "instance", aquaInstance.Name)
+		return configErr
+	}
+
+	aquaAuth, authErr := utils.SetAquaInstanceAuth(aquaInstance.Name, config)
+
+	if authErr != nil {
+		logger.Error(authErr, "Failed to register AquaInstance", "instance", aquaInstance.Name)
+		return authErr
+	}
+
+	_, jwtErr := aquaAuth.GetJWT()
+
+	if jwtErr != nil {
+		logger.Error(jwtErr, "AquaInstance login check Failed", "instance", aquaInstance.Name)
+		return jwtErr
+	}
+
+	// read again on every check so an upgrade of aqua is picked up by the AquaScannerAccounts using the instance
+	aquaAPI, aquaVersion, apiErr := aquaAuth.DetectAPI(ctx)
+
+	aquaInstance.Status.AquaVersion = aquaVersion.String()
+	aquaInstance.Status.APIGeneration = ""
+	if aquaAPI != nil {
+		aquaInstance.Status.APIGeneration = aquaAPI.Generation()
+	}
+	if aquaVersion.Raw != "" {
+		recordAquaVersion(aquaInstance.Name, aquaVersion, aquaAPI)
+	}
+
+	if apiErr != nil {
+		logger.Error(apiErr, "AquaInstance version check Failed", "instance", aquaInstance.Name, "version", aquaVersion.String())
+	}
+
+	return apiErr
+}
+
+// reads the credentials, CA bundle and client certificate referenced by the AquaInstance
+func (r *AquaInstanceReconciler) instanceConfig(ctx context.Context, aquaInstance *asa.AquaInstance) (utils.AquaInstanceConfig, error) {
+	spec := aquaInstance.Spec
+
+	config := utils.AquaInstanceConfig{
+		Url: spec.URL,
+		Http: utils.AquaHttpConfig{
+			InsecureSkipVerify: spec.TLS.InsecureSkipVerify,
+			MinTLSVersion:      spec.TLS.MinVersion,
+			ProxyUrl:           spec.HTTP.ProxyURL,
+			NoProxy:            spec.HTTP.NoProxy,
+		},
+	}
+
+	if spec.HTTP.ConnectTimeout != nil {
+		config.Http.ConnectTimeout = spec.HTTP.ConnectTimeout.Duration
+	}
+
+	if spec.HTTP.ReadTimeout != nil {
+		config.Http.ReadTimeout = spec.HTTP.ReadTimeout.Duration
+	}
+
+	creds := &corev1.Secret{}
+
+	if err := r.Get(ctx, types.NamespacedName{Name: spec.CredentialsSecretRef.Name, Namespace: spec.CredentialsSecretRef.Namespace}, creds); err != nil {
+		return config, err
+	}
+
+	config.Credentials = utils.AquaCredentialsFromSecretData(creds.Data)
+
+	if spec.AuthMethod != "" {
+		config.Credentials.Method = spec.AuthMethod
+	}
+
+	if caRef := spec.TLS.CABundleSecretRef; caRef != nil {
+		caSecret := &corev1.Secret{}
+
+		if err := r.Get(ctx, types.NamespacedName{Name: caRef.Name, Namespace: caRef.Namespace}, caSecret); err != nil {
+			return config, err
+		}
+
+		config.Http.CABundle = append(config.Http.CABundle, caSecret.Data[keyOrDefault(caRef.Key, defaultCABundleKey)]...)
+	}
+
+	if caRef := spec.TLS.CABundleConfigMapRef; caRef != nil {
+		caConfigMap := &corev1.ConfigMap{}
+
+		if err := r.Get(ctx, types.NamespacedName{Name: caRef.Name, Namespace: caRef.Namespace}, caConfigMap); err != nil {
+			return config, err
+		}
+
+		config.Http.CABundle = append(config.Http.CABundle, []byte(caConfigMap.Data[keyOrDefault(caRef.Key, defaultCABundleKey)])...)
+	}
+
+	if certRef := spec.TLS.ClientCertificateSecretRef; certRef != nil {
+		certSecret := &corev1.Secret{}
+
+		if err := r.Get(ctx, types.NamespacedName{Name: certRef.Name, Namespace: certRef.Namespace}, certSecret); err != nil {
+			return config, err
+		}
+
+		config.Http.ClientCert = certSecret.Data[corev1.TLSCertKey]
+		config.Http.ClientKey = certSecret.Data[corev1.TLSPrivateKeyKey]
+	}
+
+	return config, nil
+}
+
+func keyOrDefault(key string, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+	return key
+}
+
+func (r *AquaInstanceReconciler) updateHealth(ctx context.Context, aquaInstance *asa.AquaInstance, checkErr error) error {
+	logger := log.FromContext(ctx)
+
+	now := metav1.Now()
+	aquaInstance.Status.LastCheckTime = &now
+
+	// AquaScannerAccounts are not reconciled in a Degraded instance
+	degraded := metav1.Condition{Type: asa.AquaInstanceConditionDegraded, ObservedGeneration: aquaInstance.Generation}
+
+	if checkErr != nil {
+		aquaInstance.Status.Healthy = false
+		aquaInstance.Status.Message = checkErr.Error()
+		degraded.Status, degraded.Reason, degraded.Message = metav1.ConditionTrue, "CheckFailed", checkErr.Error()
+		aquaInstanceHealthy.WithLabelValues(aquaInstance.Name).Set(0)
+		aquaInstanceLoginFailures.WithLabelValues(aquaInstance.Name).Inc()
+	} else {
+		aquaInstance.Status.Healthy = true
+		aquaInstance.Status.Message = "Aqua login check Passed"
+		degraded.Status, degraded.Reason, degraded.Message = metav1.ConditionFalse, "CheckPassed", aquaInstance.Status.Message
+		aquaInstanceHealthy.WithLabelValues(aquaInstance.Name).Set(1)
+	}
+	meta.SetStatusCondition(&aquaInstance.Status.Conditions, degraded)
+
+	err := r.Status().Update(ctx, aquaInstance)
+
+	if err != nil {
+		logger.Error(err, "Failed to update AquaInstance status", "instance", aquaInstance.Name)
+	}
+
+	return err
+}
+
+// maps a secret or configmap to the AquaInstances that reference it so that credential, CA and client certificate
+// changes are picked up right away
+func (r *AquaInstanceReconciler) instancesForObject(object client.Object) []reconcile.Request {
+	aquaInstances := &asa.AquaInstanceList{}
+
+	if err := r.List(context.Background(), aquaInstances); err != nil {
+		aquaInstanceLog.Error(err, "Failed to list AquaInstances", "object", object.GetName())
+		return nil
+	}
+
+	_, isConfigMap := object.(*corev1.ConfigMap)
+
+	var requests []reconcile.Request
+
+	for _, aquaInstance := range aquaInstances.Items {
+		tlsSpec := aquaInstance.Spec.TLS
+		var refs []types.NamespacedName
+
+		if isConfigMap {
+			if tlsSpec.CABundleConfigMapRef != nil {
+				refs = append(refs, types.NamespacedName{Name: tlsSpec.CABundleConfigMapRef.Name, Namespace: tlsSpec.CABundleConfigMapRef.Namespace})
+			}
+		} else {
+			refs = append(refs, types.NamespacedName{Name: aquaInstance.Spec.CredentialsSecretRef.Name, Namespace: aquaInstance.Spec.CredentialsSecretRef.Namespace})
+			if tlsSpec.CABundleSecretRef != nil {
+				refs = append(refs, types.NamespacedName{Name: tlsSpec.CABundleSecretRef.Name, Namespace: tlsSpec.CABundleSecretRef.Namespace})
+			}
+			if tlsSpec.ClientCertificateSecretRef != nil {
+				refs = append(refs, types.NamespacedName{Name: tlsSpec.ClientCertificateSecretRef.Name, Namespace: tlsSpec.ClientCertificateSecretRef.Namespace})
+			}
+		}
+
+		for _, ref := range refs {
+			if ref.Name == object.GetName() && ref.Namespace == object.GetNamespace() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: aquaInstance.Name}})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AquaInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Client = newTracingClient(r.Client)
+	return ctrl.NewControllerManagedBy(mgr).
+		// status updates from the health check must not retrigger the health check
+		For(&asa.AquaInstance{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the manager only caches Secrets and ConfigMaps with utils.WatchLabel, a change to a referenced object without
+		// it is picked up by the next health check
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.instancesForObject), builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.instancesForObject), builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Complete(traced("AquaInstance", r))
+}
@@ -18,27 +18,90 @@ package controllers
 
 import (
 	"context"
-	"os"
-	"strconv"
+	"crypto/rand"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	configv1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/config/v1alpha1"
 	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
 	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
 )
 
 const aquaScannerAccountFinalizer = "mamoa.devops.gov.bc.ca/finalizer"
 
+// event reasons recorded on AquaScannerAccounts
+const (
+	// a new plan of an account in dry run mode
+	dryRunPlannedReason = "DryRunPlanned"
+	// a rotation requested with the rotate-credentials annotation was applied to aqua
+	credentialsRotatedReason = "CredentialsRotated"
+	// a resync requested with the resync annotation was applied to aqua
+	resyncedReason = "Resynced"
+	// spec.delivery.tekton is set but Tekton is not installed in the cluster
+	tektonNotInstalledReason = "TektonNotInstalled"
+	// the aqua instance of the account runs a version the operator does not support
+	unsupportedAquaVersionReason = "UnsupportedAquaVersion"
+)
+
+// why a reconcile changes aqua, recorded with every change in the audit trail
+const (
+	auditReasonCreate            = "Create"
+	auditReasonRotationRequested = "RotationRequested"
+	auditReasonRotationDue       = "RotationDue"
+	auditReasonResyncRequested   = "ResyncRequested"
+	auditReasonSpecChanged       = "SpecChanged"
+	// the credentials secret of a Complete account was deleted, a new password is set
+	auditReasonCredentialsLost = "CredentialsLost"
+	auditReasonDeleted         = "Deleted"
+)
+
+// logs what happens outside of a reconcile of an AquaScannerAccount
+var aquaScannerAccountLog = componentLogger("aquascanneraccount")
+
 // AquaScannerAccountReconciler reconciles a AquaScannerAccount object
 type AquaScannerAccountReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// the manager's config file after defaulting and validation, a defaulted config is used when nil
+	Config *configv1alpha1.OperatorConfig
+	// the operator's own aqua instance, used for AquaScannerAccounts that do not resolve to an AquaInstance.
+	// Defaults to utils.GetAquaAuth
+	AquaAuth *utils.AquaAuth
+	// reads the credentials Secret without going through the cache. Defaults to the manager's APIReader
+	APIReader client.Reader
+	// where the changes made in aqua are recorded, nil keeps no audit trail
+	Audit utils.AuditSink
+
+	// built from namespaces.selector of the config, nil when every watched namespace is reconciled
+	namespaceSelector labels.Selector
 }
 
 type AquaObjectState struct {
@@ -51,6 +114,12 @@ type AquaObjectState struct {
 //+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquascanneraccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquascanneraccounts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquascanneraccounts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=mamoa.devops.gov.bc.ca,resources=aquainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=tekton.dev,resources=tasks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -59,63 +128,173 @@ type AquaObjectState struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 
 func (r *AquaScannerAccountReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
 
 	// Fetch the aqua scanner account instance
-	aquaScannerAccount := &asa.AquaScannerAccount{}
+	aquaScannerAccount := &asav2.AquaScannerAccount{}
 
 	err := r.Get(ctx, req.NamespacedName, aquaScannerAccount)
-	namespacePrefix := strings.TrimSuffix(req.Namespace, "-tools")
-	aquaScannerAccountName := "ScannerCLI_" + namespacePrefix
-
-	// set env var for aqua auth check when the variable is unset
-	if os.Getenv("ASA_LOGIN_CHECK_DID_FAIL") == "" {
-		utils.SetEnvForAsaLoginCheck(utils.GetAquaAuth().GetJWT, ctrl.Log)
-	}
-
-	aquaLoginCheckFailed, boolCastErr := strconv.ParseBool(os.Getenv("ASA_LOGIN_CHECK_DID_FAIL"))
-
-	if boolCastErr != nil {
-		ctrl.Log.Error(boolCastErr, "failed to parse boolean from env var ASA_LOGIN_CHECK_DID_FAIL")
-		return ctrl.Result{}, boolCastErr
-	}
 
 	if err != nil {
 		if errors.IsNotFound(err) {
-			ctrl.Log.Error(err, "AquaScannerAccount not found. Ignoring as this object is deleted")
+			logger.Info("AquaScannerAccount not found. Ignoring as this object is deleted")
 			return ctrl.Result{}, nil
 		}
-		ctrl.Log.Error(err, "Failed to get AquaScannerAccount %v", req.NamespacedName)
+		logger.Error(err, "Failed to get AquaScannerAccount")
 		// if another error it means we failed to get the AquaScannerAccount
 		return ctrl.Result{}, err
 	}
+
+	if !r.isSelectedNamespace(aquaScannerAccount) {
+		// requeues bypass the event filter so accounts in a namespace that stopped matching are checked here as well
+		logger.Info("AquaScannerAccount is in a namespace that does not match namespaces.selector. Ignoring")
+		return ctrl.Result{}, nil
+	}
+
+	// the status is built up in memory during the reconcile and written once at the end
+	original := aquaScannerAccount.DeepCopy()
+
+	result, reconcileErr := r.reconcileAquaScannerAccount(ctx, req, aquaScannerAccount, original)
+
+	setReadyCondition(aquaScannerAccount)
+
+	patchErr := utils.PatchStatus(ctx, aquaScannerAccount, original, r.Status(), logger)
+
+	if errors.IsNotFound(patchErr) {
+		// the finalizer was removed and the object is gone
+		return result, reconcileErr
+	}
+	if errors.IsConflict(patchErr) {
+		// someone else changed the object while it was reconciled, the reconcile is retried against the latest version.
+		// Everything done in aqua is idempotent so repeating it is safe
+		logger.Info("AquaScannerAccount was modified during reconcilliation, requeueing")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if patchErr != nil {
+		return ctrl.Result{Requeue: true}, patchErr
+	}
+
+	return result, reconcileErr
+}
+
+func (r *AquaScannerAccountReconciler) reconcileAquaScannerAccount(ctx context.Context, req ctrl.Request, aquaScannerAccount *asav2.AquaScannerAccount, original *asav2.AquaScannerAccount) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	requiredSuffix := *r.Config.Namespaces.RequiredSuffix
+	namespacePrefix := strings.TrimSuffix(req.Namespace, requiredSuffix)
+
 	// if in wrong namespace
-	if !strings.HasSuffix(req.Namespace, "-tools") {
-		errorMessage := "AquaScannerAccount not allowed to be created in a non '-tools' namespace"
+	if !strings.HasSuffix(req.Namespace, requiredSuffix) {
+		errorMessage := "AquaScannerAccount not allowed to be created in a non '" + requiredSuffix + "' namespace"
 		err := errors.NewBadRequest(errorMessage)
 
-		ctrl.Log.Error(err, "AquaScannerAccount can only be created in namespaces ending in -tools. It was created in %v", req.Namespace)
+		logger.Error(err, "AquaScannerAccount can only be created in namespaces ending in the required suffix", "requiredSuffix", requiredSuffix)
+
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: errorMessage})
+
+		return ctrl.Result{}, err
+	}
+
+	aquaScannerAccountName, nameErr := r.Config.Naming.AccountName(configv1alpha1.AccountNameData{Namespace: req.Namespace, NamespacePrefix: namespacePrefix})
+
+	if nameErr != nil {
+		logger.Error(nameErr, "Failed to render the aqua account name from naming.accountNameTemplate")
+
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: "Unable to name the aqua account: " + nameErr.Error()})
+
+		return ctrl.Result{}, nameErr
+	}
+
+	instanceName, aquaAuth, aquaLoginCheckFailed, instanceErr := r.resolveAquaInstance(ctx, aquaScannerAccount)
+
+	if instanceErr != nil {
+		logger.Error(instanceErr, "Failed to resolve the AquaInstance for AquaScannerAccount", "instanceRef", aquaScannerAccount.Spec.InstanceRef)
+
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: instanceErr.Error()})
+
+		return ctrl.Result{RequeueAfter: aquaInstanceHealthCheckInterval}, nil
+	}
+
+	aquaAPI, aquaVersion, apiErr := aquaAuth.API(ctx)
 
-		updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, asa.AquaScannerAccountStatus{State: "Failed", Message: errorMessage}, r.Status(), ctrl.Log)
+	if aquaVersion.Raw != "" {
+		recordAquaVersion(instanceName, aquaVersion, aquaAPI)
+	}
+
+	switch {
+	case utils.IsUnsupportedAquaVersion(apiErr):
+		// nothing is sent to an aqua the operator does not know how to talk to, not even to delete the account
+		logger.Error(apiErr, "Refusing to reconcile the AquaScannerAccount against an unsupported version of aqua", "instance", instanceName, "version", aquaVersion.String())
+
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: apiErr.Error(), AquaVersion: aquaVersion.String()})
+		r.Recorder.Event(aquaScannerAccount, corev1.EventTypeWarning, unsupportedAquaVersionReason, apiErr.Error())
 
-		if updateErr != nil {
-			return ctrl.Result{Requeue: true}, updateErr
+		return ctrl.Result{RequeueAfter: aquaInstanceHealthCheckInterval}, nil
+	case apiErr != nil && !aquaLoginCheckFailed:
+		logger.Error(apiErr, "Failed to read the version of aqua", "instance", instanceName, "url", aquaAuth.GetUrl())
+
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: "Unable to read the version of aqua: " + apiErr.Error()})
+
+		return ctrl.Result{Requeue: true}, apiErr
+	case apiErr == nil:
+		// SetStatus refreshes the timestamp, an unchanged version must not cause a status patch
+		if previous := aquaScannerAccount.Status.AquaVersion; previous != aquaVersion.String() {
+			if previous != "" {
+				logger.Info("Aqua version changed", "previousVersion", previous, "version", aquaVersion.String(), "api", aquaAPI.Generation())
+			}
+			utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{AquaVersion: aquaVersion.String()})
 		}
+	}
 
-		return ctrl.Result{}, err
+	templates := utils.AquaTemplates{Dir: r.Config.Templates.Directory, Overrides: r.Config.Templates.Overrides}
+
+	password, legacyPassword, passwordErr := r.currentPassword(ctx, aquaScannerAccount)
+
+	if passwordErr != nil {
+		logger.Error(passwordErr, "Failed to read the password of the AquaScannerAccount from its credentials secret")
+		return ctrl.Result{Requeue: true}, passwordErr
+	}
+
+	if legacyPassword {
+		// the v2 status has no field for the password, it is moved to the Secret before the status is written as v2
+		logger.Info("Moving the password of the AquaScannerAccount from status.accountSecret to its credentials secret")
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{AccountName: aquaScannerAccountName})
+
+		if err := r.reconcileCredentialsSecret(ctx, aquaScannerAccount, aquaAuth.GetUrl(), password); err != nil {
+			logger.Error(err, "Failed to move the password of the AquaScannerAccount to its credentials secret")
+			return ctrl.Result{Requeue: true}, err
+		}
+	}
+
+	auditSubject := &utils.AuditSubject{
+		Kind:        "AquaScannerAccount",
+		Namespace:   aquaScannerAccount.Namespace,
+		Name:        aquaScannerAccount.Name,
+		UID:         aquaScannerAccount.UID,
+		ReconcileID: reconcileIDFrom(ctx),
+		Reason:      auditReasonCreate,
+	}
+
+	graph, graphErr := utils.NewAquaResourceGraph(r.aquaResources(logger, aquaAuth, aquaAPI, templates, aquaScannerAccount, aquaScannerAccountName, namespacePrefix, password, auditSubject)...)
+
+	if graphErr != nil {
+		logger.Error(graphErr, "Invalid aqua resource dependencies")
+		return ctrl.Result{}, graphErr
 	}
 
 	// initialize desired state
 	desiredState, shouldUpdateDesiredState := utils.SetDesiredStateIfNeeded(aquaScannerAccount.Status.DesiredState)
 	if shouldUpdateDesiredState {
 
-		ctrl.Log.Info("AquaScannerObject is being initialized with the desiredState because is has not been set")
+		logger.Info("AquaScannerObject is being initialized with the desiredState because is has not been set")
 
-		updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, asa.AquaScannerAccountStatus{DesiredState: desiredState}, r.Status(), ctrl.Log)
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{DesiredState: desiredState})
+	}
 
-		if updateErr != nil {
-			return ctrl.Result{Requeue: true}, updateErr
-		}
+	// in dry run mode nothing is changed in aqua and the finalizer is left as it is, only status.plan is written
+	dryRun := r.Config.Reconcile.DryRun || aquaScannerAccount.IsDryRun()
+	if !dryRun {
+		r.clearPlan(aquaScannerAccount)
 	}
 
 	// Check if the AquaScannerAccount instance is marked to be deleted, which is
@@ -123,19 +302,41 @@ func (r *AquaScannerAccountReconciler) Reconcile(ctx context.Context, req ctrl.R
 	isAquaScannerAccountMarkedToBeDeleted := aquaScannerAccount.GetDeletionTimestamp() != nil
 	if isAquaScannerAccountMarkedToBeDeleted {
 		if controllerutil.ContainsFinalizer(aquaScannerAccount, aquaScannerAccountFinalizer) {
+			if dryRun {
+				// the account is only deleted once it leaves dry run mode so its aqua objects are not orphaned
+				planTeardown := graph.PlanTeardown
+				if aquaScannerAccount.RetainsAquaObjects() {
+					planTeardown = func(ctx context.Context) ([]asav2.AquaPlannedChange, error) { return nil, nil }
+				}
+				return r.planAquaScannerAccount(ctx, aquaScannerAccount, planTeardown)
+			}
+
 			// Run finalization logic for aquaScannerAccountFinalizer. If the
 			// finalization logic fails, don't remove the finalizer so
 			// that we can retry during the next reconciliation.
-			if err := r.finalizeAquaScannerAccount(ctrl.Log, aquaScannerAccount, aquaScannerAccountName); err != nil {
+			auditSubject.Reason = auditReasonDeleted
+			if aquaScannerAccount.RetainsAquaObjects() {
+				logger.Info("Leaving the aqua objects of the AquaScannerAccount in aqua, its deletionPolicy is Retain")
+			} else if err := r.finalizeAquaScannerAccount(ctx, graph); err != nil {
 				return ctrl.Result{Requeue: true}, err
 			}
 
+			// the credentials Secret is garbage collected with the account, ServiceAccounts must not keep pointing at it
+			for _, serviceAccount := range aquaScannerAccount.Status.ServiceAccounts {
+				if _, err := r.patchServiceAccount(ctx, aquaScannerAccount.Namespace, serviceAccount, func(sa *corev1.ServiceAccount) bool {
+					return utils.UnlinkServiceAccount(sa, aquaScannerAccount.CredentialsSecretName())
+				}); err != nil {
+					logger.Error(err, "Failed to unlink ServiceAccount from the AquaScannerAccount", "serviceAccount", serviceAccount)
+					return ctrl.Result{Requeue: true}, err
+				}
+			}
+
 			// Remove aquaScannerAccountFinalizer. Once all finalizers have been
 			// removed, the object will be deleted.
 			controllerutil.RemoveFinalizer(aquaScannerAccount, aquaScannerAccountFinalizer)
-			err := r.Update(ctx, aquaScannerAccount)
+			err := r.updatePreservingStatus(ctx, aquaScannerAccount, original)
 			if err != nil {
-				ctrl.Log.Error(err, "Failed to remove aquaScannerAccount Finalizer")
+				logger.Error(err, "Failed to remove aquaScannerAccount Finalizer")
 				return ctrl.Result{Requeue: true}, err
 			}
 		}
@@ -143,11 +344,11 @@ func (r *AquaScannerAccountReconciler) Reconcile(ctx context.Context, req ctrl.R
 	}
 
 	// Add finalizer for this CR
-	if !controllerutil.ContainsFinalizer(aquaScannerAccount, aquaScannerAccountFinalizer) {
+	if !dryRun && !controllerutil.ContainsFinalizer(aquaScannerAccount, aquaScannerAccountFinalizer) {
 		controllerutil.AddFinalizer(aquaScannerAccount, aquaScannerAccountFinalizer)
-		err = r.Update(ctx, aquaScannerAccount)
+		err := r.updatePreservingStatus(ctx, aquaScannerAccount, original)
 		if err != nil {
-			ctrl.Log.Error(err, "Failed to add aquaScannerAccount Finalizer")
+			logger.Error(err, "Failed to add aquaScannerAccount Finalizer")
 			return ctrl.Result{Requeue: true}, err
 		}
 	}
@@ -155,244 +356,874 @@ func (r *AquaScannerAccountReconciler) Reconcile(ctx context.Context, req ctrl.R
 	if aquaLoginCheckFailed {
 		errorMessage := "AquaScannerAccount failed to authenticate with Aqua API. Reconcilliation Failed."
 
-		updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, asa.AquaScannerAccountStatus{State: "Failed", Message: errorMessage}, r.Status(), ctrl.Log)
-
-		if updateErr != nil {
-			return ctrl.Result{Requeue: true}, updateErr
-		}
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: errorMessage})
 
 		err := errors.NewUnauthorized(errorMessage)
 
-		aquaUrl := os.Getenv("AQUA_URL")
-		aquaUsername := os.Getenv("AQUA_USER")
-
-		ctrl.Log.Error(err, "AquaScannerAccount did not authenticate with Aqua. This is required for reconciliation. Does the manager have the correct credentials to authenticate with Aqua ( url: "+aquaUrl+" user: "+aquaUsername+")?")
+		logger.Error(err, "AquaScannerAccount did not authenticate with Aqua. This is required for reconciliation. Does the manager have the correct credentials to authenticate with Aqua?", "instance", instanceName, "url", aquaAuth.GetUrl(), "authMethod", aquaAuth.GetAuthMethod())
 		return ctrl.Result{}, err
 	}
 
-	if aquaScannerAccount.Status.State != "Complete" {
+	if dryRun {
+		// planned even when the account is Complete so template and operator changes can be previewed
+		return r.planAquaScannerAccount(ctx, aquaScannerAccount, graph.Plan)
+	}
 
-		newStatus := asa.AquaScannerAccountStatus{State: "Running", Message: "Beginning reconcilliation"}
+	// a rotation or resync requested through the annotations, a password older than spec.rotation.interval, or a
+	// change to the spec such as spec.scope, re-applies the aqua objects of a Complete account
+	rotationRequested := aquaScannerAccount.RotationRequested()
+	resyncRequested := aquaScannerAccount.ResyncRequested()
+	specChanged := aquaScannerAccount.SpecChanged()
+	rotationDueIn, rotates := aquaScannerAccount.RotationDueIn(time.Now())
+	rotationDue := rotates && rotationDueIn <= 0
+
+	// a Complete account whose credentials secret was deleted gets a new password, the old one can not be recovered
+	if aquaScannerAccount.Status.State != "Complete" || rotationRequested || rotationDue || resyncRequested || specChanged || password == "" {
+
+		newStatus := asav2.AquaScannerAccountStatus{State: "Running", Message: "Beginning reconcilliation", Instance: instanceName, AccountName: aquaScannerAccountName}
+
+		switch {
+		case rotationRequested:
+			auditSubject.Reason = auditReasonRotationRequested
+		case rotationDue:
+			auditSubject.Reason = auditReasonRotationDue
+		case resyncRequested:
+			auditSubject.Reason = auditReasonResyncRequested
+		case aquaScannerAccount.Status.State == "Complete" && password == "":
+			auditSubject.Reason = auditReasonCredentialsLost
+		case aquaScannerAccount.Status.State == "Complete":
+			auditSubject.Reason = auditReasonSpecChanged
+		}
 
 		// if this is the first time reconciling the CR the currentState will be empty and needs to be initialized
-		if aquaScannerAccount.Status.CurrentState == (asa.AquaScannerAccountAquaObjectState{}) {
-			newStatus.CurrentState = asa.AquaScannerAccountAquaObjectState{ApplicationScope: asa.NotCreated.String(), PermissionSet: asa.NotCreated.String(), Role: asa.NotCreated.String(), User: asa.NotCreated.String()}
+		if aquaScannerAccount.Status.CurrentState == (asav2.AquaScannerAccountAquaObjectState{}) {
+			for _, kind := range graph.Kinds() {
+				newStatus.CurrentState.Set(kind, asav2.NotCreated.String())
+			}
 		}
 
-		updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+		utils.SetStatus(aquaScannerAccount, newStatus)
 
-		if updateErr != nil {
-			return ctrl.Result{Requeue: true}, updateErr
+		if rotationRequested || rotationDue {
+			// the new password is generated and stored below, Apply then updates the existing user with it
+			logger.Info("Rotating the password of the AquaScannerAccount", "requested", rotationRequested)
+			password = ""
 		}
-
-		applicationScope := utils.ApplicationScope{
-			Name:               aquaScannerAccountName,
-			Description:        "Application Scoped to " + namespacePrefix + "-* and DockerHub only.",
-			TechnicalLeadEmail: "",
-			NamespacePrefix:    namespacePrefix,
+		if rotationRequested {
+			aquaScannerAccount.Status.LastRotation = aquaScannerAccount.GetAnnotations()[asav2.RotateCredentialsAnnotation]
+		}
+		if resyncRequested {
+			logger.Info("Resyncing the aqua objects of the AquaScannerAccount")
+			aquaScannerAccount.Status.LastResync = aquaScannerAccount.GetAnnotations()[asav2.ResyncAnnotation]
 		}
+		if specChanged {
+			logger.Info("Re-applying the aqua objects of the AquaScannerAccount, its spec changed", "generation", aquaScannerAccount.Generation)
+		}
+
+		if password == "" {
+			// the password has to be stored before the user is created so that a user created by a reconcile that
+			// fails afterwards is recreated with the same password
+			var generated string
+			generateErr := traceStep(ctx, "GeneratePassword", func(ctx context.Context) (err error) {
+				generated, err = r.generatePassword(ctx, aquaAuth, aquaAPI)
+				return err
+			})
+			if generateErr != nil {
+				logger.Error(generateErr, "Failed to generate a password for the AquaScannerAccount")
+				utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: "Failed to generate a password. " + generateErr.Error() + ". Will re-attempt."})
+				return ctrl.Result{Requeue: true}, generateErr
+			}
+			password = generated
+			utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{LastRotationTime: &metav1.Time{Time: time.Now()}})
+
+			if checkpointErr := r.reconcileCredentialsSecret(ctx, aquaScannerAccount, aquaAuth.GetUrl(), password); checkpointErr != nil {
+				logger.Error(checkpointErr, "Failed to store the new password in the credentials secret of the AquaScannerAccount")
+				return ctrl.Result{Requeue: true}, checkpointErr
+			}
 
-		permissionSet := utils.PermissionSet{
-			Name:               aquaScannerAccountName,
-			Description:        "Permission Set for AquaScannerAccount: UI read and scan read/write priviledges only",
-			TechnicalLeadEmail: "",
+			// the user resource is rebuilt with the new password, the dependencies are unchanged so this can not fail
+			graph, _ = utils.NewAquaResourceGraph(r.aquaResources(logger, aquaAuth, aquaAPI, templates, aquaScannerAccount, aquaScannerAccountName, namespacePrefix, password, auditSubject)...)
 		}
 
-		role := utils.Role{
-			Name:             aquaScannerAccountName,
-			Description:      "AquaScannerAccount created Role to allow Scanning of resources scoped to " + namespacePrefix + "-* and DockerHub only.",
-			ApplicationScope: applicationScope,
-			PermissionSet:    permissionSet,
+		var results map[string]error
+		applyErr := traceStep(ctx, "ApplyAquaObjects", func(ctx context.Context) (err error) {
+			results, err = graph.Apply(ctx)
+			return err
+		})
+
+		newCurrentState := aquaScannerAccount.Status.CurrentState
+		for kind, result := range results {
+			if result == nil {
+				newCurrentState.Set(kind, asav2.Created.String())
+			} else {
+				newCurrentState.Set(kind, asav2.NotCreated.String())
+			}
 		}
 
-		if aquaScannerAccount.Status.CurrentState.ApplicationScope != aquaScannerAccount.Status.DesiredState.ApplicationScope {
-			applicationScopeErr := utils.CreateAquaApplicationScope(ctrl.Log, applicationScope)
+		if applyErr != nil {
+			logger.Error(applyErr, "Failed to create aqua objects")
 
-			if applicationScopeErr != nil {
-				ctrl.Log.Error(applicationScopeErr, "Failed to create application scope")
+			utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Failed", Message: "Reconcilliation failed. " + applyErr.Error() + ". Will re-attempt.", CurrentState: newCurrentState})
 
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.ApplicationScope = asa.NotCreated.String()
-				newStatus := asa.AquaScannerAccountStatus{State: "Failed", Message: "Reconcilliation failed. Was unable to create application scope. Will re-attempt.", CurrentState: newCurrentState}
+			return ctrl.Result{Requeue: true}, applyErr
+		}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{CurrentState: newCurrentState})
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+		// set status to Complete
+		if aquaScannerAccount.Status.CurrentState == aquaScannerAccount.Status.DesiredState {
+			utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{State: "Complete", Message: "Reconcilliation Successful!"})
 
-				return ctrl.Result{Requeue: true}, applicationScopeErr
-			} else {
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.ApplicationScope = asa.Created.String()
-				newStatus := asa.AquaScannerAccountStatus{CurrentState: newCurrentState}
+			if rotationRequested || rotationDue {
+				r.Recorder.Event(aquaScannerAccount, corev1.EventTypeNormal, credentialsRotatedReason, "The password of the aqua user was rotated")
+			}
+			if resyncRequested {
+				r.Recorder.Event(aquaScannerAccount, corev1.EventTypeNormal, resyncedReason, "The aqua objects were re-applied")
+			}
+		}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+	}
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+	if aquaScannerAccount.Status.State == "Complete" {
+		if err := traceStep(ctx, "DeliverCredentials", func(ctx context.Context) error {
+			return r.deliverCredentials(ctx, aquaScannerAccount, aquaAuth.GetUrl(), password)
+		}); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+
+		if rotationDueIn, rotates := aquaScannerAccount.RotationDueIn(time.Now()); rotates {
+			// the account is reconciled again when its password is due
+			if rotationDueIn < time.Second {
+				rotationDueIn = time.Second
 			}
+			return ctrl.Result{RequeueAfter: rotationDueIn}, nil
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// writes the credentials of a Complete account to the secrets, Task and ServiceAccounts they are delivered through
+func (r *AquaScannerAccountReconciler) deliverCredentials(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount, aquaURL string, password string) error {
+	logger := log.FromContext(ctx)
 
+	if err := r.reconcileCredentialsSecret(ctx, aquaScannerAccount, aquaURL, password); err != nil {
+		logger.Error(err, "Failed to write the credentials secret of the AquaScannerAccount")
+		return err
+	}
+	if err := r.reconcileJenkinsSecret(ctx, aquaScannerAccount, aquaURL, password); err != nil {
+		logger.Error(err, "Failed to write the Jenkins secret of the AquaScannerAccount")
+		return err
+	}
+	if err := r.reconcileTektonTask(ctx, aquaScannerAccount); err != nil {
+		logger.Error(err, "Failed to write the Tekton Task of the AquaScannerAccount")
+		return err
+	}
+	if err := r.reconcileServiceAccounts(ctx, aquaScannerAccount); err != nil {
+		logger.Error(err, "Failed to link the ServiceAccounts of the AquaScannerAccount")
+		return err
+	}
+	if err := r.reconcileCredentialReaders(ctx, aquaScannerAccount); err != nil {
+		logger.Error(err, "Failed to grant spec.delivery.credentialReaders access to the credentials of the AquaScannerAccount")
+		return err
+	}
+	return nil
+}
+
+// mirrors the state of the account in the Ready condition, for tools that wait on conditions such as kubectl wait
+func setReadyCondition(aquaScannerAccount *asav2.AquaScannerAccount) {
+	condition := metav1.Condition{
+		Type:               asav2.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Pending",
+		Message:            aquaScannerAccount.Status.Message,
+		ObservedGeneration: aquaScannerAccount.Generation,
+	}
+	if aquaScannerAccount.Status.State != "" {
+		condition.Reason = aquaScannerAccount.Status.State
+	}
+	if aquaScannerAccount.IsReady() {
+		condition.Status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&aquaScannerAccount.Status.Conditions, condition)
+}
+
+/*
+	Keeps the url and credentials of the account in a Secret owned by the account. The Secret is the only place the
+	password is kept, so that reading the account does not reveal it, and it is rewritten after a rotation.
+*/
+func (r *AquaScannerAccountReconciler) reconcileCredentialsSecret(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount, aquaURL string, password string) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: aquaScannerAccount.CredentialsSecretName(), Namespace: aquaScannerAccount.Namespace}}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Type = corev1.SecretTypeOpaque
+		utils.SetWatchLabel(secret)
+		secret.Data = map[string][]byte{
+			asav2.CredentialsSecretURLKey:      []byte(aquaURL),
+			asav2.CredentialsSecretUserKey:     []byte(aquaScannerAccount.Status.AccountName),
+			asav2.CredentialsSecretPasswordKey: []byte(password),
 		}
+		return controllerutil.SetControllerReference(aquaScannerAccount, secret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
 
-		if aquaScannerAccount.Status.CurrentState.PermissionSet != asa.Created.String() {
-			permissionSetErr := utils.CreateAquaPermissionSet(ctrl.Log, permissionSet)
+	// SetStatus refreshes the timestamp, the status of a Complete account is only patched when the reference changed
+	if ref := aquaScannerAccount.Status.CredentialsSecretRef; ref == nil || ref.Name != secret.Name {
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{CredentialsSecretRef: &corev1.LocalObjectReference{Name: secret.Name}})
+	}
+	return nil
+}
 
-			if permissionSetErr != nil {
-				ctrl.Log.Error(permissionSetErr, "Failed to create permission set")
+/*
+	Returns the password of the account from its credentials Secret, or from status.accountSecret of v1 for accounts an
+	older version of the operator stored before it moved passwords to the Secret, in which case the second return is
+	true. The password is empty when it was never generated or the Secret was deleted.
+*/
+func (r *AquaScannerAccountReconciler) currentPassword(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount) (string, bool, error) {
+	key := types.NamespacedName{Name: aquaScannerAccount.CredentialsSecretName(), Namespace: aquaScannerAccount.Namespace}
+	secret := &corev1.Secret{}
+
+	// a stale cached copy would hand back the password from before the last rotation
+	err := r.APIReader.Get(ctx, key, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", false, err
+	}
 
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.PermissionSet = asa.NotCreated.String()
-				newStatus := asa.AquaScannerAccountStatus{State: "Failed", Message: "Reconcilliation failed. Was unable to create permission set. Will re-attempt.", CurrentState: newCurrentState}
+	// a Secret someone else created under the same name is not trusted
+	if err == nil && metav1.IsControlledBy(secret, aquaScannerAccount) {
+		return string(secret.Data[asav2.CredentialsSecretPasswordKey]), false, nil
+	}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+	// v1 objects are read as they are stored, without going through the conversion that drops the password
+	legacyAccount := &asa.AquaScannerAccount{}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Name: aquaScannerAccount.Name, Namespace: aquaScannerAccount.Namespace}, legacyAccount); err != nil {
+		return "", false, client.IgnoreNotFound(err)
+	}
+	return legacyAccount.Status.AccountSecret, legacyAccount.Status.AccountSecret != "", nil
+}
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+/*
+	Generates a password following passwords of the config, made stricter by the password policy of aqua where it asks
+	for more so that aqua does not turn the user down. Aqua versions that do not expose a policy, or an aqua that does not
+	let the operator read it, get the configured one.
+*/
+func (r *AquaScannerAccountReconciler) generatePassword(ctx context.Context, aquaAuth *utils.AquaAuth, aquaAPI utils.AquaAPI) (string, error) {
+	passwords := r.Config.Passwords
+	policy := utils.PasswordPolicy{
+		Length:          passwords.Length,
+		RequiredClasses: passwords.RequiredClasses,
+		Symbols:         passwords.Symbols,
+	}
+	if passwords.MinEntropyBits != nil {
+		policy.MinEntropyBits = *passwords.MinEntropyBits
+	}
 
-				return ctrl.Result{Requeue: true}, permissionSetErr
-			} else {
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.PermissionSet = asa.Created.String()
-				newStatus := asa.AquaScannerAccountStatus{CurrentState: newCurrentState}
+	if configv1alpha1.IsEnabled(passwords.FollowAquaPolicy) {
+		if aquaPolicy, found := utils.GetAquaPasswordPolicy(ctx, log.FromContext(ctx), aquaAuth, aquaAPI); found {
+			policy = policy.MergeAquaPolicy(aquaPolicy)
+		}
+	}
+
+	return utils.GeneratePasswordWithPolicy(policy, rand.Reader)
+}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+/*
+	Delivers the credentials to the Secret the OpenShift Jenkins sync plugin reads when spec.delivery.jenkins is set, so
+	Jenkins picks up a rotated password without anyone touching it. The Secret it was delivered to before is deleted
+	when delivery is turned off or the Secret is renamed.
+*/
+func (r *AquaScannerAccountReconciler) reconcileJenkinsSecret(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount, aquaURL string, password string) error {
+	logger := log.FromContext(ctx)
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+	name := aquaScannerAccount.JenkinsSecretName()
+
+	if name != "" {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: aquaScannerAccount.Namespace}}
+
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+			if !secret.CreationTimestamp.IsZero() && !metav1.IsControlledBy(secret, aquaScannerAccount) {
+				return fmt.Errorf("secret %v already exists and is not managed by the AquaScannerAccount", name)
 			}
+			utils.SetJenkinsCredentials(secret, aquaScannerAccount, aquaURL, password)
+			utils.SetWatchLabel(secret)
+			return controllerutil.SetControllerReference(aquaScannerAccount, secret, r.Scheme)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if previous := aquaScannerAccount.Status.JenkinsSecret; previous != "" && previous != name {
+		if err := r.deleteOwned(ctx, aquaScannerAccount, &corev1.Secret{}, previous); err != nil {
+			return err
 		}
+		logger.Info("Deleted the previous Jenkins secret of the AquaScannerAccount", "secret", previous)
+	}
 
-		if aquaScannerAccount.Status.CurrentState.Role != asa.Created.String() {
-			roleErr := utils.CreateAquaRole(ctrl.Log, role)
+	// set directly rather than through SetStatus so turning delivery off clears it
+	aquaScannerAccount.Status.JenkinsSecret = name
+	return nil
+}
 
-			if roleErr != nil {
-				ctrl.Log.Error(roleErr, "Failed to create role")
+/*
+	Keeps a Role allowing get on the account's credentials and a RoleBinding of it to spec.delivery.credentialReaders. A
+	reader removed from the spec is removed from the RoleBinding and both are deleted once the list is empty, leaving
+	the Secret to the admins of the namespace.
+*/
+func (r *AquaScannerAccountReconciler) reconcileCredentialReaders(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount) error {
+	name := aquaScannerAccount.CredentialReadersRoleName()
 
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.Role = asa.NotCreated.String()
-				newStatus := asa.AquaScannerAccountStatus{State: "Failed", Message: "Reconcilliation failed. Was unable to create role. Will re-attempt.", CurrentState: newCurrentState}
+	if len(aquaScannerAccount.Spec.Delivery.CredentialReaders) == 0 {
+		// the RoleBinding goes first so there is never a binding to a missing Role
+		if err := r.deleteOwned(ctx, aquaScannerAccount, &rbacv1.RoleBinding{}, name); err != nil {
+			return err
+		}
+		if err := r.deleteOwned(ctx, aquaScannerAccount, &rbacv1.Role{}, name); err != nil {
+			return err
+		}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+		// set directly rather than through SetStatus so removing the last reader clears it
+		aquaScannerAccount.Status.CredentialReadersRole = ""
+		return nil
+	}
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+	role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: aquaScannerAccount.Namespace}}
 
-				return ctrl.Result{Requeue: true}, roleErr
-			} else {
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.Role = asa.Created.String()
-				newStatus := asa.AquaScannerAccountStatus{CurrentState: newCurrentState}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		if !role.CreationTimestamp.IsZero() && !metav1.IsControlledBy(role, aquaScannerAccount) {
+			return fmt.Errorf("role %v already exists and is not managed by the AquaScannerAccount", name)
+		}
+		utils.SetCredentialReadersRole(role, aquaScannerAccount)
+		return controllerutil.SetControllerReference(aquaScannerAccount, role, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+	binding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: aquaScannerAccount.Namespace}}
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
-			}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, binding, func() error {
+		if !binding.CreationTimestamp.IsZero() && !metav1.IsControlledBy(binding, aquaScannerAccount) {
+			return fmt.Errorf("rolebinding %v already exists and is not managed by the AquaScannerAccount", name)
 		}
+		utils.SetCredentialReadersRoleBinding(binding, aquaScannerAccount)
+		return controllerutil.SetControllerReference(aquaScannerAccount, binding, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
 
-		if aquaScannerAccount.Status.CurrentState.User != asa.Created.String() {
+	if aquaScannerAccount.Status.CredentialReadersRole != name {
+		utils.SetStatus(aquaScannerAccount, asav2.AquaScannerAccountStatus{CredentialReadersRole: name})
+	}
+	return nil
+}
 
-			var pwd *string
+/*
+	Creates the Tekton Task of the account when spec.delivery.tekton is set and rewrites its spec on every reconcile, so
+	it follows a change of imageScans.scannerImage once the operator restarts with it. The Task it created before is
+	deleted when spec.delivery.tekton is removed or the Task is renamed. Without Tekton in the cluster an event is
+	recorded and the rest of the account is reconciled as usual.
+*/
+func (r *AquaScannerAccountReconciler) reconcileTektonTask(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount) error {
+	logger := log.FromContext(ctx)
 
-			if aquaScannerAccount.Status.AccountSecret != "" {
-				pwd = &aquaScannerAccount.Status.AccountSecret
-			} else {
-				pw := utils.GeneratePassword(16, true, true, true)
-				pwd = &pw
+	name := aquaScannerAccount.TektonTaskName()
+
+	if name != "" {
+		task := &unstructured.Unstructured{}
+		task.SetGroupVersionKind(utils.TektonTaskGVK)
+		task.SetName(name)
+		task.SetNamespace(aquaScannerAccount.Namespace)
+
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, task, func() error {
+			if task.GetResourceVersion() != "" && !metav1.IsControlledBy(task, aquaScannerAccount) {
+				return fmt.Errorf("task %v already exists and is not managed by the AquaScannerAccount", name)
 			}
+			err := utils.SetTektonTask(task, aquaScannerAccount, utils.TektonTaskOptions{
+				ScannerImage:      r.Config.ImageScans.ScannerImage,
+				ReportImage:       r.Config.ImageScans.ReportImage,
+				CredentialsSecret: aquaScannerAccount.CredentialsSecretName(),
+			})
+			if err != nil {
+				return err
+			}
+			return controllerutil.SetControllerReference(aquaScannerAccount, task, r.Scheme)
+		})
+		if meta.IsNoMatchError(err) {
+			r.Recorder.Event(aquaScannerAccount, corev1.EventTypeWarning, tektonNotInstalledReason, "spec.delivery.tekton is set but Tekton is not installed in the cluster, no Task was created")
+			name = ""
+		} else if err != nil {
+			return err
+		}
+	}
 
-			user := utils.User{
-				Name:     aquaScannerAccountName,
-				Password: *pwd,
-				Role:     role,
+	if previous := aquaScannerAccount.Status.TektonTask; previous != "" && previous != name {
+		task := &unstructured.Unstructured{}
+		task.SetGroupVersionKind(utils.TektonTaskGVK)
+
+		err := r.Get(ctx, types.NamespacedName{Name: previous, Namespace: aquaScannerAccount.Namespace}, task)
+		if err == nil && metav1.IsControlledBy(task, aquaScannerAccount) {
+			if err = r.Delete(ctx, task); err == nil {
+				logger.Info("Deleted the previous Tekton Task of the AquaScannerAccount", "task", previous)
 			}
-			// update asa status before creating user just incase user creation fails, the user will be recreated with the
-			// same password as before
-			updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, asa.AquaScannerAccountStatus{AccountName: user.Name, AccountSecret: user.Password}, r.Status(), ctrl.Log)
-			if updateErr != nil {
-				return ctrl.Result{Requeue: true}, updateErr
+		}
+		if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return err
+		}
+	}
+
+	// set directly rather than through SetStatus so removing spec.delivery.tekton clears it
+	aquaScannerAccount.Status.TektonTask = name
+	return nil
+}
+
+/*
+	Links the credentials Secret to the ServiceAccounts in spec.delivery.serviceAccounts and unlinks it from the ones
+	removed from the list. The Secret keeps its name through rotations so the link stays valid, and ServiceAccounts are
+	watched so a recreated ServiceAccount is linked again.
+*/
+func (r *AquaScannerAccountReconciler) reconcileServiceAccounts(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount) error {
+	secretName := aquaScannerAccount.CredentialsSecretName()
+	wanted := map[string]bool{}
+	linked := []string{}
+
+	for _, serviceAccount := range aquaScannerAccount.Spec.Delivery.ServiceAccounts {
+		if wanted[serviceAccount] {
+			continue
+		}
+		wanted[serviceAccount] = true
+
+		exists, err := r.patchServiceAccount(ctx, aquaScannerAccount.Namespace, serviceAccount, func(sa *corev1.ServiceAccount) bool {
+			return utils.LinkServiceAccount(sa, secretName)
+		})
+		if err != nil {
+			return err
+		}
+		if exists {
+			linked = append(linked, serviceAccount)
+		}
+	}
+
+	for _, serviceAccount := range aquaScannerAccount.Status.ServiceAccounts {
+		if wanted[serviceAccount] {
+			continue
+		}
+		if _, err := r.patchServiceAccount(ctx, aquaScannerAccount.Namespace, serviceAccount, func(sa *corev1.ServiceAccount) bool {
+			return utils.UnlinkServiceAccount(sa, secretName)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// set directly rather than through SetStatus so emptying spec.delivery.serviceAccounts clears it
+	aquaScannerAccount.Status.ServiceAccounts = linked
+	return nil
+}
+
+/*
+	Patches the ServiceAccount when mutate changed it. The patch is optimistically locked since the secrets list is
+	replaced as a whole and the token controller may add to it at the same time. Returns false when the ServiceAccount
+	does not exist.
+*/
+func (r *AquaScannerAccountReconciler) patchServiceAccount(ctx context.Context, namespace string, name string, mutate func(*corev1.ServiceAccount) bool) (bool, error) {
+	serviceAccount := &corev1.ServiceAccount{}
+
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, serviceAccount)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	original := serviceAccount.DeepCopy()
+	if !mutate(serviceAccount) {
+		return true, nil
+	}
+	return true, r.Patch(ctx, serviceAccount, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+}
+
+// maps a ServiceAccount to the AquaScannerAccounts in its namespace that list it in spec.delivery.serviceAccounts
+func (r *AquaScannerAccountReconciler) accountsLinkingServiceAccount(object client.Object) []reconcile.Request {
+	aquaScannerAccounts := &asav2.AquaScannerAccountList{}
+
+	if err := r.List(context.Background(), aquaScannerAccounts, client.InNamespace(object.GetNamespace())); err != nil {
+		aquaScannerAccountLog.Error(err, "Failed to list AquaScannerAccounts in namespace", "namespace", object.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, aquaScannerAccount := range aquaScannerAccounts.Items {
+		for _, serviceAccount := range aquaScannerAccount.Spec.Delivery.ServiceAccounts {
+			if serviceAccount == object.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: aquaScannerAccount.Name, Namespace: aquaScannerAccount.Namespace}})
+				break
 			}
+		}
+	}
+	return requests
+}
 
-			userErr := utils.CreateAquaAccount(ctrl.Log, user)
+// deletes the object of the account called name into object, objects the account does not control are left alone
+func (r *AquaScannerAccountReconciler) deleteOwned(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount, object client.Object, name string) error {
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: aquaScannerAccount.Namespace}, object)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-			if userErr != nil {
-				ctrl.Log.Error(userErr, "Failed to create user")
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.User = asa.NotCreated.String()
-				newStatus := asa.AquaScannerAccountStatus{State: "Failed", Message: "Reconcilliation failed. Was unable to create aqua user. Will re-attempt.", CurrentState: newCurrentState}
+	if !metav1.IsControlledBy(object, aquaScannerAccount) {
+		return nil
+	}
+	if err := r.Delete(ctx, object); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
 
-				aquaScannerAccount.Status.State = "Failed"
+/*
+	Writes the changes plan would make in aqua to status.plan and the planned change metric. An event is recorded when
+	the plan changes. The account is planned again periodically so the plan follows changes made in aqua.
+*/
+func (r *AquaScannerAccountReconciler) planAquaScannerAccount(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount, plan func(context.Context) ([]asav2.AquaPlannedChange, error)) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var changes []asav2.AquaPlannedChange
+	planErr := traceStep(ctx, "PlanAquaObjects", func(ctx context.Context) (err error) {
+		changes, err = plan(ctx)
+		return err
+	})
+
+	newPlan := &asav2.AquaScannerAccountPlan{Changes: changes}
+	if planErr != nil {
+		logger.Error(planErr, "Failed to read the current state of aqua objects, the plan is incomplete")
+		newPlan.Error = planErr.Error()
+	}
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.Action]++
+	}
+	for _, action := range plannedChangeActions {
+		aquaScannerAccountPlannedChanges.WithLabelValues(aquaScannerAccount.Namespace, aquaScannerAccount.Name, action).Set(float64(counts[action]))
+	}
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+	previousPlan := aquaScannerAccount.Status.Plan
+	if previousPlan != nil && previousPlan.Error == newPlan.Error && equality.Semantic.DeepEqual(previousPlan.Changes, newPlan.Changes) {
+		// unchanged, the status is not rewritten
+		return ctrl.Result{RequeueAfter: aquaInstanceHealthCheckInterval}, planErr
+	}
 
-				return ctrl.Result{Requeue: true}, userErr
-			} else {
-				newCurrentState := aquaScannerAccount.Status.CurrentState
-				newCurrentState.User = asa.Created.String()
-				newStatus := asa.AquaScannerAccountStatus{CurrentState: newCurrentState}
+	newPlan.Timestamp = metav1.Timestamp{Seconds: time.Now().Unix()}
+	aquaScannerAccount.Status.Plan = newPlan
 
-				updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
+	r.Recorder.Event(aquaScannerAccount, corev1.EventTypeNormal, dryRunPlannedReason, describePlan(newPlan))
 
-				if updateErr != nil {
-					return ctrl.Result{Requeue: true}, updateErr
-				}
+	return ctrl.Result{RequeueAfter: aquaInstanceHealthCheckInterval}, planErr
+}
+
+// removes the plan of an account that left dry run mode
+func (r *AquaScannerAccountReconciler) clearPlan(aquaScannerAccount *asav2.AquaScannerAccount) {
+	aquaScannerAccount.Status.Plan = nil
+	for _, action := range plannedChangeActions {
+		aquaScannerAccountPlannedChanges.DeleteLabelValues(aquaScannerAccount.Namespace, aquaScannerAccount.Name, action)
+	}
+}
+
+// a one line summary of the plan for events, e.g. "Dry run: would Create User, Update Role (description)"
+func describePlan(plan *asav2.AquaScannerAccountPlan) string {
+	if len(plan.Changes) == 0 && plan.Error == "" {
+		return "Dry run: aqua is up to date, no changes would be made"
+	}
+
+	descriptions := []string{}
+	for _, change := range plan.Changes {
+		description := change.Action + " " + change.Kind
+		if len(change.Diff) > 0 {
+			fields := []string{}
+			for _, diff := range change.Diff {
+				fields = append(fields, diff.Field)
 			}
+			description += " (" + strings.Join(fields, ", ") + ")"
 		}
+		descriptions = append(descriptions, description)
+	}
 
-		// set status to Complete
-		if aquaScannerAccount.Status.CurrentState == aquaScannerAccount.Status.DesiredState {
-			newStatus := asa.AquaScannerAccountStatus{State: "Complete", Message: "Reconcilliation Successful!"}
+	message := "Dry run: would " + strings.Join(descriptions, ", ")
+	if len(plan.Changes) == 0 {
+		message = "Dry run: no changes planned"
+	}
+	if plan.Error != "" {
+		message += fmt.Sprintf(". The plan is incomplete: %v", plan.Error)
+	}
+	return message
+}
+
+/*
+	Updates the object, used for finalizers, without losing the status built up in memory. The response of the update
+	replaces the whole object so the status is put back afterwards and original is moved to the new resource version
+	for the status patch at the end of the reconcile.
+*/
+func (r *AquaScannerAccountReconciler) updatePreservingStatus(ctx context.Context, aquaScannerAccount *asav2.AquaScannerAccount, original *asav2.AquaScannerAccount) error {
+	status := aquaScannerAccount.Status
+
+	if err := r.Update(ctx, aquaScannerAccount); err != nil {
+		aquaScannerAccount.Status = status
+		return err
+	}
 
-			updateErr := utils.UpdateStatus(ctx, aquaScannerAccount, newStatus, r.Status(), ctrl.Log)
-			if updateErr != nil {
-				return ctrl.Result{Requeue: true}, updateErr
+	original.Status = aquaScannerAccount.Status
+	original.ResourceVersion = aquaScannerAccount.ResourceVersion
+	aquaScannerAccount.Status = status
+	return nil
+}
+
+/*
+	Resolves the aqua instance the AquaScannerAccount is provisioned in. Once objects have been created the instance
+	recorded in the status is used so that a change of the cluster default does not orphan them. An empty instance name
+	means the operator's own AQUA_URL, AQUA_USER and AQUA_PASSWORD are used.
+	The boolean return is true when the operator is unable to authenticate with the aqua instance.
+*/
+func (r *AquaScannerAccountReconciler) resolveAquaInstance(ctx context.Context, account *asav2.AquaScannerAccount) (string, *utils.AquaAuth, bool, error) {
+	logger := log.FromContext(ctx)
+
+	instanceName := account.Status.Instance
+
+	if instanceName == "" {
+		instanceName = account.Spec.InstanceRef
+	}
+
+	if instanceName != "" && !configv1alpha1.IsEnabled(r.Config.Features.AquaInstances) {
+		return instanceName, nil, false, errors.NewBadRequest("AquaInstance " + instanceName + " can not be used, the aquaInstances feature is disabled in the operator config")
+	}
+
+	if instanceName == "" && configv1alpha1.IsEnabled(r.Config.Features.AquaInstances) {
+		aquaInstances := &asa.AquaInstanceList{}
+
+		if err := r.List(ctx, aquaInstances); err != nil {
+			return "", nil, false, err
+		}
+
+		for _, aquaInstance := range aquaInstances.Items {
+			if aquaInstance.IsDefault() {
+				instanceName = aquaInstance.Name
+				break
 			}
 		}
+	}
 
+	if instanceName == "" {
+		// the login is checked once here, after that by the OperatorCredentials controller when the credentials change
+		aquaLoginCheckFailed, checked := r.AquaAuth.LoginCheckFailed()
+		if !checked {
+			aquaLoginCheckFailed = r.AquaAuth.CheckLogin(logger) != nil
+		}
+
+		return "", r.AquaAuth, aquaLoginCheckFailed, nil
 	}
-	return ctrl.Result{}, nil
+
+	aquaInstance := &asa.AquaInstance{}
+
+	if err := r.Get(ctx, types.NamespacedName{Name: instanceName}, aquaInstance); err != nil {
+		if errors.IsNotFound(err) {
+			return instanceName, nil, false, errors.NewBadRequest("AquaInstance " + instanceName + " does not exist")
+		}
+		return instanceName, nil, false, err
+	}
+
+	// an account being deleted may still tear down what it created in an instance that no longer allows its namespace
+	if account.GetDeletionTimestamp() == nil {
+		allowed, err := utils.AquaInstanceAllowsNamespace(ctx, r, instanceName, account.Namespace)
+		if err != nil {
+			return instanceName, nil, false, err
+		}
+		if !allowed {
+			return instanceName, nil, false, errors.NewBadRequest("AquaInstance " + instanceName + " does not allow AquaScannerAccounts in the namespace " + account.Namespace)
+		}
+	}
+
+	aquaAuth, ok := utils.GetAquaInstanceAuth(instanceName)
+
+	if !ok {
+		return instanceName, nil, false, errors.NewServiceUnavailable("AquaInstance " + instanceName + " has not been checked by the operator yet")
+	}
+
+	return instanceName, aquaAuth, !aquaInstance.Status.Healthy, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AquaScannerAccountReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&asa.AquaScannerAccount{}).
-		Complete(r)
+	if r.Config == nil {
+		r.Config = &configv1alpha1.OperatorConfig{}
+		r.Config.Default()
+	}
+	if r.AquaAuth == nil {
+		aquaAuth, err := utils.GetAquaAuth()
+		if err != nil {
+			return err
+		}
+		r.AquaAuth = aquaAuth
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("aqua-scanner-operator")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	r.Client = newTracingClient(r.Client)
+	r.APIReader = newTracingReader(r.APIReader, mgr.GetScheme())
+
+	if r.Config.Namespaces.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(r.Config.Namespaces.Selector)
+		if err != nil {
+			return err
+		}
+		r.namespaceSelector = selector
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&asav2.AquaScannerAccount{}, builder.WithPredicates(predicate.NewPredicateFuncs(r.isSelectedNamespace))).
+		Owns(&corev1.Secret{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		// a ServiceAccount that is recreated, or loses its link, is linked again
+		Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, handler.EnqueueRequestsFromMapFunc(r.accountsLinkingServiceAccount))
+
+	// Tasks are only watched, so that a deleted or edited Task is restored, when Tekton is installed
+	if _, err := mgr.GetRESTMapper().RESTMapping(utils.TektonTaskGVK.GroupKind(), utils.TektonTaskGVK.Version); err == nil {
+		task := &unstructured.Unstructured{}
+		task.SetGroupVersionKind(utils.TektonTaskGVK)
+		controllerBuilder = controllerBuilder.Owns(task)
+	}
+
+	if r.namespaceSelector != nil {
+		// a namespace that starts or stops matching the selector has its accounts reconciled
+		controllerBuilder = controllerBuilder.Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.accountsInNamespace), builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+
+	return controllerBuilder.
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Config.Reconcile.MaxConcurrentReconciles,
+			RateLimiter:             newRateLimiter(r.Config.Reconcile),
+		}).
+		Complete(traced("AquaScannerAccount", r))
 }
 
-func (r *AquaScannerAccountReconciler) finalizeAquaScannerAccount(reqLogger *log.DelegatingLogger, m *asa.AquaScannerAccount, aquaScannerName string) error {
+// returns true when the account's namespace matches namespaces.selector, or when no selector is configured
+func (r *AquaScannerAccountReconciler) isSelectedNamespace(object client.Object) bool {
+	if r.namespaceSelector == nil {
+		return true
+	}
 
-	delAcctErr := utils.DeleteAquaAccount(ctrl.Log, aquaScannerName)
-	if delAcctErr != nil {
-		return delAcctErr
+	namespace := &corev1.Namespace{}
+
+	if err := r.Get(context.Background(), types.NamespacedName{Name: object.GetNamespace()}, namespace); err != nil {
+		aquaScannerAccountLog.Error(err, "Failed to get namespace to match it against namespaces.selector", "namespace", object.GetNamespace())
+		return false
 	}
 
-	delRoleErr := utils.DeleteAquaRole(ctrl.Log, aquaScannerName)
-	if delRoleErr != nil {
-		return delRoleErr
+	return r.namespaceSelector.Matches(labels.Set(namespace.GetLabels()))
+}
+
+// maps a namespace to the AquaScannerAccounts in it
+func (r *AquaScannerAccountReconciler) accountsInNamespace(object client.Object) []reconcile.Request {
+	aquaScannerAccounts := &asav2.AquaScannerAccountList{}
+
+	if err := r.List(context.Background(), aquaScannerAccounts, client.InNamespace(object.GetName())); err != nil {
+		aquaScannerAccountLog.Error(err, "Failed to list AquaScannerAccounts in namespace", "namespace", object.GetName())
+		return nil
 	}
 
-	delAppScopeErr := utils.DeleteAquaApplicationScope(ctrl.Log, aquaScannerName)
-	if delAppScopeErr != nil {
-		return delAppScopeErr
+	requests := []reconcile.Request{}
+	for _, aquaScannerAccount := range aquaScannerAccounts.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: aquaScannerAccount.Name, Namespace: aquaScannerAccount.Namespace}})
 	}
+	return requests
+}
 
-	delPermissionSetErr := utils.DeleteAquaPermissionSet(ctrl.Log, aquaScannerName)
+// the same rate limiter controller-runtime uses by default with the delays and limits taken from the operator config
+func newRateLimiter(config configv1alpha1.ReconcileConfig) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(config.Retry.BaseDelay.Duration, config.Retry.MaxDelay.Duration),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(config.RateLimit.QPS), config.RateLimit.Burst)},
+	)
+}
 
-	if delPermissionSetErr != nil {
-		return delPermissionSetErr
+func (r *AquaScannerAccountReconciler) finalizeAquaScannerAccount(ctx context.Context, graph *utils.AquaResourceGraph) error {
+	if err := traceStep(ctx, "TeardownAquaObjects", graph.Teardown); err != nil {
+		return err
 	}
 
-	reqLogger.Info("Successfully finalized AquaScannerAccount")
+	log.FromContext(ctx).Info("Successfully finalized AquaScannerAccount")
 	return nil
 }
+
+/*
+	The aqua objects managed for an AquaScannerAccount. Dependencies are declared by the resources themselves, a new
+	kind of aqua object only needs to be added here and to AquaScannerAccountAquaObjectState.
+*/
+func (r *AquaScannerAccountReconciler) aquaResources(logger logr.Logger, aquaAuth *utils.AquaAuth, aquaAPI utils.AquaAPI, templates utils.AquaTemplates, aquaScannerAccount *asav2.AquaScannerAccount, aquaScannerAccountName string, namespacePrefix string, password string, auditSubject *utils.AuditSubject) []utils.AquaResource {
+	aquaClient := utils.AquaResourceClient{Logger: logger, AquaAuth: aquaAuth, API: aquaAPI, Templates: templates, Audit: r.Audit, Subject: auditSubject}
+
+	registries := aquaScannerAccount.ScopeRegistries()
+	scopeDescription := describeScope(namespacePrefix, registries)
+
+	applicationScope := utils.ApplicationScope{
+		Name:               aquaScannerAccountName,
+		Description:        "Application Scoped to " + scopeDescription,
+		TechnicalLeadEmail: "",
+		NamespacePrefix:    namespacePrefix,
+		Registries:         utils.NewApplicationScopeRegistries(registries),
+	}
+
+	actions, uiAccess := utils.PermissionSetActions(aquaScannerAccount.ProfileOrDefault())
+
+	permissionSet := utils.PermissionSet{
+		Name:               aquaScannerAccountName,
+		Description:        "Permission Set for AquaScannerAccount: UI read and scan read/write priviledges only",
+		TechnicalLeadEmail: "",
+		Actions:            actions,
+		UIAccess:           uiAccess,
+	}
+	if !uiAccess {
+		permissionSet.Description = "Permission Set for AquaScannerAccount: scan read/write priviledges only"
+	}
+
+	role := utils.Role{
+		Name:             aquaScannerAccountName,
+		Description:      "AquaScannerAccount created Role to allow Scanning of resources scoped to " + scopeDescription,
+		ApplicationScope: applicationScope,
+		PermissionSet:    permissionSet,
+	}
+
+	user := utils.User{
+		Name:     aquaScannerAccountName,
+		Password: password,
+		Role:     role,
+	}
+
+	return []utils.AquaResource{
+		&utils.ApplicationScopeResource{AquaResourceClient: aquaClient, ApplicationScope: applicationScope},
+		&utils.PermissionSetResource{AquaResourceClient: aquaClient, PermissionSet: permissionSet},
+		&utils.RoleResource{AquaResourceClient: aquaClient, Role: role},
+		&utils.UserResource{AquaResourceClient: aquaClient, User: user},
+	}
+}
+
+/*
+	Describes the images in scope for the descriptions of the aqua objects, e.g. "abc123-* and DockerHub only.". The
+	default scope keeps the description objects were created with before the scope could be configured.
+*/
+func describeScope(namespacePrefix string, registries []string) string {
+	if len(registries) == 1 && registries[0] == asav2.DefaultScopeRegistry {
+		return namespacePrefix + "-* and DockerHub only."
+	}
+	if len(registries) == 0 {
+		return namespacePrefix + "-* only."
+	}
+	return namespacePrefix + "-* and " + strings.Join(registries, ", ") + " only."
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/config/v1alpha1"
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+// counts the status patches sent through the client
+type statusPatchCounter struct {
+	client.Client
+	patches int
+}
+
+func (c *statusPatchCounter) Status() client.StatusWriter {
+	return &countingStatusWriter{StatusWriter: c.Client.Status(), counter: c}
+}
+
+type countingStatusWriter struct {
+	client.StatusWriter
+	counter *statusPatchCounter
+}
+
+func (w *countingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.counter.patches++
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func TestReconcileCompleteAccountDoesNotPatchStatusAgain(t *testing.T) {
+	// answers the identity check and the version of aqua, nothing else is sent to aqua for a Complete account
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version":"2022.4.46"}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	asa.AddToScheme(scheme)
+	asav2.AddToScheme(scheme)
+
+	created := asav2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", User: "Created", Role: "Created"}
+	account := &asav2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools", UID: "account-uid", Generation: 1, Finalizers: []string{aquaScannerAccountFinalizer}},
+		Status: asav2.AquaScannerAccountStatus{
+			State:        "Complete",
+			Message:      "Reconcilliation Successful!",
+			AccountName:  "ScannerCLI_abc123",
+			CurrentState: created,
+			DesiredState: created,
+			Conditions:   []metav1.Condition{{Type: asav2.ConditionReady, Status: metav1.ConditionTrue, Reason: "Complete", Message: "Reconcilliation Successful!", ObservedGeneration: 1, LastTransitionTime: metav1.Now()}},
+		},
+	}
+	controller := true
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            account.CredentialsSecretName(),
+			Namespace:       account.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: asav2.GroupVersion.String(), Kind: "AquaScannerAccount", Name: account.Name, UID: account.UID, Controller: &controller}},
+		},
+		Data: map[string][]byte{asav2.CredentialsSecretPasswordKey: []byte("password")},
+	}
+
+	counter := &statusPatchCounter{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(account, secret).Build()}
+
+	config := &configv1alpha1.OperatorConfig{}
+	config.Default()
+	aquaAuth, _ := utils.NewAquaAuth(server.URL, utils.AquaCredentials{Method: utils.TokenAuthMethod, Token: "token"}, nil)
+
+	r := &AquaScannerAccountReconciler{
+		Client:    counter,
+		Scheme:    scheme,
+		Recorder:  record.NewFakeRecorder(10),
+		Config:    config,
+		AquaAuth:  aquaAuth,
+		APIReader: counter,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: account.Name, Namespace: account.Namespace}}
+
+	// the first pass records the version of aqua and the credentials secret
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("the first reconcile of a Complete account was supposed to pass but got %v", err)
+	}
+
+	reconciled := &asav2.AquaScannerAccount{}
+	counter.Get(context.Background(), req.NamespacedName, reconciled)
+	if reconciled.Status.AquaVersion != "2022.4.46" || reconciled.Status.CredentialsSecretRef == nil {
+		t.Fatalf("the first reconcile was supposed to record the aqua version and credentials secret but got %+v", reconciled.Status)
+	}
+
+	counter.patches = 0
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("the second reconcile of a Complete account was supposed to pass but got %v", err)
+	}
+	if counter.patches != 0 {
+		t.Errorf("the second reconcile of an unchanged Complete account was not supposed to patch its status but sent %v patches", counter.patches)
+	}
+}
@@ -14,20 +14,19 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package v1
+package controllers
 
 import (
+	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
-	logf "sigs.k8s.io/controller-runtime/pkg/log"
-)
 
-// log is for logging in this package.
-var aquascanneraccountlog = logf.Log.WithName("aquascanneraccount-resource")
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
 
-func (r *AquaScannerAccount) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).
-		For(r).
-		Complete()
+/*
+	The logger of a component for what it logs outside of a reconcile, e.g. in the map function of a watch, where
+	there is no request-scoped logger. It redacts secrets whatever logger the manager was given.
+*/
+func componentLogger(name string) logr.Logger {
+	return utils.NewRedactingLogger(ctrl.Log.WithName(name))
 }
-
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+var (
+	aquaInstanceHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aqua_instance_healthy",
+			Help: "Whether the operator was able to authenticate with the aqua instance (1) or not (0)",
+		},
+		[]string{"instance"},
+	)
+
+	aquaInstanceLoginFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aqua_instance_login_failures_total",
+			Help: "Number of failed health check logins against the aqua instance",
+		},
+		[]string{"instance"},
+	)
+
+	aquaOperatorCredentialsDegraded = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "aqua_operator_credentials_degraded",
+			Help: "Whether aqua rejected the credentials in the operator's credentials secret (1) or not (0)",
+		},
+	)
+
+	aquaOperatorCredentialsReloads = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "aqua_operator_credentials_reloads_total",
+			Help: "Number of times the operator's aqua credentials were reloaded from its credentials secret",
+		},
+	)
+
+	aquaScannerAccountPlannedChanges = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aqua_scanner_account_planned_changes",
+			Help: "Number of changes to aqua planned for an AquaScannerAccount in dry run mode, by action (Create, Update or Delete)",
+		},
+		[]string{"namespace", "name", "action"},
+	)
+
+	aquaVersionInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aqua_version_info",
+			Help: "The version the aqua instance runs and the generation of the aqua api the operator talks to it with (unsupported when none), always 1",
+		},
+		[]string{"instance", "version", "api"},
+	)
+)
+
+// the actions of an AquaPlannedChange
+var plannedChangeActions = []string{"Create", "Update", "Delete"}
+
+func init() {
+	metrics.Registry.MustRegister(
+		aquaInstanceHealthy,
+		aquaInstanceLoginFailures,
+		aquaOperatorCredentialsDegraded,
+		aquaOperatorCredentialsReloads,
+		aquaScannerAccountPlannedChanges,
+		aquaVersionInfo,
+	)
+}
+
+// the labels aquaVersionInfo was last set with for each instance, the series of a previous version is removed
+var (
+	aquaVersionLabelsMu sync.Mutex
+	aquaVersionLabels   = map[string]prometheus.Labels{}
+)
+
+// records the version of the aqua instance, the operator's own aqua is recorded as the instance ""
+func recordAquaVersion(instance string, version utils.AquaVersion, api utils.AquaAPI) {
+	generation := "unsupported"
+	if api != nil {
+		generation = api.Generation()
+	}
+	labels := prometheus.Labels{"instance": instance, "version": version.String(), "api": generation}
+
+	aquaVersionLabelsMu.Lock()
+	defer aquaVersionLabelsMu.Unlock()
+
+	if previous, ok := aquaVersionLabels[instance]; ok {
+		aquaVersionInfo.Delete(previous)
+	}
+	aquaVersionInfo.With(labels).Set(1)
+	aquaVersionLabels[instance] = labels
+}
+
+// removes the version of an aqua instance that was deleted
+func forgetAquaVersion(instance string) {
+	aquaVersionLabelsMu.Lock()
+	defer aquaVersionLabelsMu.Unlock()
+
+	if previous, ok := aquaVersionLabels[instance]; ok {
+		aquaVersionInfo.Delete(previous)
+		delete(aquaVersionLabels, instance)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+const (
+	credentialsReloadedReason = "CredentialsReloaded"
+	credentialsDegradedReason = "Degraded"
+)
+
+// OperatorCredentialsReconciler watches the secret holding the operator's own aqua credentials
+// (AQUA_URL, AQUA_AUTH_METHOD and the credentials for the method) and reloads them without restarting the manager
+type OperatorCredentialsReconciler struct {
+	client.Client
+	Scheme          *runtime.Scheme
+	Recorder        record.EventRecorder
+	SecretName      string
+	SecretNamespace string
+	// the operator's own aqua instance the credentials are loaded into. Defaults to utils.GetAquaAuth
+	AquaAuth *utils.AquaAuth
+	// overrides the AQUA_AUTH_METHOD key of the secret when set
+	AuthMethod string
+
+	// holds the credentials secret alone, see SetupWithManager
+	secrets cache.Cache
+}
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile swaps the credentials of the operator's AquaAuth, which throws away the cached jwt, and checks that aqua
+// accepts the new credentials. When it does not the operator is reported as Degraded until the secret is fixed.
+func (r *OperatorCredentialsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+
+	err := r.secrets.Get(ctx, req.NamespacedName, secret)
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Error(err, "Aqua credentials secret not found. Keeping the current credentials")
+			aquaOperatorCredentialsDegraded.Set(1)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get aqua credentials secret")
+		return ctrl.Result{}, err
+	}
+
+	aquaAuth := r.AquaAuth
+
+	aquaUrl := string(secret.Data["AQUA_URL"])
+	if aquaUrl == "" {
+		// the url is allowed to be set through the manager's env instead of the secret
+		aquaUrl = aquaAuth.GetUrl()
+	}
+
+	credentials := utils.AquaCredentialsFromSecretData(secret.Data)
+	if r.AuthMethod != "" {
+		credentials.Method = r.AuthMethod
+	}
+
+	changed, credentialsErr := aquaAuth.SetCredentials(aquaUrl, credentials)
+
+	if credentialsErr != nil {
+		logger.Error(credentialsErr, "Aqua credentials secret is invalid. Keeping the current credentials")
+		aquaOperatorCredentialsDegraded.Set(1)
+		r.Recorder.Event(secret, corev1.EventTypeWarning, credentialsDegradedReason, credentialsErr.Error())
+		return ctrl.Result{}, nil
+	}
+
+	if changed {
+		logger.Info("Aqua credentials changed, discarding the cached login", "authMethod", aquaAuth.GetAuthMethod())
+		aquaOperatorCredentialsReloads.Inc()
+	}
+
+	if loginErr := aquaAuth.CheckLogin(logger); loginErr != nil {
+		aquaOperatorCredentialsDegraded.Set(1)
+		r.Recorder.Event(secret, corev1.EventTypeWarning, credentialsDegradedReason, "Aqua rejected the credentials in this secret, AquaScannerAccounts will not be reconciled until they are fixed")
+		// the check is retried in case aqua was unreachable rather than the credentials being wrong
+		return ctrl.Result{RequeueAfter: aquaInstanceHealthCheckInterval}, nil
+	}
+
+	aquaOperatorCredentialsDegraded.Set(0)
+	r.Recorder.Event(secret, corev1.EventTypeNormal, credentialsReloadedReason, "Aqua accepted the credentials in this secret")
+
+	return ctrl.Result{}, nil
+}
+
+/*
+	SetupWithManager sets up the controller with the Manager. The secret is read from a cache of its own that only
+	lists and watches it, the manager's cache only holds the Secrets with utils.WatchLabel and the secret is not
+	expected to carry it.
+*/
+func (r *OperatorCredentialsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Client = newTracingClient(r.Client)
+	if r.AquaAuth == nil {
+		aquaAuth, err := utils.GetAquaAuth()
+		if err != nil {
+			return err
+		}
+		r.AquaAuth = aquaAuth
+	}
+
+	secrets, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:    mgr.GetScheme(),
+		Mapper:    mgr.GetRESTMapper(),
+		Namespace: r.SecretNamespace,
+		SelectorsByObject: cache.SelectorsByObject{
+			&corev1.Secret{}: {Field: fields.OneTermEqualSelector("metadata.name", r.SecretName)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := mgr.Add(secrets); err != nil {
+		return err
+	}
+	r.secrets = secrets
+
+	c, err := controller.New("operatorcredentials", mgr, controller.Options{Reconciler: traced("OperatorCredentials", r)})
+	if err != nil {
+		return err
+	}
+	return c.Watch(source.NewKindWithCache(&corev1.Secret{}, secrets), &handler.EnqueueRequestForObject{}, predicate.ResourceVersionChangedPredicate{})
+}
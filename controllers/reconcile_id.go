@@ -0,0 +1,42 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type reconcileIDKey struct{}
+
+/*
+	Returns ctx carrying a new id that tells the reconciles of the same object apart, e.g. in the audit trail. The logger
+	of ctx logs the id as reconcileID along with the name and namespace controller-runtime adds.
+*/
+func withReconcileID(ctx context.Context) context.Context {
+	id := string(uuid.NewUUID())
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("reconcileID", id))
+	return context.WithValue(ctx, reconcileIDKey{}, id)
+}
+
+// the id withReconcileID put in ctx, empty outside of a reconcile
+func reconcileIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// the CRD of AquaScannerAccounts, whose status.storedVersions is trimmed once every account is stored as v2
+const aquaScannerAccountCRDName = "aquascanneraccounts.mamoa.devops.gov.bc.ca"
+
+var customResourceDefinitionGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+// logs the migration, which runs outside of any reconcile
+var storageMigrationLog = componentLogger("storage-migration")
+
+/*
+	StorageMigrator rewrites the AquaScannerAccounts stored as v1alpha1 or v1 in the storage version v2 when the manager
+	starts. Updating an object without changing it is enough, the API server converts it and stores it as v2. Once
+	every account is migrated the older versions are dropped from status.storedVersions of the CRD so they can be
+	removed from it in a later release.
+*/
+type StorageMigrator struct {
+	client.Client
+	// reads accounts as v1 without going through the cache. Defaults to the manager's APIReader
+	APIReader client.Reader
+	// true when the manager only watches some namespaces. Accounts in other namespaces can not be migrated so
+	// status.storedVersions is left alone
+	NamespacesRestricted bool
+	// how long to wait before trying the accounts that could not be migrated again. Defaults to 5 minutes
+	RetryInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=update;patch
+
+// SetupWithManager adds the migration to the Manager, it runs once the manager is elected leader.
+func (m *StorageMigrator) SetupWithManager(mgr ctrl.Manager) error {
+	if m.APIReader == nil {
+		m.APIReader = mgr.GetAPIReader()
+	}
+	if m.RetryInterval == 0 {
+		m.RetryInterval = 5 * time.Minute
+	}
+	return mgr.Add(m)
+}
+
+// NeedLeaderElection keeps replicas that are not the leader from migrating at the same time
+func (m *StorageMigrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Start migrates the accounts and retries the ones left until every account is migrated or the manager stops
+func (m *StorageMigrator) Start(ctx context.Context) error {
+	ctx = ctrl.LoggerInto(ctx, storageMigrationLog)
+	for {
+		done, err := m.migrate(ctx)
+		if err != nil {
+			storageMigrationLog.Error(err, "Failed to migrate the stored AquaScannerAccounts to v2, will re-attempt")
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.RetryInterval):
+		}
+	}
+}
+
+// rewrites every account as v2, returns true when none are left
+func (m *StorageMigrator) migrate(ctx context.Context) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+	aquaScannerAccounts := &asav2.AquaScannerAccountList{}
+
+	if err := m.List(ctx, aquaScannerAccounts); err != nil {
+		return false, err
+	}
+
+	pending := 0
+	for i := range aquaScannerAccounts.Items {
+		aquaScannerAccount := &aquaScannerAccounts.Items[i]
+		name := types.NamespacedName{Name: aquaScannerAccount.Name, Namespace: aquaScannerAccount.Namespace}
+
+		// rewriting an account still holding its password in status.accountSecret would lose the password, the
+		// AquaScannerAccount controller moves it to the credentials Secret first
+		legacyAccount := &asa.AquaScannerAccount{}
+		if err := m.APIReader.Get(ctx, name, legacyAccount); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if legacyAccount.Status.AccountSecret != "" {
+			log.Info("AquaScannerAccount still has its password in status.accountSecret, migrating it later", "name", name)
+			pending++
+			continue
+		}
+
+		if err := m.Update(ctx, aquaScannerAccount); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if errors.IsConflict(err) {
+				// changed since it was listed, a write of the change stored it as v2 already but it is checked again
+				pending++
+				continue
+			}
+			return false, err
+		}
+	}
+
+	if pending > 0 {
+		log.Info("AquaScannerAccounts are left to migrate to v2", "pending", pending)
+		return false, nil
+	}
+
+	log.Info("Every AquaScannerAccount is stored as v2", "migrated", len(aquaScannerAccounts.Items))
+
+	if m.NamespacesRestricted {
+		return true, nil
+	}
+	return true, m.trimStoredVersions(ctx)
+}
+
+// sets status.storedVersions of the CRD to v2 alone
+func (m *StorageMigrator) trimStoredVersions(ctx context.Context) error {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(customResourceDefinitionGVK)
+
+	if err := m.APIReader.Get(ctx, types.NamespacedName{Name: aquaScannerAccountCRDName}, crd); err != nil {
+		return err
+	}
+
+	storedVersions, _, err := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+	if err != nil {
+		return err
+	}
+	if len(storedVersions) == 1 && storedVersions[0] == asav2.GroupVersion.Version {
+		return nil
+	}
+
+	original := crd.DeepCopy()
+	if err := unstructured.SetNestedStringSlice(crd.Object, []string{asav2.GroupVersion.Version}, "status", "storedVersions"); err != nil {
+		return err
+	}
+	if err := m.Status().Patch(ctx, crd, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+		return err
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Removed the versions no AquaScannerAccount is stored in from the CRD", "crd", aquaScannerAccountCRDName, "storedVersions", storedVersions)
+	return nil
+}
@@ -32,6 +32,7 @@ import (
 
 	mamoadevopsgovbccav1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
 	mamoadevopsgovbccav1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1alpha1"
+	mamoadevopsgovbccav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -69,6 +70,9 @@ var _ = BeforeSuite(func() {
 	err = mamoadevopsgovbccav1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = mamoadevopsgovbccav2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
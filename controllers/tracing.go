@@ -0,0 +1,186 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
+)
+
+/*
+	Runs every reconcile of r in a span named after the kind it reconciles. The span carries the reconcile id, which is
+	put in the ctx handed to r so the audit trail and the trace of a reconcile can be matched up.
+*/
+type tracingReconciler struct {
+	kind       string
+	reconciler reconcile.Reconciler
+}
+
+func traced(kind string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &tracingReconciler{kind: kind, reconciler: r}
+}
+
+func (t *tracingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx = withReconcileID(ctx)
+	ctx, span := utils.Tracer().Start(ctx, "Reconcile "+t.kind, trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.object.kind", t.kind),
+		attribute.String("k8s.object.name", req.Name),
+		attribute.String("reconcile.id", reconcileIDFrom(ctx)),
+	))
+	defer span.End()
+
+	result, err := t.reconciler.Reconcile(ctx, req)
+	utils.RecordSpanError(span, err)
+	span.SetAttributes(attribute.Bool("reconcile.requeue", result.Requeue || result.RequeueAfter > 0))
+	return result, err
+}
+
+// runs step in a span called name, a child of the span in ctx
+func traceStep(ctx context.Context, name string, step func(ctx context.Context) error) error {
+	ctx, span := utils.Tracer().Start(ctx, name)
+	defer span.End()
+
+	err := step(ctx)
+	utils.RecordSpanError(span, err)
+	return err
+}
+
+// records a span for every call the reconcilers make to the kubernetes api, or to the cache for reads
+type tracingClient struct {
+	client.Client
+}
+
+func newTracingClient(c client.Client) client.Client {
+	if _, ok := c.(*tracingClient); ok || c == nil {
+		return c
+	}
+	return &tracingClient{Client: c}
+}
+
+func (c *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return traceKubernetesCall(ctx, c.Scheme(), "Get", obj, key.Namespace, key.Name, func(ctx context.Context) error {
+		return c.Client.Get(ctx, key, obj)
+	})
+}
+
+func (c *tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return traceKubernetesCall(ctx, c.Scheme(), "List", list, "", "", func(ctx context.Context) error {
+		return c.Client.List(ctx, list, opts...)
+	})
+}
+
+func (c *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return traceKubernetesCall(ctx, c.Scheme(), "Create", obj, obj.GetNamespace(), obj.GetName(), func(ctx context.Context) error {
+		return c.Client.Create(ctx, obj, opts...)
+	})
+}
+
+func (c *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return traceKubernetesCall(ctx, c.Scheme(), "Delete", obj, obj.GetNamespace(), obj.GetName(), func(ctx context.Context) error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
+
+func (c *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return traceKubernetesCall(ctx, c.Scheme(), "Update", obj, obj.GetNamespace(), obj.GetName(), func(ctx context.Context) error {
+		return c.Client.Update(ctx, obj, opts...)
+	})
+}
+
+func (c *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return traceKubernetesCall(ctx, c.Scheme(), "Patch", obj, obj.GetNamespace(), obj.GetName(), func(ctx context.Context) error {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+func (c *tracingClient) Status() client.StatusWriter {
+	return &tracingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+// status writes are spans of their own, they are often what a slow reconcile waits on
+type tracingStatusWriter struct {
+	client.StatusWriter
+	client *tracingClient
+}
+
+func (w *tracingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return traceKubernetesCall(ctx, w.client.Scheme(), "UpdateStatus", obj, obj.GetNamespace(), obj.GetName(), func(ctx context.Context) error {
+		return w.StatusWriter.Update(ctx, obj, opts...)
+	})
+}
+
+func (w *tracingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return traceKubernetesCall(ctx, w.client.Scheme(), "PatchStatus", obj, obj.GetNamespace(), obj.GetName(), func(ctx context.Context) error {
+		return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+// a client.Reader such as the manager's APIReader whose reads are recorded as spans
+type tracingReader struct {
+	client.Reader
+	scheme *runtime.Scheme
+}
+
+func newTracingReader(r client.Reader, scheme *runtime.Scheme) client.Reader {
+	if _, ok := r.(*tracingReader); ok || r == nil {
+		return r
+	}
+	return &tracingReader{Reader: r, scheme: scheme}
+}
+
+func (r *tracingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return traceKubernetesCall(ctx, r.scheme, "Get", obj, key.Namespace, key.Name, func(ctx context.Context) error {
+		return r.Reader.Get(ctx, key, obj)
+	})
+}
+
+func (r *tracingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return traceKubernetesCall(ctx, r.scheme, "List", list, "", "", func(ctx context.Context) error {
+		return r.Reader.List(ctx, list, opts...)
+	})
+}
+
+// runs call in a span named after the verb and the kind of obj, e.g. "kubernetes Get Secret"
+func traceKubernetesCall(ctx context.Context, scheme *runtime.Scheme, verb string, obj runtime.Object, namespace string, name string, call func(ctx context.Context) error) error {
+	kind := fmt.Sprintf("%T", obj)
+	if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+		kind = gvk.Kind
+	}
+
+	attributes := []attribute.KeyValue{attribute.String("k8s.object.kind", kind)}
+	if namespace != "" {
+		attributes = append(attributes, attribute.String("k8s.namespace.name", namespace))
+	}
+	if name != "" {
+		attributes = append(attributes, attribute.String("k8s.object.name", name))
+	}
+
+	return traceStep(ctx, "kubernetes "+verb+" "+kind, func(ctx context.Context) error {
+		trace.SpanFromContext(ctx).SetAttributes(attributes...)
+		return call(ctx)
+	})
+}
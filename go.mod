@@ -3,10 +3,22 @@ module github.com/bcgov-platform-services/aqua-scan-cli-operator
 go 1.16
 
 require (
+	github.com/go-logr/logr v0.4.0
+	github.com/google/gofuzz v1.1.0
 	github.com/kataras/jwt v0.1.2
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.13.0
+	github.com/prometheus/client_golang v1.11.0
+	github.com/spf13/pflag v1.0.5
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781
+	golang.org/x/time v0.0.0-20210611083556-38a9dc6acbc6
+	k8s.io/api v0.21.2
 	k8s.io/apimachinery v0.21.2
 	k8s.io/client-go v0.21.2
 	sigs.k8s.io/controller-runtime v0.9.2
+	sigs.k8s.io/yaml v1.2.0
 )
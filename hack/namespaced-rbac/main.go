@@ -0,0 +1,195 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// namespaced-rbac generates the Roles and RoleBindings the operator needs when namespaces.watch limits it to a list
+// of namespaces. The rules are taken from the ClusterRole controller-gen generates so both stay in sync.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/config/v1alpha1"
+)
+
+// resources in the generated ClusterRole that are not namespaced
+var clusterScopedResources = map[string]bool{
+	"aquainstances":                    true,
+	"aquainstances/status":             true,
+	"namespaces":                       true,
+	"customresourcedefinitions":        true,
+	"customresourcedefinitions/status": true,
+	"tokenreviews":                     true,
+	"subjectaccessreviews":             true,
+}
+
+// cluster scoped resources the admin api uses, they are left out when it is off. Namespaces are read for the contacts
+// of the accounts
+var adminAPIResources = map[string]bool{
+	"namespaces":           true,
+	"tokenreviews":         true,
+	"subjectaccessreviews": true,
+}
+
+// cluster scoped resources the validating webhook uses to review who adds credential readers
+var webhookResources = map[string]bool{
+	"subjectaccessreviews": true,
+}
+
+// cluster scoped resources used for AquaInstances, they are left out when the feature is off. Namespaces are read to
+// match them against spec.namespaceSelector of the instance
+var aquaInstanceResources = map[string]bool{
+	"aquainstances":        true,
+	"aquainstances/status": true,
+	"namespaces":           true,
+}
+
+func main() {
+	var configFile, roleFile, name, serviceAccount, serviceAccountNamespace string
+	flag.StringVar(&configFile, "config", "config/manager/controller_manager_config.yaml", "The operator config file with namespaces.watch.")
+	flag.StringVar(&roleFile, "role", "config/rbac/role.yaml", "The ClusterRole generated by controller-gen.")
+	flag.StringVar(&name, "name", "aqua-scanner-operator-manager-role", "The name of the generated Roles.")
+	flag.StringVar(&serviceAccount, "service-account", "aqua-scanner-operator-controller-manager", "The service account of the manager.")
+	flag.StringVar(&serviceAccountNamespace, "service-account-namespace", "openshift-bcgov-aqua", "The namespace the manager runs in.")
+	flag.Parse()
+
+	if err := run(os.Stdout, configFile, roleFile, name, rbacv1.Subject{Kind: "ServiceAccount", Name: serviceAccount, Namespace: serviceAccountNamespace}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out *os.File, configFile string, roleFile string, name string, subject rbacv1.Subject) error {
+	operatorConfig := &configv1alpha1.OperatorConfig{}
+	if err := readYaml(configFile, operatorConfig); err != nil {
+		return err
+	}
+	operatorConfig.Default()
+	if errs := operatorConfig.Validate(); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+
+	// the selector is matched against every namespace in the cluster, which a Role can not grant
+	if operatorConfig.Namespaces.Selector != nil {
+		return fmt.Errorf("namespaces.selector is set in %v, the operator reads namespaces cluster wide and needs the ClusterRole in %v", configFile, roleFile)
+	}
+
+	// the manager always watches its own namespace for the credentials secret
+	namespaces := operatorConfig.Namespaces.WatchedNamespaces(subject.Namespace)
+	if namespaces == nil {
+		return fmt.Errorf("namespaces.watch is empty in %v, the operator needs the ClusterRole in %v", configFile, roleFile)
+	}
+	sort.Strings(namespaces)
+
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := readYaml(roleFile, clusterRole); err != nil {
+		return err
+	}
+
+	namespacedRules, clusterRules := splitRules(clusterRole.Rules, configv1alpha1.IsEnabled(operatorConfig.Features.AquaInstances), operatorConfig.Admin.BindAddress != "", configv1alpha1.IsEnabled(operatorConfig.Features.Webhooks))
+
+	objects := []interface{}{}
+	for _, namespace := range namespaces {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Rules:      namespacedRules,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name + "binding", Namespace: namespace},
+				RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: name},
+				Subjects:   []rbacv1.Subject{subject},
+			})
+	}
+
+	// AquaInstances, namespaces and the reviews of the admin api and webhook are cluster scoped so they always need a ClusterRole,
+	// it is left out when none of them is used
+	if len(clusterRules) > 0 {
+		objects = append(objects,
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: name + "-cluster"},
+				Rules:      clusterRules,
+			},
+			&rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name + "-clusterbinding"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: name + "-cluster"},
+				Subjects:   []rbacv1.Subject{subject},
+			})
+	}
+
+	fmt.Fprintf(out, "# Generated by hack/namespaced-rbac from %v and namespaces.watch in %v. DO NOT EDIT.\n", roleFile, configFile)
+	for _, object := range objects {
+		b, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "---\n%s", b)
+	}
+	return nil
+}
+
+/*
+	Splits the rules of the ClusterRole by the scope of their resources. Namespaces are read for namespaces.selector,
+	which run refuses because it needs the ClusterRole, for the namespace selectors of AquaInstances and by the admin
+	api, so their rule is only kept with AquaInstances or the admin api. The CRD is only written once every account in
+	the cluster is migrated to v2, which a namespaced operator can not tell, so its rules are dropped.
+*/
+func splitRules(rules []rbacv1.PolicyRule, aquaInstances bool, adminAPI bool, webhooks bool) ([]rbacv1.PolicyRule, []rbacv1.PolicyRule) {
+	namespacedRules := []rbacv1.PolicyRule{}
+	clusterRules := []rbacv1.PolicyRule{}
+
+	for _, rule := range rules {
+		namespaced := rule.DeepCopy()
+		namespaced.Resources = nil
+		cluster := rule.DeepCopy()
+		cluster.Resources = nil
+
+		for _, resource := range rule.Resources {
+			switch {
+			case !clusterScopedResources[resource]:
+				namespaced.Resources = append(namespaced.Resources, resource)
+			case (aquaInstances && aquaInstanceResources[resource]) || (adminAPI && adminAPIResources[resource]) || (webhooks && webhookResources[resource]):
+				cluster.Resources = append(cluster.Resources, resource)
+			}
+		}
+
+		if len(namespaced.Resources) > 0 {
+			namespacedRules = append(namespacedRules, *namespaced)
+		}
+		if len(cluster.Resources) > 0 {
+			clusterRules = append(clusterRules, *cluster)
+		}
+	}
+	return namespacedRules, clusterRules
+}
+
+func readYaml(path string, obj interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, obj)
+}
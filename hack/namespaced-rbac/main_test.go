@@ -0,0 +1,107 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+var clusterRoleRules = []rbacv1.PolicyRule{
+	{APIGroups: []string{""}, Resources: []string{"configmaps", "secrets"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get", "list", "watch"}},
+	{APIGroups: []string{"apiextensions.k8s.io"}, Resources: []string{"customresourcedefinitions", "customresourcedefinitions/status"}, Verbs: []string{"get", "update"}},
+	{APIGroups: []string{"mamoa.devops.gov.bc.ca"}, Resources: []string{"aquainstances", "aquainstances/status", "aquascanneraccounts"}, Verbs: []string{"get", "update"}},
+	{APIGroups: []string{"authentication.k8s.io"}, Resources: []string{"tokenreviews"}, Verbs: []string{"create"}},
+	{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"subjectaccessreviews"}, Verbs: []string{"create"}},
+}
+
+func resources(rules []rbacv1.PolicyRule) string {
+	names := []string{}
+	for _, rule := range rules {
+		names = append(names, rule.Resources...)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSplitRules(t *testing.T) {
+	namespaced, cluster := splitRules(clusterRoleRules, false, false, false)
+	if resources(namespaced) != "configmaps,secrets,aquascanneraccounts" {
+		t.Errorf("splitRules was supposed to keep only the namespaced resources in the Role but got %v", resources(namespaced))
+	}
+	if len(cluster) != 0 {
+		t.Errorf("splitRules was supposed to leave out the ClusterRole when no cluster scoped resource is used but got %v", resources(cluster))
+	}
+	if len(namespaced[1].Resources) != 1 || namespaced[1].APIGroups[0] != "mamoa.devops.gov.bc.ca" || namespaced[1].Verbs[1] != "update" {
+		t.Errorf("splitRules was supposed to keep the api groups and verbs of a split rule but got %+v", namespaced[1])
+	}
+
+	_, cluster = splitRules(clusterRoleRules, true, false, false)
+	if resources(cluster) != "namespaces,aquainstances,aquainstances/status" {
+		t.Errorf("splitRules was supposed to keep only the AquaInstance resources and the namespaces they select in the ClusterRole but got %v", resources(cluster))
+	}
+
+	_, cluster = splitRules(clusterRoleRules, false, true, false)
+	if resources(cluster) != "namespaces,tokenreviews,subjectaccessreviews" {
+		t.Errorf("splitRules was supposed to keep the reviews of the admin api in the ClusterRole but got %v", resources(cluster))
+	}
+
+	_, cluster = splitRules(clusterRoleRules, false, false, true)
+	if resources(cluster) != "subjectaccessreviews" {
+		t.Errorf("splitRules was supposed to keep only the access reviews of the webhook in the ClusterRole but got %v", resources(cluster))
+	}
+
+	_, cluster = splitRules(clusterRoleRules, false, false, true)
+	if strings.Contains(resources(cluster), "namespaces") {
+		t.Errorf("splitRules was supposed to drop the namespaces rule without AquaInstances and the admin api but got %v", resources(cluster))
+	}
+
+	_, cluster = splitRules(clusterRoleRules, true, true, true)
+	if strings.Contains(resources(cluster), "customresourcedefinitions") {
+		t.Errorf("splitRules was supposed to drop the CRD rules but got %v", resources(cluster))
+	}
+}
+
+func TestRunRejectsNamespaceSelector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "namespaced-rbac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "controller_manager_config.yaml")
+	config := `
+apiVersion: config.mamoa.devops.gov.bc.ca/v1alpha1
+kind: OperatorConfig
+namespaces:
+  selector:
+    matchLabels:
+      aqua-scanner: enabled
+`
+	if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err = run(os.Stdout, configFile, "role.yaml", "manager-role", rbacv1.Subject{Kind: "ServiceAccount", Name: "manager", Namespace: "openshift-bcgov-aqua"})
+	if err == nil || !strings.Contains(err.Error(), "namespaces.selector") {
+		t.Errorf("run was supposed to refuse a config with namespaces.selector but got %v", err)
+	}
+}
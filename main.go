@@ -17,24 +17,34 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	configv1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/config/v1alpha1"
 	mamoadevopsgovbccav1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
 	mamoadevopsgovbccav1alpha1 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1alpha1"
+	mamoadevopsgovbccav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
 
 	"github.com/bcgov-platform-services/aqua-scan-cli-operator/controllers"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -48,47 +58,203 @@ func init() {
 
 	utilruntime.Must(mamoadevopsgovbccav1alpha1.AddToScheme(scheme))
 	utilruntime.Must(mamoadevopsgovbccav1.AddToScheme(scheme))
+	utilruntime.Must(mamoadevopsgovbccav2.AddToScheme(scheme))
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
+	var configFile string
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var credentialsSecretName string
+	var credentialsSecretNamespace string
+	flag.StringVar(&configFile, "config", "",
+		"The operator config file. When set the metrics, probe and leader election flags are ignored in favour of the file.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&credentialsSecretName, "aqua-credentials-secret-name", "",
+		"The secret holding the operator's AQUA_URL, AQUA_USER and AQUA_PASSWORD. "+
+			"When set the secret is watched and credential changes are picked up without a restart. "+
+			"Overrides aqua.credentialsSecretRef.name in the config file.")
+	flag.StringVar(&credentialsSecretNamespace, "aqua-credentials-secret-namespace", "",
+		"The namespace of the aqua credentials secret. Overrides aqua.credentialsSecretRef.namespace in the config file, "+
+			"defaults to the POD_NAMESPACE env var.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// passwords and jwts are redacted from everything the manager logs, whichever logger it is logged with
+	ctrl.SetLogger(utils.NewRedactingLogger(zap.New(zap.UseFlagOptions(&opts))))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	var err error
+	operatorConfig := configv1alpha1.OperatorConfig{}
+	options := ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "81c785f5.devops.gov.bc.ca",
-	})
+	}
+	if configFile != "" {
+		// the file is defaulted and validated as it is loaded, an invalid field stops the manager from starting
+		options, err = ctrl.Options{Scheme: scheme}.AndFrom(ctrl.ConfigFile().AtPath(configFile).OfKind(&operatorConfig))
+		if options.LeaderElectionID == "" {
+			options.LeaderElectionID = "81c785f5.devops.gov.bc.ca"
+		}
+	} else {
+		_, err = operatorConfig.Complete()
+	}
+	if err != nil {
+		setupLog.Error(err, "invalid operator config", "file", configFile)
+		os.Exit(1)
+	}
+
+	if credentialsSecretName == "" {
+		credentialsSecretName = operatorConfig.Aqua.CredentialsSecretRef.Name
+	}
+	if credentialsSecretNamespace == "" {
+		credentialsSecretNamespace = operatorConfig.Aqua.CredentialsSecretRef.Namespace
+	}
+	if credentialsSecretNamespace == "" {
+		credentialsSecretNamespace = os.Getenv("POD_NAMESPACE")
+	}
+
+	newCache := cache.New
+	if watchNamespaces := operatorConfig.Namespaces.WatchedNamespaces(credentialsSecretNamespace); watchNamespaces != nil {
+		// the cache only lists and watches these namespaces so the operator can run with namespaced RBAC
+		setupLog.Info("watching namespaces", "namespaces", watchNamespaces)
+		newCache = cache.MultiNamespacedCacheBuilder(watchNamespaces)
+	}
+	// only the Secrets and ConfigMaps with the watch label are cached, the others are read from the API server so ones
+	// created before the operator labelled them are still found
+	options.NewCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		opts.SelectorsByObject = cache.SelectorsByObject{
+			&corev1.Secret{}:    {Label: utils.WatchLabelSelector()},
+			&corev1.ConfigMap{}: {Label: utils.WatchLabelSelector()},
+		}
+		return newCache(config, opts)
+	}
+	options.ClientDisableCacheFor = []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}}
+
+	aquaAuth, err := newOperatorAquaAuth(operatorConfig.Aqua)
+	if err != nil {
+		setupLog.Error(err, "unable to configure the aqua client")
+		os.Exit(1)
+	}
+
+	auditSink, err := newAuditSink(operatorConfig.Audit)
+	if err != nil {
+		setupLog.Error(err, "unable to open the audit trail", "sink", operatorConfig.Audit.Sink)
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	tracerProvider, err := newTracerProvider(ctx, operatorConfig.Tracing)
+	if err != nil {
+		setupLog.Error(err, "unable to configure tracing", "endpoint", operatorConfig.Tracing.Endpoint)
+		os.Exit(1)
+	}
+	utils.InstallTracing(tracerProvider)
+
+	// only logged, every reconcile reads the version again and refuses an unsupported aqua with a clear status
+	if aquaAuth.GetUrl() != "" {
+		if aquaAPI, aquaVersion, err := aquaAuth.DetectAPI(ctx); err != nil {
+			setupLog.Error(err, "unable to detect the version of aqua", "url", aquaAuth.GetUrl(), "version", aquaVersion.String())
+		} else {
+			setupLog.Info("detected aqua", "url", aquaAuth.GetUrl(), "version", aquaVersion.String(), "api", aquaAPI.Generation())
+		}
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
 	if err = (&controllers.AquaScannerAccountReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("aqua-scanner-operator"),
+		Config:   &operatorConfig,
+		AquaAuth: aquaAuth,
+		Audit:    auditSink,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AquaScannerAccount")
 		os.Exit(1)
 	}
-	if err = (&mamoadevopsgovbccav1.AquaScannerAccount{}).SetupWebhookWithManager(mgr); err != nil {
+	if configv1alpha1.IsEnabled(operatorConfig.Features.AquaInstances) {
+		if err = (&controllers.AquaInstanceReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "AquaInstance")
+			os.Exit(1)
+		}
+	}
+	if configv1alpha1.IsEnabled(operatorConfig.Features.ImageScans) {
+		if err = (&controllers.AquaImageScanReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Config: &operatorConfig,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "AquaImageScan")
+			os.Exit(1)
+		}
+	}
+	if credentialsSecretName != "" && configv1alpha1.IsEnabled(operatorConfig.Features.CredentialsReload) {
+		if err = (&controllers.OperatorCredentialsReconciler{
+			Client:          mgr.GetClient(),
+			Scheme:          mgr.GetScheme(),
+			Recorder:        mgr.GetEventRecorderFor("aqua-scanner-operator"),
+			SecretName:      credentialsSecretName,
+			SecretNamespace: credentialsSecretNamespace,
+			AquaAuth:        aquaAuth,
+			AuthMethod:      operatorConfig.Aqua.AuthMethod,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OperatorCredentials")
+			os.Exit(1)
+		}
+	}
+	if err = (&controllers.StorageMigrator{
+		Client:               mgr.GetClient(),
+		NamespacesRestricted: operatorConfig.Namespaces.WatchedNamespaces(credentialsSecretNamespace) != nil,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create storage migration", "kind", "AquaScannerAccount")
+		os.Exit(1)
+	}
+	if operatorConfig.Admin.BindAddress != "" {
+		if err = (&controllers.AdminAPI{
+			Client:      mgr.GetClient(),
+			BindAddress: operatorConfig.Admin.BindAddress,
+			CertDir:     operatorConfig.Admin.CertDir,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to set up the admin api", "address", operatorConfig.Admin.BindAddress)
+			os.Exit(1)
+		}
+	}
+	// features.webhooks only turns off validation, the conversion webhook is needed to serve v1 and v1alpha1
+	var aquaScannerAccountValidator *mamoadevopsgovbccav2.AquaScannerAccountValidator
+	if configv1alpha1.IsEnabled(operatorConfig.Features.Webhooks) {
+		aquaScannerAccountValidator = &mamoadevopsgovbccav2.AquaScannerAccountValidator{
+			Client:            mgr.GetClient(),
+			AllowedRegistries: operatorConfig.Scope.RegistriesAllowedIn,
+		}
+		if configv1alpha1.IsEnabled(operatorConfig.Features.AquaInstances) {
+			aquaScannerAccountValidator.InstanceAllowsNamespace = func(ctx context.Context, instance string, namespace string) (bool, error) {
+				return utils.AquaInstanceAllowsNamespace(ctx, mgr.GetClient(), instance, namespace)
+			}
+		}
+	}
+	if err = (&mamoadevopsgovbccav2.AquaScannerAccount{}).SetupWebhookWithManager(mgr, aquaScannerAccountValidator); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "AquaScannerAccount")
 		os.Exit(1)
 	}
@@ -105,8 +271,96 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctx)
+
+	if tracerProvider != nil {
+		// the spans still buffered are exported before the manager exits
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if shutdownErr := tracerProvider.Shutdown(shutdownCtx); shutdownErr != nil {
+			setupLog.Error(shutdownErr, "unable to export the remaining spans")
+		}
+	}
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+/*
+	Builds the client for the operator's own aqua instance. Settings in the config file take precedence over the
+	AQUA_* env vars, which are still read so existing deployments keep working. The credentials are read from the env
+	here and replaced by the credentials secret once it has been reconciled.
+*/
+func newOperatorAquaAuth(config configv1alpha1.AquaConfig) (*utils.AquaAuth, error) {
+	httpConfig, err := utils.AquaHttpConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := httpConfig.LoadFiles(config.TLS.CABundleFile, config.TLS.ClientCertFile, config.TLS.ClientKeyFile); err != nil {
+		return nil, err
+	}
+	if config.TLS.InsecureSkipVerify {
+		httpConfig.InsecureSkipVerify = true
+	}
+	if config.TLS.MinVersion != "" {
+		httpConfig.MinTLSVersion = config.TLS.MinVersion
+	}
+	if config.HTTP.ProxyURL != "" {
+		httpConfig.ProxyUrl = config.HTTP.ProxyURL
+	}
+	if config.HTTP.NoProxy != "" {
+		httpConfig.NoProxy = config.HTTP.NoProxy
+	}
+	if config.HTTP.ConnectTimeout != nil {
+		httpConfig.ConnectTimeout = config.HTTP.ConnectTimeout.Duration
+	}
+	if config.HTTP.ReadTimeout != nil {
+		httpConfig.ReadTimeout = config.HTTP.ReadTimeout.Duration
+	}
+
+	client, err := utils.NewAquaHttpClient(httpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	aquaUrl := config.URL
+	if aquaUrl == "" {
+		aquaUrl = os.Getenv("AQUA_URL")
+	}
+
+	credentials := utils.AquaCredentialsFromEnv()
+	if config.AuthMethod != "" {
+		credentials.Method = config.AuthMethod
+	}
+
+	return &utils.AquaAuth{Url: aquaUrl, Credentials: credentials, Client: client}, nil
+}
+
+// exports spans to tracing.endpoint of the config file, nil when tracing is off
+func newTracerProvider(ctx context.Context, config configv1alpha1.TracingConfig) (*sdktrace.TracerProvider, error) {
+	if config.Endpoint == "" {
+		return nil, nil
+	}
+	return utils.NewOTLPTracerProvider(ctx, utils.TracingOptions{
+		Endpoint:      config.Endpoint,
+		Insecure:      config.Insecure,
+		SamplingRatio: *config.SamplingRatio,
+		ServiceName:   config.ServiceName,
+	})
+}
+
+// the sink of audit.sink in the config file, nil when the audit trail is off
+func newAuditSink(config configv1alpha1.AuditConfig) (utils.AuditSink, error) {
+	switch config.Sink {
+	case configv1alpha1.AuditSinkFile:
+		return utils.NewFileAuditSink(config.File, utils.AuditRetention{MaxAge: config.MaxAge.Duration, MaxRecords: config.MaxRecords})
+	case configv1alpha1.AuditSinkStdout:
+		return utils.NewWriterAuditSink(os.Stdout), nil
+	case configv1alpha1.AuditSinkHTTP:
+		return utils.NewHTTPAuditSink(config.URL, config.Timeout.Duration), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// the aqua objects the operator manages for an account, in the order they are created
+var accountReportKinds = []string{"ApplicationScope", "PermissionSet", "Role", "User"}
+
+// the values of AccountReport.Drift
+const (
+	// every aqua object is in the state the operator wants it in and no change is planned for it
+	DriftInSync = "InSync"
+	// an aqua object is not in the state the operator wants it in, or a change to it is planned in dry run mode
+	DriftDrifted = "Drifted"
+	// the account has not been reconciled yet
+	DriftUnknown = "Unknown"
+)
+
+// an aqua object of an AccountReport
+type AccountReportAquaObject struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// the change planned for the object while the account is in dry run mode
+	Planned string `json:"planned,omitempty"`
+}
+
+// what the admin api reports for an AquaScannerAccount, never the password
+type AccountReport struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// the contacts of the namespace
+	Owners           []Contact          `json:"owners"`
+	Instance         string             `json:"instance,omitempty"`
+	AquaVersion      string             `json:"aquaVersion,omitempty"`
+	State            string             `json:"state,omitempty"`
+	Message          string             `json:"message,omitempty"`
+	Ready            string             `json:"ready"`
+	Conditions       []metav1.Condition `json:"conditions"`
+	LastRotationTime *metav1.Time       `json:"lastRotationTime,omitempty"`
+	DryRun           bool               `json:"dryRun,omitempty"`
+	Drift            string             `json:"drift"`
+	// the kinds of the aqua objects that drifted
+	DriftedObjects []string                  `json:"driftedObjects,omitempty"`
+	AquaObjects    []AccountReportAquaObject `json:"aquaObjects"`
+}
+
+// reports account, owners are the contacts of its namespace
+func NewAccountReport(account *asav2.AquaScannerAccount, owners []Contact) AccountReport {
+	report := AccountReport{
+		Namespace:        account.Namespace,
+		Name:             account.Name,
+		Owners:           owners,
+		Instance:         account.Status.Instance,
+		AquaVersion:      account.Status.AquaVersion,
+		State:            account.Status.State,
+		Message:          account.Status.Message,
+		Ready:            string(metav1.ConditionUnknown),
+		Conditions:       account.Status.Conditions,
+		LastRotationTime: account.Status.LastRotationTime,
+		DryRun:           account.IsDryRun(),
+		Drift:            DriftInSync,
+		AquaObjects:      []AccountReportAquaObject{},
+	}
+	if report.Owners == nil {
+		report.Owners = []Contact{}
+	}
+	if report.Conditions == nil {
+		report.Conditions = []metav1.Condition{}
+	}
+	if ready := meta.FindStatusCondition(account.Status.Conditions, asav2.ConditionReady); ready != nil {
+		report.Ready = string(ready.Status)
+	}
+
+	planned := map[string]string{}
+	if account.Status.Plan != nil {
+		for _, change := range account.Status.Plan.Changes {
+			planned[change.Kind] = change.Action
+		}
+	}
+
+	// every aqua object of the account is named after the account
+	for _, kind := range accountReportKinds {
+		object := AccountReportAquaObject{Kind: kind, Name: account.Status.AccountName, State: account.Status.CurrentState.Get(kind), Planned: planned[kind]}
+		report.AquaObjects = append(report.AquaObjects, object)
+
+		if object.State != account.Status.DesiredState.Get(kind) || object.Planned != "" {
+			report.DriftedObjects = append(report.DriftedObjects, kind)
+		}
+	}
+
+	switch {
+	case account.Status.DesiredState == (asav2.AquaScannerAccountAquaObjectState{}):
+		report.Drift = DriftUnknown
+		report.DriftedObjects = nil
+	case len(report.DriftedObjects) > 0:
+		report.Drift = DriftDrifted
+	}
+	return report
+}
+
+// selects account reports, empty fields match every report
+type AccountReportQuery struct {
+	Namespace string
+	Name      string
+	Instance  string
+	State     string
+	// True, False or Unknown
+	Ready string
+	// InSync, Drifted or Unknown
+	Drift string
+	// part of the email of an owner, matched case insensitively
+	Owner string
+	// the name of an aqua object of the account
+	AquaObject string
+	// accounts whose password was last rotated before RotatedBefore, or never
+	RotatedBefore time.Time
+}
+
+func (q AccountReportQuery) Matches(report AccountReport) bool {
+	switch {
+	case q.Namespace != "" && report.Namespace != q.Namespace,
+		q.Name != "" && report.Name != q.Name,
+		q.Instance != "" && report.Instance != q.Instance,
+		q.State != "" && report.State != q.State,
+		q.Ready != "" && report.Ready != q.Ready,
+		q.Drift != "" && report.Drift != q.Drift,
+		q.Owner != "" && !report.hasOwner(q.Owner),
+		q.AquaObject != "" && !report.hasAquaObject(q.AquaObject),
+		!q.RotatedBefore.IsZero() && report.LastRotationTime != nil && !report.LastRotationTime.Time.Before(q.RotatedBefore):
+		return false
+	}
+	return true
+}
+
+func (r AccountReport) hasOwner(email string) bool {
+	for _, owner := range r.Owners {
+		if strings.Contains(strings.ToLower(owner.Email), strings.ToLower(email)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r AccountReport) hasAquaObject(name string) bool {
+	for _, object := range r.AquaObjects {
+		if object.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// the columns WriteAccountReportsCsv writes, one row per report
+var accountReportCsvHeader = []string{
+	"namespace", "name", "owners", "instance", "aquaVersion", "state", "ready", "conditions", "lastRotationTime",
+	"drift", "driftedObjects", "applicationScope", "permissionSet", "role", "user",
+}
+
+// writes the reports as csv, lists are joined with "; " so the export opens in a spreadsheet as it is
+func WriteAccountReportsCsv(w io.Writer, reports []AccountReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(accountReportCsvHeader); err != nil {
+		return err
+	}
+
+	for _, report := range reports {
+		owners := []string{}
+		for _, owner := range report.Owners {
+			owners = append(owners, owner.String())
+		}
+		conditions := []string{}
+		for _, condition := range report.Conditions {
+			conditions = append(conditions, fmt.Sprintf("%v=%v (%v)", condition.Type, condition.Status, condition.Reason))
+		}
+		lastRotationTime := ""
+		if report.LastRotationTime != nil {
+			lastRotationTime = report.LastRotationTime.UTC().Format(time.RFC3339)
+		}
+
+		row := []string{
+			report.Namespace, report.Name, strings.Join(owners, "; "), report.Instance, report.AquaVersion, report.State,
+			report.Ready, strings.Join(conditions, "; "), lastRotationTime, report.Drift, strings.Join(report.DriftedObjects, "; "),
+		}
+		for _, object := range report.AquaObjects {
+			row = append(row, object.Name)
+		}
+		for i := range row {
+			row[i] = escapeCsvFormula(row[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+/*
+	quotes a cell a spreadsheet would run as a formula, the namespaces, contacts and messages of the export are
+	written by the teams, so a contact like =HYPERLINK(...) would run on the admin's machine
+*/
+func escapeCsvFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+var createdState = asav2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", User: "Created", Role: "Created"}
+
+func reportedAccount(namespace string, status asav2.AquaScannerAccountStatus) *asav2.AquaScannerAccount {
+	account := &asav2.AquaScannerAccount{Status: status}
+	account.Name = "scanner"
+	account.Namespace = namespace
+	return account
+}
+
+func TestNewAccountReport(t *testing.T) {
+	rotated := metav1.NewTime(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	owners := []Contact{{Role: "Technical Lead", Email: "patrick.simonian@gov.bc.ca"}}
+
+	report := NewAccountReport(reportedAccount("abc123-tools", asav2.AquaScannerAccountStatus{
+		State:            "Complete",
+		AccountName:      "ScannerCLI_abc123",
+		Instance:         "production",
+		AquaVersion:      "2022.4.46",
+		CurrentState:     createdState,
+		DesiredState:     createdState,
+		LastRotationTime: &rotated,
+		Conditions:       []metav1.Condition{{Type: asav2.ConditionReady, Status: metav1.ConditionTrue, Reason: "Reconciled"}},
+	}), owners)
+
+	if report.Drift != DriftInSync || len(report.DriftedObjects) != 0 || report.Ready != "True" {
+		t.Errorf("an account whose aqua objects are created was supposed to be ready and in sync but got %+v", report)
+	}
+	if len(report.AquaObjects) != 4 || report.AquaObjects[0].Kind != "ApplicationScope" || report.AquaObjects[3].Name != "ScannerCLI_abc123" {
+		t.Errorf("the aqua objects were supposed to be named after the account but got %+v", report.AquaObjects)
+	}
+	if report.Instance != "production" || report.AquaVersion != "2022.4.46" || report.LastRotationTime != &rotated || report.Owners[0] != owners[0] {
+		t.Errorf("the status and owners of the account were supposed to be reported but got %+v", report)
+	}
+
+	drifted := NewAccountReport(reportedAccount("abc123-tools", asav2.AquaScannerAccountStatus{
+		AccountName:  "ScannerCLI_abc123",
+		CurrentState: asav2.AquaScannerAccountAquaObjectState{ApplicationScope: "Created", PermissionSet: "Created", User: "Not Created", Role: "Created"},
+		DesiredState: createdState,
+		Plan:         &asav2.AquaScannerAccountPlan{Changes: []asav2.AquaPlannedChange{{Kind: "Role", Action: "Update"}}},
+	}), nil)
+
+	if drifted.Drift != DriftDrifted || len(drifted.DriftedObjects) != 2 || drifted.DriftedObjects[0] != "Role" || drifted.DriftedObjects[1] != "User" {
+		t.Errorf("the missing user and planned change to the role were supposed to be drift but got %+v", drifted)
+	}
+	if drifted.Ready != "Unknown" || drifted.Owners == nil || drifted.Conditions == nil {
+		t.Errorf("an account without conditions or owners was supposed to be reported with empty lists but got %+v", drifted)
+	}
+
+	if unreconciled := NewAccountReport(reportedAccount("abc123-tools", asav2.AquaScannerAccountStatus{}), nil); unreconciled.Drift != DriftUnknown {
+		t.Errorf("an account that was never reconciled was supposed to have an unknown drift but got %+v", unreconciled)
+	}
+}
+
+func TestAccountReportQuery(t *testing.T) {
+	rotated := metav1.NewTime(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	report := NewAccountReport(reportedAccount("abc123-tools", asav2.AquaScannerAccountStatus{
+		State:            "Complete",
+		AccountName:      "ScannerCLI_abc123",
+		CurrentState:     createdState,
+		DesiredState:     createdState,
+		LastRotationTime: &rotated,
+	}), []Contact{{Role: "Technical Lead", Email: "Patrick.Simonian@gov.bc.ca"}})
+
+	matching := []AccountReportQuery{
+		{},
+		{Namespace: "abc123-tools", Name: "scanner", State: "Complete", Ready: "Unknown", Drift: DriftInSync},
+		{Owner: "patrick.simonian"},
+		{AquaObject: "ScannerCLI_abc123"},
+		{RotatedBefore: rotated.Add(time.Hour)},
+	}
+	for _, query := range matching {
+		if !query.Matches(report) {
+			t.Errorf("%+v was supposed to match the report", query)
+		}
+	}
+
+	notMatching := []AccountReportQuery{
+		{Namespace: "def456-tools"},
+		{Instance: "production"},
+		{Drift: DriftDrifted},
+		{Owner: "matt.damon"},
+		{AquaObject: "ScannerCLI_def456"},
+		{RotatedBefore: rotated.Add(-time.Hour)},
+	}
+	for _, query := range notMatching {
+		if query.Matches(report) {
+			t.Errorf("%+v was not supposed to match the report", query)
+		}
+	}
+}
+
+func TestWriteAccountReportsCsv(t *testing.T) {
+	rotated := metav1.NewTime(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	report := NewAccountReport(reportedAccount("abc123-tools", asav2.AquaScannerAccountStatus{
+		State:            "Complete",
+		AccountName:      "ScannerCLI_abc123",
+		CurrentState:     createdState,
+		DesiredState:     createdState,
+		LastRotationTime: &rotated,
+		Conditions:       []metav1.Condition{{Type: asav2.ConditionReady, Status: metav1.ConditionTrue, Reason: "Reconciled"}},
+	}), []Contact{{Role: "Product Owner", Email: "matt.damon@gov.bc.ca"}, {Role: "Technical Lead", Email: "patrick.simonian@gov.bc.ca"}})
+
+	out := &bytes.Buffer{}
+	if err := WriteAccountReportsCsv(out, []AccountReport{report}); err != nil {
+		t.Fatalf("WriteAccountReportsCsv was not supposed to return an error but got %v", err)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("the csv was supposed to hold a header and a row but got %v, %v", rows, err)
+	}
+	row := map[string]string{}
+	for i, column := range rows[0] {
+		row[column] = rows[1][i]
+	}
+
+	expected := map[string]string{
+		"namespace":        "abc123-tools",
+		"owners":           "Product Owner <matt.damon@gov.bc.ca>; Technical Lead <patrick.simonian@gov.bc.ca>",
+		"ready":            "True",
+		"conditions":       "Ready=True (Reconciled)",
+		"lastRotationTime": "2026-03-01T12:00:00Z",
+		"drift":            "InSync",
+		"user":             "ScannerCLI_abc123",
+	}
+	for column, value := range expected {
+		if row[column] != value {
+			t.Errorf("the %v column was supposed to be %q but got %q", column, value, row[column])
+		}
+	}
+}
+
+func TestWriteAccountReportsCsvEscapesFormulas(t *testing.T) {
+	report := NewAccountReport(reportedAccount("abc123-tools", asav2.AquaScannerAccountStatus{AccountName: "ScannerCLI_abc123"}),
+		[]Contact{{Role: "=HYPERLINK(\"https://example.com\")", Email: "matt.damon@gov.bc.ca"}})
+	report.Instance = "@SUM(A1:A2)"
+	report.AquaVersion = "-1+1"
+	report.State = "+1"
+
+	out := &bytes.Buffer{}
+	if err := WriteAccountReportsCsv(out, []AccountReport{report}); err != nil {
+		t.Fatalf("WriteAccountReportsCsv was not supposed to return an error but got %v", err)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("the csv was supposed to hold a header and a row but got %v, %v", rows, err)
+	}
+	row := map[string]string{}
+	for i, column := range rows[0] {
+		row[column] = rows[1][i]
+	}
+
+	expected := map[string]string{
+		"namespace":   "abc123-tools",
+		"owners":      "'=HYPERLINK(\"https://example.com\") <matt.damon@gov.bc.ca>",
+		"instance":    "'@SUM(A1:A2)",
+		"aquaVersion": "'-1+1",
+		"state":       "'+1",
+	}
+	for column, value := range expected {
+		if row[column] != value {
+			t.Errorf("the %v column was supposed to be %q but got %q", column, value, row[column])
+		}
+	}
+}
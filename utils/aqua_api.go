@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// where aqua serves the version of the console
+const aquaVersionPath = "/api/v1/version"
+
+// how long a detected version is trusted before it is read again, so an upgrade of aqua is picked up
+const aquaVersionTTL = 5 * time.Minute
+
+// the version of an aqua console as it reports it, e.g. 6.5.22034 or 2022.4.46
+type AquaVersion struct {
+	Major int
+	Minor int
+	Raw   string
+}
+
+func (v AquaVersion) String() string {
+	return v.Raw
+}
+
+// returns the version in s, only the major and minor version are needed to pick the api generation
+func ParseAquaVersion(s string) (AquaVersion, error) {
+	raw := strings.TrimSpace(s)
+	parts := strings.SplitN(strings.TrimPrefix(raw, "v"), ".", 3)
+	if len(parts) < 2 {
+		return AquaVersion{}, fmt.Errorf("unable to read the aqua version %q, expected MAJOR.MINOR", s)
+	}
+
+	major, majorErr := strconv.Atoi(parts[0])
+	minor, minorErr := strconv.Atoi(parts[1])
+	if majorErr != nil || minorErr != nil {
+		return AquaVersion{}, fmt.Errorf("unable to read the aqua version %q, expected MAJOR.MINOR", s)
+	}
+	return AquaVersion{Major: major, Minor: minor, Raw: raw}, nil
+}
+
+// the aqua versions the operator can talk to
+const supportedAquaVersions = "5.x, 6.x and 2022.4 and later"
+
+// returned for an aqua version no AquaAPI supports, nothing is sent to such an aqua
+type UnsupportedAquaVersionError struct {
+	Version AquaVersion
+}
+
+func (e *UnsupportedAquaVersionError) Error() string {
+	return fmt.Sprintf("aqua %v is not supported, the operator supports aqua %v", e.Version, supportedAquaVersions)
+}
+
+func IsUnsupportedAquaVersion(err error) bool {
+	var unsupported *UnsupportedAquaVersionError
+	return errors.As(err, &unsupported)
+}
+
+// what a request to aqua does to an aqua object
+type AquaAction string
+
+const (
+	AquaGet    AquaAction = "Get"
+	AquaCreate AquaAction = "Create"
+	AquaUpdate AquaAction = "Update"
+	AquaDelete AquaAction = "Delete"
+)
+
+// the method and path of a request to aqua
+type AquaRequest struct {
+	Method string
+	Path   string
+	// the body of a Delete that names the objects it removes, Creates and Updates send the rendered template
+	Payload []byte
+}
+
+/*
+	One generation of the aqua api. The AquaResources ask the AquaAPI of their aqua where an object lives and what
+	aqua's answers mean, so supporting a new generation of aqua means adding an AquaAPI and picking it in
+	AquaAPIForVersion.
+*/
+type AquaAPI interface {
+	// the first aqua release that serves the generation, e.g. 2022.4
+	Generation() string
+	// the request for action on the aqua object of kind called name
+	Request(kind string, action AquaAction, name string) (AquaRequest, error)
+	// whether aqua's answer to a Get or Delete means the object does not exist
+	IsNotFound(kind string, statusCode int, message string) bool
+	// whether aqua's answer to a Create means an object called name exists already
+	IsAlreadyExists(kind string, name string, statusCode int, message string) bool
+	// where aqua serves its password policy
+	PasswordPolicyPath() string
+}
+
+// returns the AquaAPI that talks to aqua version, or an UnsupportedAquaVersionError
+func AquaAPIForVersion(version AquaVersion) (AquaAPI, error) {
+	switch {
+	case version.Major > 2022 || version.Major == 2022 && version.Minor >= 4:
+		return aquaAPIV2022{}, nil
+	case version.Major == 5 || version.Major == 6:
+		return aquaAPIV5{}, nil
+	}
+	return nil, &UnsupportedAquaVersionError{Version: version}
+}
+
+/*
+	Aqua 5.0 up to 6.5. Users are managed through /api/v1/users, roles, permission sets and application scopes through
+	/api/v2/access_management. Aqua answers 400 or 404 with a message for objects that are missing or exist already.
+*/
+type aquaAPIV5 struct{}
+
+func (aquaAPIV5) Generation() string { return "5.0" }
+
+func (aquaAPIV5) Request(kind string, action AquaAction, name string) (AquaRequest, error) {
+	switch kind {
+	case "User":
+		return restRequest("/api/v1/users", action, name), nil
+	case "Role":
+		return restRequest("/api/v2/access_management/roles", action, name), nil
+	case "PermissionSet":
+		// every action goes to one collection, otherwise Observe misses the permission set and its drift is never fixed
+		if action == AquaDelete {
+			return bulkDeleteRequest("DELETE", "/api/v2/access_management/permissions/"+name, name)
+		}
+		return restRequest("/api/v2/access_management/permissions", action, name), nil
+	case "ApplicationScope":
+		if action == AquaDelete {
+			return bulkDeleteRequest("POST", "/api/v2/access_management/scopes/delete", name)
+		}
+		return restRequest("/api/v2/access_management/scopes", action, name), nil
+	}
+	return AquaRequest{}, fmt.Errorf("aqua %v does not manage %v objects", aquaAPIV5{}.Generation(), kind)
+}
+
+func (aquaAPIV5) IsNotFound(kind string, statusCode int, message string) bool {
+	return statusCode == 404 || statusCode == 400 && strings.HasPrefix(message, "No such")
+}
+
+func (aquaAPIV5) IsAlreadyExists(kind string, name string, statusCode int, message string) bool {
+	switch kind {
+	case "User":
+		return statusCode == 400 && strings.Contains(message, "User with username "+name+" already exists")
+	case "Role":
+		return statusCode == 404 && strings.Contains(message, "role "+name+" already exists")
+	case "PermissionSet":
+		return statusCode == 404 && strings.Contains(message, "permission "+name+" already exists")
+	case "ApplicationScope":
+		return statusCode == 404 && strings.Contains(message, "application scope "+name+" already exists")
+	}
+	return false
+}
+
+func (aquaAPIV5) PasswordPolicyPath() string { return "/api/v1/settings/password_policy" }
+
+/*
+	Aqua 2022.4 and later. Users moved to /api/v2/access_management with the other objects and aqua answers with the
+	status code alone, 404 for a missing object and 409 for one that exists already.
+*/
+type aquaAPIV2022 struct {
+	aquaAPIV5
+}
+
+func (aquaAPIV2022) Generation() string { return "2022.4" }
+
+func (api aquaAPIV2022) Request(kind string, action AquaAction, name string) (AquaRequest, error) {
+	if kind == "User" {
+		return restRequest("/api/v2/access_management/users", action, name), nil
+	}
+	return api.aquaAPIV5.Request(kind, action, name)
+}
+
+func (aquaAPIV2022) IsNotFound(kind string, statusCode int, message string) bool {
+	return statusCode == 404
+}
+
+func (aquaAPIV2022) IsAlreadyExists(kind string, name string, statusCode int, message string) bool {
+	return statusCode == 409
+}
+
+// the requests of an object under collection
+func restRequest(collection string, action AquaAction, name string) AquaRequest {
+	switch action {
+	case AquaCreate:
+		return AquaRequest{Method: "POST", Path: collection}
+	case AquaUpdate:
+		return AquaRequest{Method: "PUT", Path: collection + "/" + name}
+	case AquaDelete:
+		return AquaRequest{Method: "DELETE", Path: collection + "/" + name}
+	}
+	return AquaRequest{Method: "GET", Path: collection + "/" + name}
+}
+
+// a delete whose body lists the names of the objects it removes
+func bulkDeleteRequest(method string, path string, name string) (AquaRequest, error) {
+	payload, err := json.Marshal([]string{name})
+	if err != nil {
+		return AquaRequest{}, err
+	}
+	return AquaRequest{Method: method, Path: path, Payload: payload}, nil
+}
+
+// the answer of aqua to a GET of aquaVersionPath
+type aquaVersionResponse struct {
+	Version string `json:"version"`
+}
+
+// asks aqua which version it runs
+func getAquaVersion(ctx context.Context, aa *AquaAuth) (AquaVersion, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", aa.GetUrl()+aquaVersionPath, nil)
+	if err != nil {
+		return AquaVersion{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if err := aa.Authenticate(req); err != nil {
+		return AquaVersion{}, err
+	}
+
+	res, err := aa.HttpClient().Do(req)
+	if err != nil {
+		return AquaVersion{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return AquaVersion{}, fmt.Errorf("failed to read the version of aqua, the response status from aqua was %v", res.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return AquaVersion{}, err
+	}
+	version := aquaVersionResponse{}
+	if err := json.Unmarshal(body, &version); err != nil {
+		return AquaVersion{}, fmt.Errorf("failed to read the version of aqua: %v", err)
+	}
+	return ParseAquaVersion(version.Version)
+}
@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestParseAquaVersion(t *testing.T) {
+	cases := []struct {
+		version string
+		major   int
+		minor   int
+		valid   bool
+	}{
+		{"6.5.22034", 6, 5, true},
+		{"2022.4.46", 2022, 4, true},
+		{" v5.3 ", 5, 3, true},
+		{"6", 0, 0, false},
+		{"latest", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, c := range cases {
+		version, err := ParseAquaVersion(c.version)
+		if (err == nil) != c.valid {
+			t.Errorf("ParseAquaVersion(%q) returned the error %v", c.version, err)
+			continue
+		}
+		if c.valid && (version.Major != c.major || version.Minor != c.minor) {
+			t.Errorf("ParseAquaVersion(%q) was supposed to be %v.%v but got %+v", c.version, c.major, c.minor, version)
+		}
+	}
+}
+
+func TestAquaAPIForVersion(t *testing.T) {
+	cases := map[string]string{
+		"4.6.20":    "",
+		"5.3.21":    "5.0",
+		"6.5.22034": "5.0",
+		"7.0.1":     "",
+		"2022.2.1":  "",
+		"2022.4.46": "2022.4",
+		"2023.1.0":  "2022.4",
+	}
+
+	for raw, generation := range cases {
+		version, _ := ParseAquaVersion(raw)
+		api, err := AquaAPIForVersion(version)
+
+		if generation == "" {
+			if !IsUnsupportedAquaVersion(err) || api != nil {
+				t.Errorf("aqua %v was supposed to be unsupported but got %v, %v", raw, api, err)
+			}
+			continue
+		}
+		if err != nil || api.Generation() != generation {
+			t.Errorf("aqua %v was supposed to be talked to with the %v api but got %v, %v", raw, generation, api, err)
+		}
+	}
+}
+
+func TestAquaAPIRequests(t *testing.T) {
+	cases := []struct {
+		api     AquaAPI
+		kind    string
+		action  AquaAction
+		method  string
+		path    string
+		payload string
+	}{
+		{aquaAPIV5{}, "User", AquaGet, "GET", "/api/v1/users/ScannerCLI_abc123", ""},
+		{aquaAPIV5{}, "User", AquaCreate, "POST", "/api/v1/users", ""},
+		{aquaAPIV5{}, "Role", AquaUpdate, "PUT", "/api/v2/access_management/roles/ScannerCLI_abc123", ""},
+		{aquaAPIV5{}, "PermissionSet", AquaGet, "GET", "/api/v2/access_management/permissions/ScannerCLI_abc123", ""},
+		{aquaAPIV5{}, "PermissionSet", AquaUpdate, "PUT", "/api/v2/access_management/permissions/ScannerCLI_abc123", ""},
+		{aquaAPIV5{}, "PermissionSet", AquaCreate, "POST", "/api/v2/access_management/permissions", ""},
+		{aquaAPIV5{}, "PermissionSet", AquaDelete, "DELETE", "/api/v2/access_management/permissions/ScannerCLI_abc123", `["ScannerCLI_abc123"]`},
+		{aquaAPIV5{}, "ApplicationScope", AquaDelete, "POST", "/api/v2/access_management/scopes/delete", `["ScannerCLI_abc123"]`},
+		{aquaAPIV2022{}, "User", AquaGet, "GET", "/api/v2/access_management/users/ScannerCLI_abc123", ""},
+		{aquaAPIV2022{}, "User", AquaDelete, "DELETE", "/api/v2/access_management/users/ScannerCLI_abc123", ""},
+		{aquaAPIV2022{}, "ApplicationScope", AquaCreate, "POST", "/api/v2/access_management/scopes", ""},
+	}
+
+	for _, c := range cases {
+		request, err := c.api.Request(c.kind, c.action, "ScannerCLI_abc123")
+		if err != nil {
+			t.Errorf("%v of a %v with the %v api returned the error %v", c.action, c.kind, c.api.Generation(), err)
+			continue
+		}
+		if request.Method != c.method || request.Path != c.path || string(request.Payload) != c.payload {
+			t.Errorf("%v of a %v with the %v api was supposed to be %v %v %v but got %v %v %s", c.action, c.kind, c.api.Generation(), c.method, c.path, c.payload, request.Method, request.Path, request.Payload)
+		}
+	}
+
+	if _, err := (aquaAPIV2022{}).Request("Registry", AquaGet, "docker.io"); err == nil {
+		t.Errorf("a request for a kind the api does not manage was supposed to return an error")
+	}
+}
+
+// an object read at one path and changed at another is never observed, so its drift is never corrected
+func TestAquaAPIRequestsShareACollection(t *testing.T) {
+	for _, api := range []AquaAPI{aquaAPIV5{}, aquaAPIV2022{}} {
+		for _, kind := range []string{"ApplicationScope", "PermissionSet", "Role", "User"} {
+			get, _ := api.Request(kind, AquaGet, "ScannerCLI_abc123")
+			collection := strings.TrimSuffix(get.Path, "/ScannerCLI_abc123")
+
+			for _, action := range []AquaAction{AquaCreate, AquaUpdate, AquaDelete} {
+				request, err := api.Request(kind, action, "ScannerCLI_abc123")
+				if err != nil || !strings.HasPrefix(request.Path, collection) {
+					t.Errorf("%v of a %v with the %v api was supposed to go to %v like its Get but got %v, %v", action, kind, api.Generation(), collection, request.Path, err)
+				}
+			}
+		}
+	}
+}
+
+func TestAquaAPIUnknownKind(t *testing.T) {
+	if _, err := (aquaAPIV5{}).Request("Registry", AquaGet, "docker.io"); err == nil {
+		t.Errorf("a request for a kind the api does not manage was supposed to return an error")
+	}
+}
+
+// an aqua that reports version, which can be changed to upgrade it
+type versionedAqua struct {
+	mu       sync.Mutex
+	version  string
+	requests int
+}
+
+func (a *versionedAqua) setVersion(version string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.version = version
+}
+
+func (a *versionedAqua) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r.URL.Path != aquaVersionPath {
+		w.WriteHeader(404)
+		return
+	}
+	a.requests++
+	fmt.Fprintf(w, `{"version":%q}`, a.version)
+}
+
+func TestAquaAuthDetectsTheAquaVersion(t *testing.T) {
+	aqua := &versionedAqua{version: "6.5.22034"}
+	server := httptest.NewServer(aqua)
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+
+	api, version, err := aa.API(context.Background())
+	if err != nil || api.Generation() != "5.0" || version.String() != "6.5.22034" {
+		t.Fatalf("aqua 6.5 was supposed to be talked to with the 5.0 api but got %v, %v, %v", api, version, err)
+	}
+
+	// the version is not read again while it is fresh
+	aqua.setVersion("2022.4.46")
+	if api, _, _ := aa.API(context.Background()); api.Generation() != "5.0" || aqua.requests != 1 {
+		t.Errorf("the detected version was supposed to be reused but got the %v api after %v requests", api.Generation(), aqua.requests)
+	}
+
+	// the check of the aqua instance picks up the upgrade
+	if _, _, err := aa.DetectAPI(context.Background()); err != nil {
+		t.Fatalf("DetectAPI was not supposed to return an error but got %v", err)
+	}
+	if api, version, _ := aa.API(context.Background()); api.Generation() != "2022.4" || version.String() != "2022.4.46" {
+		t.Errorf("the upgrade of aqua was supposed to be picked up but got the %v api for %v", api.Generation(), version)
+	}
+
+	// a new url is a different aqua
+	other := httptest.NewServer(&versionedAqua{version: "7.0.1"})
+	defer other.Close()
+	aa.SetCredentials(other.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"})
+	api, version, err = aa.API(context.Background())
+	if !IsUnsupportedAquaVersion(err) || api != nil || version.String() != "7.0.1" {
+		t.Errorf("aqua 7.0 was supposed to be refused with its version but got %v, %v, %v", api, version, err)
+	}
+}
+
+func TestAquaAuthVersionErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+
+	api, version, err := aa.API(context.Background())
+	if err == nil || IsUnsupportedAquaVersion(err) || api != nil || version.Raw != "" {
+		t.Errorf("a version aqua did not answer with was supposed to be an error but got %v, %v, %v", api, version, err)
+	}
+
+	c := AquaResourceClient{Logger: ctrl.Log, AquaAuth: aa, Templates: AquaTemplates{Dir: "../templates"}}
+	if _, _, err := c.getAquaObject(context.Background(), "User", "ScannerCLI_abc123"); err == nil {
+		t.Errorf("a request without an aqua api was supposed to return an error")
+	}
+}
+
+func TestAquaAPIV2022StatusCodes(t *testing.T) {
+	requests := []string{}
+	mu := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(409)
+			fmt.Fprint(w, `{"message":"conflict"}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	c := AquaResourceClient{Logger: ctrl.Log, AquaAuth: aa, API: aquaAPIV2022{}, Templates: AquaTemplates{Dir: "../templates"}}
+	user := &UserResource{AquaResourceClient: c, User: User{Name: "ScannerCLI_abc123", Role: Role{Name: "ScannerCLI_abc123"}, Password: "hunter2hunter2"}}
+
+	if err := user.Create(context.Background()); err != nil {
+		t.Errorf("a user that exists already was supposed to be accepted but got %v", err)
+	}
+	if err := user.Delete(context.Background()); err != nil {
+		t.Errorf("a user that does not exist was supposed to be accepted as deleted but got %v", err)
+	}
+
+	expected := []string{"POST /api/v2/access_management/users", "DELETE /api/v2/access_management/users/ScannerCLI_abc123"}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("the user was supposed to be managed through the access management api but got %v", requests)
+	}
+}
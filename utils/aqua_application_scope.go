@@ -1,18 +1,10 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"html/template"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type ApplicationScope struct {
@@ -20,109 +12,98 @@ type ApplicationScope struct {
 	NamespacePrefix    string
 	Description        string
 	TechnicalLeadEmail string
+	// registries any image of can be scanned, see NewApplicationScopeRegistries
+	Registries []ApplicationScopeRegistry
 }
 
-func DeleteAquaApplicationScope(reqLogger *log.DelegatingLogger, applicationScope string) error {
-	reqLogger.Info("Deleting applicationScope %v in aqua", "applicationScope", applicationScope)
-
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
-
-	reqPayload, jsonErr := json.Marshal([]string{applicationScope})
+// a registry of an ApplicationScope with the numbers of the variables the template matches it with
+type ApplicationScopeRegistry struct {
+	Name             string
+	RegistryVariable int
+	RepoVariable     int
+}
 
-	if jsonErr != nil {
-		reqLogger.Error(jsonErr, "Failed to marshal json %v", []string{applicationScope})
-		return jsonErr
+/*
+	Numbers the registries for the image expression of the ApplicationScope template. v1 to v4 match the namespace's
+	repositories in the cluster registries so the registries start at v5, which keeps Docker Hub, the default, where it
+	always was.
+*/
+func NewApplicationScopeRegistries(names []string) []ApplicationScopeRegistry {
+	registries := []ApplicationScopeRegistry{}
+	for i, name := range names {
+		registries = append(registries, ApplicationScopeRegistry{Name: name, RegistryVariable: 5 + 2*i, RepoVariable: 6 + 2*i})
 	}
+	return registries
+}
 
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v2/access_management/scopes/delete"
-	client := &http.Client{}
-
-	req, _ := http.NewRequest("POST", reqUrl, bytes.NewBuffer(reqPayload))
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+func DeleteAquaApplicationScope(ctx context.Context, c AquaResourceClient, applicationScope string) error {
+	c.Logger.Info("Deleting applicationScope in aqua", "applicationScope", applicationScope)
 
-	res, err := client.Do(req)
+	statusCode, ok, err := c.deleteAquaObject(ctx, "ApplicationScope", applicationScope)
 
 	if err != nil {
-		reqLogger.Error(err, "Failed request to POST to /api/v2/access_management/scopes/delete in aqua")
 		return err
 	}
 
-	if res.StatusCode != 204 && res.StatusCode != 404 {
-		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not delete application scope, the response status from aqua was %v", res.StatusCode))
+	if !ok {
+		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not delete application scope, the response status from aqua was %v", statusCode))
 		return e
 	}
 	return nil
 }
 
-func CreateAquaApplicationScope(reqLogger *log.DelegatingLogger, appScope ApplicationScope) error {
-	wd, _ := os.Getwd()
-
-	reqLogger.Info("Creating applicationScope %v-* in aqua", "Namespace Prefix", appScope.NamespacePrefix)
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
+func CreateAquaApplicationScope(ctx context.Context, c AquaResourceClient, appScope ApplicationScope) error {
+	c.Logger.Info("Creating applicationScope in aqua", "applicationScope", appScope.Name, "namespacePrefix", appScope.NamespacePrefix)
 
-	path := filepath.Join(wd, "./templates/ApplicationScope.json.tmpl")
+	payload, renderErr := renderAquaTemplate(c.Logger, c.Templates, "ApplicationScope", appScope)
 
-	b, fileErr := ioutil.ReadFile(path)
-
-	if fileErr != nil {
-		reqLogger.Error(fileErr, "Failed to read template file ApplicationScope.json.tmpl")
-		return fileErr
+	if renderErr != nil {
+		return renderErr
 	}
 
-	ut, templateErr := template.New("ApplicationScope").Parse(string(b))
+	statusCode, ok, err := c.createAquaObject(ctx, "ApplicationScope", appScope.Name, payload)
 
-	if templateErr != nil {
-		reqLogger.Error(templateErr, "Failed to parse template file ApplicationScope.json.tmpl")
-		return templateErr
+	if err != nil {
+		return err
 	}
 
-	var appScopeBuffer bytes.Buffer
-	ut.Execute(&appScopeBuffer, appScope)
-
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v2/access_management/scopes"
-	client := &http.Client{}
-	req, clientErr := http.NewRequest("POST", reqUrl, bytes.NewBuffer(appScopeBuffer.Bytes()))
+	if ok {
+		return nil
+	} else {
+		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not create ApplicationScope, the response status from aqua was %v", statusCode))
 
-	if clientErr != nil {
-		reqLogger.Error(clientErr, "unable to create client")
-		return clientErr
+		c.Logger.Error(e, "Unable to create ApplicationScope", "applicationScope", appScope.Name)
+		return e
 	}
+}
 
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// the application scope an AquaScannerAccount's role is restricted to
+type ApplicationScopeResource struct {
+	AquaResourceClient
+	ApplicationScope ApplicationScope
+}
 
-	res, err := client.Do(req)
+func (r *ApplicationScopeResource) Kind() string { return "ApplicationScope" }
 
-	if err != nil {
-		reqLogger.Error(err, "Failed request to POST to  /api/v2/access_management/scopes in aqua")
-		return err
-	}
-	defer res.Body.Close()
+func (r *ApplicationScopeResource) DependsOn() []string { return nil }
+
+func (r *ApplicationScopeResource) Observe(ctx context.Context) ([]byte, bool, error) {
+	return r.getAquaObject(ctx, "ApplicationScope", r.ApplicationScope.Name)
+}
 
-	var jsonData AquaResponseJson
-	body, _ := ioutil.ReadAll(res.Body)
+func (r *ApplicationScopeResource) Render() ([]byte, error) {
+	return renderAquaTemplate(r.Logger, r.Templates, "ApplicationScope", r.ApplicationScope)
+}
 
-	json.Unmarshal(body, &jsonData)
+func (r *ApplicationScopeResource) Create(ctx context.Context) error {
+	return CreateAquaApplicationScope(ctx, r.AquaResourceClient, r.ApplicationScope)
+}
 
-	if res.StatusCode == 404 && strings.Contains(jsonData.Message, "application scope "+appScope.Name+" already exists") || res.StatusCode == 201 {
-		return nil
-	} else {
-		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not create ApplicationScope, the response status from aqua was %v", res.StatusCode))
+func (r *ApplicationScopeResource) Update(ctx context.Context) error {
+	r.Logger.Info("Updating applicationScope in aqua", "applicationScope", r.ApplicationScope.Name)
+	return sendAquaTemplate(ctx, r.AquaResourceClient, "ApplicationScope", AquaUpdate, r.ApplicationScope.Name, r.ApplicationScope)
+}
 
-		reqLogger.Error(e, "Unable to create ApplicationScope")
-		return e
-	}
+func (r *ApplicationScopeResource) Delete(ctx context.Context) error {
+	return DeleteAquaApplicationScope(ctx, r.AquaResourceClient, r.ApplicationScope.Name)
 }
@@ -0,0 +1,342 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// the outcome of a change recorded in the audit trail
+const (
+	// aqua made the change, or the object was already as asked for
+	AuditSucceeded = "Succeeded"
+	// aqua answered but turned the change down
+	AuditFailed = "Failed"
+	// aqua could not be reached or the request could not be sent
+	AuditError = "Error"
+)
+
+// the AquaScannerAccount and reconcile a change to aqua is made for
+type AuditSubject struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	UID         types.UID
+	ReconcileID string
+	// why the reconcile changes aqua, e.g. a requested rotation or the deletion of the account
+	Reason string
+}
+
+// AuditRecord is a POST, PUT or DELETE the operator sent to aqua
+type AuditRecord struct {
+	Time        time.Time `json:"time"`
+	ReconcileID string    `json:"reconcileID,omitempty"`
+	Kind        string    `json:"kind,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	UID         types.UID `json:"uid,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	// the kind of aqua object changed, e.g. User
+	AquaObject string `json:"aquaObject"`
+	Method     string `json:"method"`
+	AquaURL    string `json:"aquaURL"`
+	Endpoint   string `json:"endpoint"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	// the body sent to aqua with every secret field redacted
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// AuditSink is where audit records are written to
+type AuditSink interface {
+	Record(record AuditRecord) error
+}
+
+// the records written to an AuditSink are kept for MaxAge or until there are MaxRecords newer ones, 0 keeps them
+type AuditRetention struct {
+	MaxAge     time.Duration
+	MaxRecords int
+}
+
+// builds the record of a request to aqua, the payload is redacted and left out when it is not json
+func newAuditRecord(subject *AuditSubject, aquaObject string, method string, aquaURL string, endpoint string, payload []byte) AuditRecord {
+	record := AuditRecord{Time: time.Now().UTC(), AquaObject: aquaObject, Method: method, AquaURL: aquaURL, Endpoint: endpoint}
+	if subject != nil {
+		record.ReconcileID = subject.ReconcileID
+		record.Kind = subject.Kind
+		record.Namespace = subject.Namespace
+		record.Name = subject.Name
+		record.UID = subject.UID
+		record.Reason = subject.Reason
+	}
+	if len(payload) > 0 {
+		if redacted, err := RedactAquaPayload(payload); err == nil {
+			record.Payload = redacted
+		}
+	}
+	return record
+}
+
+// writes every record as a line of json, e.g. to stdout where the logging stack of the cluster collects it
+type WriterAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{w: w}
+}
+
+func (s *WriterAuditSink) Record(record AuditRecord) error {
+	line, err := marshalAuditRecord(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+/*
+	FileAuditSink appends every record as a line of json to a file. Records past the retention are dropped by
+	rewriting the file once it holds a tenth more records than MaxRecords, or at most once an hour for MaxAge.
+*/
+type FileAuditSink struct {
+	mu        sync.Mutex
+	path      string
+	retention AuditRetention
+	records   int
+	// when records older than MaxAge were last dropped
+	pruned time.Time
+}
+
+// how often records older than AuditRetention.MaxAge are dropped
+const auditPruneInterval = time.Hour
+
+// opens the file at path, creating it and its directory when needed, and drops the records past the retention
+func NewFileAuditSink(path string, retention AuditRetention) (*FileAuditSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	s := &FileAuditSink{path: path, retention: retention}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.prune(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileAuditSink) Record(record AuditRecord) error {
+	line, err := marshalAuditRecord(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.records++
+
+	overMaxRecords := s.retention.MaxRecords > 0 && s.records > s.retention.MaxRecords+s.retention.MaxRecords/10
+	pruneAge := s.retention.MaxAge > 0 && time.Since(s.pruned) > auditPruneInterval
+	if overMaxRecords || pruneAge {
+		return s.prune()
+	}
+	return nil
+}
+
+// Query returns the records in the file that match the query, oldest first
+func (s *FileAuditSink) Query(query AuditQuery) ([]AuditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []AuditRecord{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return QueryAuditRecords(f, query)
+}
+
+// rewrites the file without the records past the retention, the caller holds the lock
+func (s *FileAuditSink) prune() error {
+	s.pruned = time.Now()
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.records = 0
+			return nil
+		}
+		return err
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	if len(data) == 0 {
+		lines = nil
+	}
+
+	kept := [][]byte{}
+	for _, line := range lines {
+		if s.retention.MaxAge > 0 {
+			record := AuditRecord{}
+			// lines that can not be read are kept, retention does not decide about them
+			if err := json.Unmarshal(line, &record); err == nil && time.Since(record.Time) > s.retention.MaxAge {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	if s.retention.MaxRecords > 0 && len(kept) > s.retention.MaxRecords {
+		kept = kept[len(kept)-s.retention.MaxRecords:]
+	}
+
+	s.records = len(kept)
+	if len(kept) == len(lines) {
+		return nil
+	}
+
+	// written next to the file and renamed so a crash does not leave half a trail behind
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	for _, line := range kept {
+		if _, err := tmp.Write(append(line, '\n')); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// posts every record as json to a collector such as a SIEM's http input
+type HTTPAuditSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPAuditSink(url string, timeout time.Duration) *HTTPAuditSink {
+	return &HTTPAuditSink{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+func (s *HTTPAuditSink) Record(record AuditRecord) error {
+	body, err := marshalAuditRecord(record)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.Client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("the audit collector at %v answered %v", s.URL, res.Status)
+	}
+	return nil
+}
+
+func marshalAuditRecord(record AuditRecord) ([]byte, error) {
+	line, err := marshalPlanJson(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// selects audit records, empty fields match every record
+type AuditQuery struct {
+	Namespace   string
+	Name        string
+	UID         types.UID
+	ReconcileID string
+	AquaObject  string
+	Method      string
+	Outcome     string
+	// records at or after Since and before Until
+	Since time.Time
+	Until time.Time
+}
+
+func (q AuditQuery) Matches(record AuditRecord) bool {
+	switch {
+	case q.Namespace != "" && record.Namespace != q.Namespace,
+		q.Name != "" && record.Name != q.Name,
+		q.UID != "" && record.UID != q.UID,
+		q.ReconcileID != "" && record.ReconcileID != q.ReconcileID,
+		q.AquaObject != "" && record.AquaObject != q.AquaObject,
+		q.Method != "" && record.Method != q.Method,
+		q.Outcome != "" && record.Outcome != q.Outcome,
+		!q.Since.IsZero() && record.Time.Before(q.Since),
+		!q.Until.IsZero() && !record.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// QueryAuditRecords reads json lines audit records from r and returns the ones matching the query in the order read
+func QueryAuditRecords(r io.Reader, query AuditQuery) ([]AuditRecord, error) {
+	records := []AuditRecord{}
+
+	scanner := bufio.NewScanner(r)
+	// payloads of application scopes with many registries make for long lines
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		record := AuditRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("line %v is not an audit record: %v", lineNumber, err)
+		}
+		if query.Matches(record) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
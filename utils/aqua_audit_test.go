@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// keeps the records in memory
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *memoryAuditSink) Record(record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestAquaChangesAreAudited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/v1/users":
+			w.WriteHeader(204)
+		case r.Method == "DELETE" && r.URL.Path == "/api/v2/access_management/roles/ScannerCLI_abc123":
+			w.WriteHeader(500)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	sink := &memoryAuditSink{}
+	subject := &AuditSubject{Kind: "AquaScannerAccount", Namespace: "abc123-tools", Name: "scanner", UID: "1234", ReconcileID: "5678", Reason: "RotationRequested"}
+	c := AquaResourceClient{Logger: ctrl.Log, AquaAuth: aa, API: aquaAPIV5{}, Templates: AquaTemplates{Dir: "../templates"}, Audit: sink, Subject: subject}
+
+	user := &UserResource{AquaResourceClient: c, User: User{Name: "ScannerCLI_abc123", Role: Role{Name: "ScannerCLI_abc123"}, Password: "hunter2hunter2"}}
+	if err := user.Create(context.Background()); err != nil {
+		t.Fatalf("Create was not supposed to return an error but got %v", err)
+	}
+	role := &RoleResource{AquaResourceClient: c, Role: Role{Name: "ScannerCLI_abc123"}}
+	if err := role.Delete(context.Background()); err == nil {
+		t.Errorf("Delete was supposed to return an error when aqua answers 500")
+	}
+
+	if len(sink.records) != 2 {
+		t.Fatalf("every change was supposed to be audited but got %+v", sink.records)
+	}
+
+	created := sink.records[0]
+	if created.Method != "POST" || created.Endpoint != "/api/v1/users" || created.AquaURL != server.URL || created.AquaObject != "User" || created.StatusCode != 204 || created.Outcome != AuditSucceeded {
+		t.Errorf("the record of the user created was supposed to describe the request but got %+v", created)
+	}
+	if created.Namespace != "abc123-tools" || created.Name != "scanner" || created.UID != "1234" || created.ReconcileID != "5678" || created.Reason != "RotationRequested" {
+		t.Errorf("the record was supposed to name the account and reconcile but got %+v", created)
+	}
+	if strings.Contains(string(created.Payload), "hunter2") || !strings.Contains(string(created.Payload), `"password":"<redacted>"`) {
+		t.Errorf("the payload was supposed to be redacted but got %s", created.Payload)
+	}
+
+	deleted := sink.records[1]
+	if deleted.Method != "DELETE" || deleted.StatusCode != 500 || deleted.Outcome != AuditFailed || deleted.Payload != nil {
+		t.Errorf("the record of the failed delete was supposed to be Failed without a payload but got %+v", deleted)
+	}
+
+	server.Close()
+	if err := role.Delete(context.Background()); err == nil {
+		t.Errorf("Delete was supposed to return an error when aqua can not be reached")
+	}
+	if unreachable := sink.records[2]; unreachable.Outcome != AuditError || unreachable.Error == "" || unreachable.StatusCode != 0 {
+		t.Errorf("the record of a request that was not answered was supposed to be an Error but got %+v", unreachable)
+	}
+}
+
+func TestFileAuditSinkRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	sink, err := NewFileAuditSink(path, AuditRetention{MaxRecords: 10})
+	if err != nil {
+		t.Fatalf("NewFileAuditSink was not supposed to return an error but got %v", err)
+	}
+
+	for i := 0; i < 25; i++ {
+		sink.Record(AuditRecord{Time: time.Now(), Namespace: "abc123-tools", Name: "scanner", ReconcileID: string(rune('a' + i)), Method: "PUT", Outcome: AuditSucceeded})
+	}
+
+	records, err := sink.Query(AuditQuery{})
+	if err != nil {
+		t.Fatalf("Query was not supposed to return an error but got %v", err)
+	}
+	// pruned back to 10 whenever it holds more than 11
+	if len(records) < 10 || len(records) > 11 || records[len(records)-1].ReconcileID != "y" {
+		t.Errorf("the file was supposed to keep the newest records around MaxRecords but got %v", len(records))
+	}
+
+	// old records are dropped when the file is opened
+	old := AuditRecord{Time: time.Now().Add(-48 * time.Hour), Method: "DELETE", Outcome: AuditSucceeded}
+	line, _ := json.Marshal(old)
+	ioutil.WriteFile(path, append(line, '\n'), 0640)
+
+	sink, _ = NewFileAuditSink(path, AuditRetention{MaxAge: 24 * time.Hour})
+	if records, _ := sink.Query(AuditQuery{}); len(records) != 0 {
+		t.Errorf("records older than MaxAge were supposed to be dropped but got %+v", records)
+	}
+}
+
+func TestHTTPAuditSink(t *testing.T) {
+	received := make(chan AuditRecord, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record := AuditRecord{}
+		json.NewDecoder(r.Body).Decode(&record)
+		received <- record
+	}))
+	defer server.Close()
+
+	if err := NewHTTPAuditSink(server.URL, time.Second).Record(AuditRecord{Method: "PUT", Endpoint: "/api/v1/users/ScannerCLI_abc123"}); err != nil {
+		t.Fatalf("Record was not supposed to return an error but got %v", err)
+	}
+	if record := <-received; record.Endpoint != "/api/v1/users/ScannerCLI_abc123" {
+		t.Errorf("the collector was supposed to receive the record but got %+v", record)
+	}
+}
+
+func TestQueryAuditRecords(t *testing.T) {
+	now := time.Now()
+	trail := ""
+	for _, record := range []AuditRecord{
+		{Time: now.Add(-2 * time.Hour), Namespace: "abc123-tools", Name: "scanner", AquaObject: "User", Method: "POST", Outcome: AuditSucceeded},
+		{Time: now.Add(-time.Hour), Namespace: "abc123-tools", Name: "scanner", AquaObject: "User", Method: "PUT", Outcome: AuditFailed},
+		{Time: now, Namespace: "def456-tools", Name: "scanner", AquaObject: "Role", Method: "DELETE", Outcome: AuditSucceeded},
+	} {
+		line, _ := json.Marshal(record)
+		trail += string(line) + "\n\n"
+	}
+
+	queries := []struct {
+		query    AuditQuery
+		expected int
+	}{
+		{AuditQuery{}, 3},
+		{AuditQuery{Namespace: "abc123-tools"}, 2},
+		{AuditQuery{Namespace: "abc123-tools", Method: "PUT"}, 1},
+		{AuditQuery{Outcome: AuditSucceeded}, 2},
+		{AuditQuery{AquaObject: "Role"}, 1},
+		{AuditQuery{Since: now.Add(-90 * time.Minute)}, 2},
+		{AuditQuery{Until: now.Add(-90 * time.Minute)}, 1},
+	}
+	for _, q := range queries {
+		records, err := QueryAuditRecords(strings.NewReader(trail), q.query)
+		if err != nil || len(records) != q.expected {
+			t.Errorf("QueryAuditRecords was supposed to return %v records for %+v but got %v, %v", q.expected, q.query, len(records), err)
+		}
+	}
+
+	if _, err := QueryAuditRecords(strings.NewReader("not json\n"), AuditQuery{}); err == nil {
+		t.Errorf("QueryAuditRecords was supposed to return an error for a line that is not a record")
+	}
+}
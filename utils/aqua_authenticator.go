@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kataras/jwt"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	PasswordAuthMethod = "password"
+	TokenAuthMethod    = "token"
+	ApiKeyAuthMethod   = "apiKey"
+)
+
+// endpoint used to check that a token or api key is accepted by aqua
+const aquaIdentityPath = "/api/v1/users/me"
+
+// the credentials the operator uses to authenticate with aqua, Method decides which of the other fields are used
+type AquaCredentials struct {
+	Method    string
+	User      string
+	Password  string
+	Token     string
+	ApiKey    string
+	ApiSecret string
+}
+
+// Authenticator adds the operator's credentials to the requests it makes to aqua
+type Authenticator interface {
+	// sets the headers that authenticate the request with aqua
+	Authenticate(aa *AquaAuth, req *http.Request) error
+	// checks that aqua accepts the credentials, returning the bearer token when the method uses one
+	Verify(aa *AquaAuth) (string, error)
+}
+
+// returns the authenticator for the method in the credentials. An empty method means password login
+func NewAuthenticator(creds AquaCredentials) (Authenticator, error) {
+	switch creds.Method {
+	case "", PasswordAuthMethod:
+		return &PasswordAuthenticator{User: creds.User, Password: creds.Password}, nil
+	case TokenAuthMethod:
+		if creds.Token == "" {
+			return nil, errors.NewBadRequest("Error: AQUA_TOKEN is required for the token auth method")
+		}
+		return &TokenAuthenticator{Token: creds.Token}, nil
+	case ApiKeyAuthMethod:
+		if creds.ApiKey == "" || creds.ApiSecret == "" {
+			return nil, errors.NewBadRequest("Error: AQUA_API_KEY and AQUA_API_SECRET are required for the apiKey auth method")
+		}
+		return &ApiKeyAuthenticator{Key: creds.ApiKey, Secret: creds.ApiSecret}, nil
+	}
+	return nil, errors.NewBadRequest("Error: unsupported aqua auth method " + creds.Method + ", expected one of password, token or apiKey")
+}
+
+// reads aqua credentials from secret data using the same keys as the operator's env
+func AquaCredentialsFromSecretData(data map[string][]byte) AquaCredentials {
+	return AquaCredentials{
+		Method:    string(data["AQUA_AUTH_METHOD"]),
+		User:      string(data["AQUA_USER"]),
+		Password:  string(data["AQUA_PASSWORD"]),
+		Token:     string(data["AQUA_TOKEN"]),
+		ApiKey:    string(data["AQUA_API_KEY"]),
+		ApiSecret: string(data["AQUA_API_SECRET"]),
+	}
+}
+
+func AquaCredentialsFromEnv() AquaCredentials {
+	return AquaCredentials{
+		Method:    os.Getenv("AQUA_AUTH_METHOD"),
+		User:      os.Getenv("AQUA_USER"),
+		Password:  os.Getenv("AQUA_PASSWORD"),
+		Token:     os.Getenv("AQUA_TOKEN"),
+		ApiKey:    os.Getenv("AQUA_API_KEY"),
+		ApiSecret: os.Getenv("AQUA_API_SECRET"),
+	}
+}
+
+// logs in with a username and password through /api/v1/login and caches the returned jwt until it expires
+type PasswordAuthenticator struct {
+	User     string
+	Password string
+	jwt      string
+	exp      int64
+	mu       sync.Mutex
+}
+
+func (pa *PasswordAuthenticator) Authenticate(aa *AquaAuth, req *http.Request) error {
+	// a login made for the request is part of the request's trace
+	jwt, err := pa.verify(req.Context(), aa)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return nil
+}
+
+func (pa *PasswordAuthenticator) Verify(aa *AquaAuth) (string, error) {
+	return pa.verify(context.Background(), aa)
+}
+
+func (pa *PasswordAuthenticator) verify(ctx context.Context, aa *AquaAuth) (string, error) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	now := time.Now().Unix()
+
+	if pa.exp == 0 || now > pa.exp {
+		err := pa.login(ctx, aa)
+
+		if err != nil {
+			pa.jwt = ""
+		}
+
+		return pa.jwt, err
+	}
+
+	return pa.jwt, nil
+}
+
+func (pa *PasswordAuthenticator) login(ctx context.Context, aa *AquaAuth) error {
+	reqBody := LoginReqBody{Id: pa.User, Password: pa.Password}
+	buffer, _ := json.Marshal(reqBody)
+	reqUrl := aa.GetUrl() + "/api/v1/login"
+	client := aa.HttpClient()
+	req, _ := http.NewRequestWithContext(ctx, "POST", reqUrl, bytes.NewBuffer(buffer))
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	defer res.Body.Close()
+
+	var jsonData LoginRes
+	body, jsonErr := ioutil.ReadAll(res.Body)
+
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	json.Unmarshal(body, &jsonData)
+
+	if res.StatusCode == 200 {
+		pa.jwt = jsonData.Token
+
+		exp := JwtPayload{}
+		token, decodeErr := jwt.Decode([]byte(jsonData.Token))
+
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		json.Unmarshal(token.Payload, &exp)
+		pa.exp = exp.Exp
+
+		return nil
+	} else {
+		// failure operator needs to quit
+		e := fmt.Errorf("failed to login to Aqua, returned status code was %v", res.StatusCode)
+		return e
+	}
+}
+
+// sends a static bearer token, for example a token issued to a service account in aqua
+type TokenAuthenticator struct {
+	Token string
+}
+
+func (ta *TokenAuthenticator) Authenticate(aa *AquaAuth, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+ta.Token)
+	return nil
+}
+
+func (ta *TokenAuthenticator) Verify(aa *AquaAuth) (string, error) {
+	return ta.Token, verifyIdentity(aa, ta)
+}
+
+/*
+	Signs every request with an aqua api key. The signature is the hex encoded HMAC-SHA256, keyed with the api secret,
+	of the unix timestamp, the http method, the request path and the request body concatenated together.
+*/
+type ApiKeyAuthenticator struct {
+	Key    string
+	Secret string
+	// allows tests to pin the timestamp, defaults to time.Now
+	Now func() time.Time
+}
+
+func (ka *ApiKeyAuthenticator) Authenticate(aa *AquaAuth, req *http.Request) error {
+	var body []byte
+
+	if req.GetBody != nil {
+		bodyReader, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		body, err = ioutil.ReadAll(bodyReader)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now
+	if ka.Now != nil {
+		now = ka.Now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+
+	req.Header.Set("X-API-Key", ka.Key)
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", ka.Sign(timestamp, req.Method, req.URL.Path, body))
+	return nil
+}
+
+// returns the signature for a request made at timestamp
+func (ka *ApiKeyAuthenticator) Sign(timestamp string, method string, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(ka.Secret))
+	mac.Write([]byte(timestamp + method + path))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ka *ApiKeyAuthenticator) Verify(aa *AquaAuth) (string, error) {
+	return "", verifyIdentity(aa, ka)
+}
+
+// checks the credentials by asking aqua who they belong to
+func verifyIdentity(aa *AquaAuth, authenticator Authenticator) error {
+	req, err := http.NewRequest("GET", aa.GetUrl()+aquaIdentityPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if err := authenticator.Authenticate(aa, req); err != nil {
+		return err
+	}
+
+	res, err := aa.HttpClient().Do(req)
+	if err != nil {
+		return errors.NewInternalError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return fmt.Errorf("failed to authenticate with Aqua, returned status code was %v", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	if a, err := NewAuthenticator(AquaCredentials{User: "admin", Password: "password"}); err != nil {
+		t.Errorf("NewAuthenticator was supposed to default to password login but got %v", err)
+	} else if _, ok := a.(*PasswordAuthenticator); !ok {
+		t.Errorf("NewAuthenticator was supposed to return a PasswordAuthenticator but got %T", a)
+	}
+
+	if _, err := NewAuthenticator(AquaCredentials{Method: TokenAuthMethod}); err == nil {
+		t.Errorf("NewAuthenticator was supposed to return an error for the token method without a token")
+	}
+
+	if _, err := NewAuthenticator(AquaCredentials{Method: ApiKeyAuthMethod, ApiKey: "key"}); err == nil {
+		t.Errorf("NewAuthenticator was supposed to return an error for the apiKey method without a secret")
+	}
+
+	if _, err := NewAuthenticator(AquaCredentials{Method: "kerberos"}); err == nil {
+		t.Errorf("NewAuthenticator was supposed to return an error for an unsupported method")
+	}
+}
+
+func TestApiKeyAuthenticatorSignature(t *testing.T) {
+	ka := &ApiKeyAuthenticator{
+		Key:    "aqua-key",
+		Secret: "aqua-secret",
+		Now:    func() time.Time { return time.Unix(1700000000, 0) },
+	}
+
+	body := []byte(`{"name":"ScannerCLI_abc123"}`)
+	req, _ := http.NewRequest("POST", "https://aqua.lab/api/v2/access_management/roles", bytes.NewBuffer(body))
+
+	if err := ka.Authenticate(&AquaAuth{}, req); err != nil {
+		t.Fatalf("Authenticate was not supposed to return an error but got %v", err)
+	}
+
+	expected := "ed5e6cb843d51d688c39485e4dd500b403772b7d91386ba098ee82dc17a86cc3"
+
+	if req.Header.Get("X-Signature") != expected {
+		t.Errorf("Authenticate was supposed to sign the request with %v but got %v", expected, req.Header.Get("X-Signature"))
+	}
+
+	if req.Header.Get("X-API-Key") != "aqua-key" || req.Header.Get("X-Timestamp") != "1700000000" {
+		t.Errorf("Authenticate was supposed to set the api key and timestamp headers but got %v", req.Header)
+	}
+
+	if req.Header.Get("Authorization") != "" {
+		t.Errorf("Authenticate was not supposed to send a bearer token with api key auth")
+	}
+}
+
+func TestTokenAuthenticatorVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != aquaIdentityPath || r.Header.Get("Authorization") != "Bearer good-token" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "good-token"}, nil)
+
+	if _, err := aa.GetJWT(); err != nil {
+		t.Errorf("GetJWT was supposed to pass for a token aqua accepts but got %v", err)
+	}
+
+	changed, _ := aa.SetCredentials(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "bad-token"})
+
+	if !changed {
+		t.Errorf("SetCredentials was supposed to report that the credentials changed")
+	}
+
+	if _, err := aa.GetJWT(); err == nil {
+		t.Errorf("GetJWT was supposed to fail for a token aqua rejects")
+	}
+}
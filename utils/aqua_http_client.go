@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	defaultAquaConnectTimeout = 10 * time.Second
+	defaultAquaReadTimeout    = 30 * time.Second
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// transport settings for the http client used to talk to an aqua instance
+type AquaHttpConfig struct {
+	// PEM encoded CA bundle trusted in addition to the system trust store
+	CABundle []byte
+	// PEM encoded client certificate and key presented to aqua for mTLS
+	ClientCert         []byte
+	ClientKey          []byte
+	InsecureSkipVerify bool
+	// one of 1.0, 1.1, 1.2 or 1.3. Defaults to 1.2
+	MinTLSVersion string
+	// proxy used for aqua requests. When empty the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars are used
+	ProxyUrl string
+	NoProxy  string
+	// time allowed to establish the connection including the TLS handshake
+	ConnectTimeout time.Duration
+	// time allowed for a whole request once the connection is established
+	ReadTimeout time.Duration
+}
+
+/*
+	Builds the http client for an aqua instance. A zero AquaHttpConfig gives a client that trusts the system
+	trust store, honours the proxy env vars and uses the default timeouts.
+*/
+func NewAquaHttpClient(config AquaHttpConfig) (*http.Client, error) {
+	tlsConfig, err := newAquaTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := newAquaProxyFunc(config)
+	if err != nil {
+		return nil, err
+	}
+
+	connectTimeout := config.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = defaultAquaConnectTimeout
+	}
+
+	readTimeout := config.ReadTimeout
+	if readTimeout == 0 {
+		readTimeout = defaultAquaReadTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	return &http.Client{Transport: NewTracingTransport(transport), Timeout: connectTimeout + readTimeout}, nil
+}
+
+func newAquaTLSConfig(config AquaHttpConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify, MinVersion: tls.VersionTLS12}
+
+	if config.MinTLSVersion != "" {
+		version, ok := tlsVersions[config.MinTLSVersion]
+		if !ok {
+			return nil, errors.NewBadRequest("Error: unsupported minimum TLS version " + config.MinTLSVersion + ", expected one of 1.0, 1.1, 1.2 or 1.3")
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(config.CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(config.CABundle) {
+			return nil, errors.NewBadRequest("Error: CA bundle for aqua instance does not contain any PEM encoded certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(config.ClientCert) > 0 || len(config.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, errors.NewBadRequest("Error: could not load the client certificate for aqua instance: " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func newAquaProxyFunc(config AquaHttpConfig) (func(*http.Request) (*url.URL, error), error) {
+	if config.ProxyUrl == "" {
+		if config.NoProxy == "" {
+			return http.ProxyFromEnvironment, nil
+		}
+		// only NO_PROXY is overridden, the proxies themselves still come from the env
+		proxyConfig := httpproxy.FromEnvironment()
+		proxyConfig.NoProxy = config.NoProxy
+		return proxyFuncFromConfig(proxyConfig), nil
+	}
+
+	if _, err := url.Parse(config.ProxyUrl); err != nil {
+		return nil, errors.NewBadRequest("Error: invalid proxy url for aqua instance: " + err.Error())
+	}
+
+	return proxyFuncFromConfig(&httpproxy.Config{HTTPProxy: config.ProxyUrl, HTTPSProxy: config.ProxyUrl, NoProxy: config.NoProxy}), nil
+}
+
+func proxyFuncFromConfig(proxyConfig *httpproxy.Config) func(*http.Request) (*url.URL, error) {
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+/*
+	Reads the http settings for the operator's own aqua instance from the env. The CA bundle and client certificate
+	are expected to be mounted into the manager pod as files.
+		AQUA_CA_BUNDLE_FILE, AQUA_CLIENT_CERT_FILE, AQUA_CLIENT_KEY_FILE, AQUA_TLS_MIN_VERSION,
+		AQUA_INSECURE_SKIP_VERIFY, AQUA_PROXY_URL, AQUA_NO_PROXY, AQUA_CONNECT_TIMEOUT, AQUA_READ_TIMEOUT
+*/
+func AquaHttpConfigFromEnv() (AquaHttpConfig, error) {
+	config := AquaHttpConfig{
+		MinTLSVersion:      os.Getenv("AQUA_TLS_MIN_VERSION"),
+		InsecureSkipVerify: os.Getenv("AQUA_INSECURE_SKIP_VERIFY") == "true",
+		ProxyUrl:           os.Getenv("AQUA_PROXY_URL"),
+		NoProxy:            os.Getenv("AQUA_NO_PROXY"),
+	}
+
+	if err := config.LoadFiles(os.Getenv("AQUA_CA_BUNDLE_FILE"), os.Getenv("AQUA_CLIENT_CERT_FILE"), os.Getenv("AQUA_CLIENT_KEY_FILE")); err != nil {
+		return config, err
+	}
+
+	durations := map[string]*time.Duration{
+		"AQUA_CONNECT_TIMEOUT": &config.ConnectTimeout,
+		"AQUA_READ_TIMEOUT":    &config.ReadTimeout,
+	}
+
+	for envVar, dst := range durations {
+		value := os.Getenv(envVar)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, errors.NewBadRequest("Error: " + envVar + " is not a valid duration: " + err.Error())
+		}
+		*dst = d
+	}
+
+	return config, nil
+}
+
+// reads the PEM encoded CA bundle, client certificate and client key from files, empty paths are skipped
+func (config *AquaHttpConfig) LoadFiles(caBundleFile string, clientCertFile string, clientKeyFile string) error {
+	// the certificate and key are allowed to be in the same file so the paths are not used as map keys
+	files := []struct {
+		path string
+		dst  *[]byte
+	}{
+		{caBundleFile, &config.CABundle},
+		{clientCertFile, &config.ClientCert},
+		{clientKeyFile, &config.ClientKey},
+	}
+
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+		*file.dst = b
+	}
+	return nil
+}
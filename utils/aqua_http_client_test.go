@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// a token with an exp claim that kataras/jwt is able to decode
+func fakeJwt(exp int64) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(JwtPayload{Exp: exp})
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func fakeLoginHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginRes{Token: fakeJwt(time.Now().Add(time.Hour).Unix())})
+}
+
+func serverCABundle(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+// generates a self signed certificate that is used as both the client certificate and its CA
+func selfSignedClientCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client key %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "aqua-scanner-operator"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create client certificate %v", err)
+	}
+
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func loginWith(t *testing.T, url string, config AquaHttpConfig) error {
+	client, err := NewAquaHttpClient(config)
+	if err != nil {
+		t.Fatalf("NewAquaHttpClient was not supposed to return an error but got %v", err)
+	}
+	aa, _ := NewAquaAuth(url, AquaCredentials{User: "admin", Password: "password"}, client)
+	_, err = aa.GetJWT()
+	return err
+}
+
+func TestAquaHttpClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(fakeLoginHandler))
+	defer server.Close()
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{}); err == nil {
+		t.Errorf("Login was supposed to fail when the aqua certificate is not trusted")
+	}
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{CABundle: serverCABundle(server)}); err != nil {
+		t.Errorf("Login was supposed to pass when the aqua CA bundle is configured but got %v", err)
+	}
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("Login was supposed to pass when verification is skipped but got %v", err)
+	}
+}
+
+func TestAquaHttpClientMutualTLS(t *testing.T) {
+	clientCert, clientKey := selfSignedClientCert(t)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(fakeLoginHandler))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{CABundle: serverCABundle(server)}); err == nil {
+		t.Errorf("Login was supposed to fail when aqua requires a client certificate and none is configured")
+	}
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{CABundle: serverCABundle(server), ClientCert: clientCert, ClientKey: clientKey}); err != nil {
+		t.Errorf("Login was supposed to pass with the client certificate configured but got %v", err)
+	}
+
+	if _, err := NewAquaHttpClient(AquaHttpConfig{ClientCert: clientCert}); err == nil {
+		t.Errorf("NewAquaHttpClient was supposed to return an error for a client certificate without a key")
+	}
+}
+
+func TestAquaHttpClientMinTLSVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(fakeLoginHandler))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{CABundle: serverCABundle(server), MinTLSVersion: "1.2"}); err != nil {
+		t.Errorf("Login was supposed to pass with a minimum TLS version of 1.2 but got %v", err)
+	}
+
+	if err := loginWith(t, server.URL, AquaHttpConfig{CABundle: serverCABundle(server), MinTLSVersion: "1.3"}); err == nil {
+		t.Errorf("Login was supposed to fail when aqua only supports TLS 1.2 and the minimum is 1.3")
+	}
+
+	if _, err := NewAquaHttpClient(AquaHttpConfig{MinTLSVersion: "2.0"}); err == nil {
+		t.Errorf("NewAquaHttpClient was supposed to return an error for an unsupported TLS version")
+	}
+}
+
+func TestAquaHttpClientTimeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		fakeLoginHandler(w, r)
+	}))
+	defer server.Close()
+
+	config := AquaHttpConfig{CABundle: serverCABundle(server), ConnectTimeout: 100 * time.Millisecond, ReadTimeout: 100 * time.Millisecond}
+
+	if err := loginWith(t, server.URL, config); err == nil {
+		t.Errorf("Login was supposed to fail when aqua takes longer than the read timeout to answer")
+	}
+}
+
+func TestAquaHttpClientProxy(t *testing.T) {
+	client, err := NewAquaHttpClient(AquaHttpConfig{ProxyUrl: "http://proxy.internal:3128", NoProxy: "aqua.internal"})
+	if err != nil {
+		t.Fatalf("NewAquaHttpClient was not supposed to return an error but got %v", err)
+	}
+
+	proxy := client.Transport.(*tracingTransport).base.(*http.Transport).Proxy
+
+	req, _ := http.NewRequest("GET", "https://aqua.apps.clab.devops.gov.bc.ca/api/v1/login", nil)
+	proxyUrl, _ := proxy(req)
+	if proxyUrl == nil || proxyUrl.Host != "proxy.internal:3128" {
+		t.Errorf("requests to aqua were supposed to go through proxy.internal:3128 but got %v", proxyUrl)
+	}
+
+	req, _ = http.NewRequest("GET", "https://aqua.internal/api/v1/login", nil)
+	proxyUrl, _ = proxy(req)
+	if proxyUrl != nil {
+		t.Errorf("requests to a NO_PROXY host were supposed to bypass the proxy but got %v", proxyUrl)
+	}
+}
+
+func TestGetAquaAuthRejectsAnUnusableCABundle(t *testing.T) {
+	lock.Lock()
+	previous := aquaAuth
+	aquaAuth = nil
+	lock.Unlock()
+	os.Setenv("AQUA_CA_BUNDLE_FILE", filepath.Join(t.TempDir(), "missing.crt"))
+	defer func() {
+		os.Unsetenv("AQUA_CA_BUNDLE_FILE")
+		lock.Lock()
+		aquaAuth = previous
+		lock.Unlock()
+	}()
+
+	if aa, err := GetAquaAuth(); err == nil || aa != nil {
+		t.Errorf("a CA bundle that can not be read was supposed to be an error instead of a client without it but got %v, %v", aa, err)
+	}
+}
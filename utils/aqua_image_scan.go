@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils/scanreport"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// label on the Jobs and pods of an AquaImageScan holding the name of the scan
+const ImageScanLabel = "mamoa.devops.gov.bc.ca/aqua-image-scan"
+
+/*
+	Runs scannercli with the arguments of the container and prints its json report between the scanreport markers
+	once it finished so the operator can read the report from the log of the pod. The credentials come from the
+	environment and the image and registry are passed as arguments so none of them end up in the script.
+*/
+const scanScript = `/opt/aquasec/scannercli scan --host "$AQUA_URL" --user "$SCANNER_USER" --password "$SCANNER_PASSWORD" --jsonfile /tmp/report.json "$@"
+status=$?
+if [ -s /tmp/report.json ]; then
+  echo "` + scanreport.BeginMarker + `"
+  cat /tmp/report.json
+  echo
+  echo "` + scanreport.EndMarker + `"
+fi
+exit $status`
+
+// the container running scannercli in scan Jobs
+const ImageScanContainer = "scannercli"
+
+// how many of the most severe vulnerabilities are kept in the status of an AquaImageScan
+const imageScanTopCVEs = 5
+
+// what the Job of an AquaImageScan needs besides the scan itself
+type ImageScanJobOptions struct {
+	ScannerImage     string
+	ImagePullSecrets []string
+	// the Secret of the AquaScannerAccount with the aqua url and scanner credentials
+	CredentialsSecret string
+	// the Job is failed when scannercli runs longer
+	Timeout time.Duration
+}
+
+/*
+	Returns the name of the Job of the scan. Job names end up in a pod label so they are kept to 63 characters, long
+	scan names are shortened and made unique with a hash of the full name.
+*/
+func ImageScanJobName(scan *asa.AquaImageScan) string {
+	name := scan.Name + "-scan"
+	if len(name) <= 63 {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(scan.Name))
+	return strings.TrimRight(scan.Name[:52], "-.") + "-" + hex.EncodeToString(sum[:])[:10]
+}
+
+// builds the Job that runs scannercli against the aqua url of the scanner account with its credentials
+func NewImageScanJob(scan *asa.AquaImageScan, options ImageScanJobOptions) *batchv1.Job {
+	// a scan that fails because the image did not pass would fail again, it is not retried
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(options.Timeout.Seconds())
+	allowPrivilegeEscalation := false
+
+	// the first argument after the script is $0
+	args := []string{"aqua-image-scan"}
+	if scan.Spec.Registry != "" {
+		args = append(args, "--registry", scan.Spec.Registry)
+	}
+	args = append(args, scan.Spec.Image)
+
+	env := []corev1.EnvVar{}
+	for _, key := range []string{asav2.CredentialsSecretURLKey, asav2.CredentialsSecretUserKey, asav2.CredentialsSecretPasswordKey} {
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: options.CredentialsSecret},
+				Key:                  key,
+			}},
+		})
+	}
+
+	pullSecrets := []corev1.LocalObjectReference{}
+	for _, pullSecret := range options.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: pullSecret})
+	}
+
+	labels := map[string]string{ImageScanLabel: scan.Name}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: ImageScanJobName(scan), Namespace: scan.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: pullSecrets,
+					Containers: []corev1.Container{{
+						Name:    ImageScanContainer,
+						Image:   options.ScannerImage,
+						Command: []string{"/bin/sh", "-c", scanScript},
+						Args:    args,
+						Env:     env,
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// returns the phase of the AquaImageScan and a message for the state of its Job
+func ImageScanPhase(job *batchv1.Job) (string, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch {
+		case condition.Type == batchv1.JobComplete:
+			return asa.ImageScanSucceeded, "scannercli passed the image"
+		case condition.Type == batchv1.JobFailed && condition.Reason == "DeadlineExceeded":
+			return asa.ImageScanFailed, "The scan timed out: " + condition.Message
+		case condition.Type == batchv1.JobFailed:
+			return asa.ImageScanFailed, "scannercli failed, the image did not pass or could not be scanned. See the logs of Job " + job.Name
+		}
+	}
+
+	if job.Status.Active > 0 {
+		return asa.ImageScanRunning, "scannercli is scanning the image"
+	}
+	return asa.ImageScanPending, "Waiting for the scan Job to start"
+}
+
+// returns the summary of a scannercli report kept in the status of an AquaImageScan
+func ImageScanReportSummary(report *scanreport.Report) *asa.AquaImageScanReport {
+	summary := &asa.AquaImageScanReport{
+		Digest:         report.Digest,
+		Policy:         asa.ImageScanPolicyPass,
+		FailedPolicies: report.FailedPolicies,
+		Vulnerabilities: asa.AquaImageScanSeverityCounts{
+			Critical:   report.Counts.Critical,
+			High:       report.Counts.High,
+			Medium:     report.Counts.Medium,
+			Low:        report.Counts.Low,
+			Negligible: report.Counts.Negligible,
+		},
+	}
+	if !report.PolicyPassed {
+		summary.Policy = asa.ImageScanPolicyFail
+	}
+
+	for _, v := range report.Top(imageScanTopCVEs) {
+		cve := asa.AquaImageScanCVE{Name: v.Name, Severity: v.Severity, Resource: v.Resource, FixVersion: v.FixVersion}
+		if v.Score != 0 {
+			cve.Score = strconv.FormatFloat(v.Score, 'f', -1, 64)
+		}
+		summary.TopCVEs = append(summary.TopCVEs, cve)
+	}
+	return summary
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	"github.com/bcgov-platform-services/aqua-scan-cli-operator/utils/scanreport"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewImageScanJob(t *testing.T) {
+	scan := &asa.AquaImageScan{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "abc123-tools"},
+		Spec:       asa.AquaImageScanSpec{Image: "quay.io/abc123/app:1.0", Registry: "Quay"},
+	}
+
+	job := NewImageScanJob(scan, ImageScanJobOptions{
+		ScannerImage:      "registry.aquasec.com/scanner:2022.4",
+		ImagePullSecrets:  []string{"aqua-registry"},
+		CredentialsSecret: "scanner-aqua-credentials",
+		Timeout:           10 * time.Minute,
+	})
+
+	if job.Name != "app-scan" || job.Namespace != "abc123-tools" {
+		t.Errorf("the Job was supposed to be called app-scan in the scan's namespace but got %v/%v", job.Namespace, job.Name)
+	}
+
+	if *job.Spec.ActiveDeadlineSeconds != 600 || *job.Spec.BackoffLimit != 0 {
+		t.Errorf("the Job was supposed to time out after 600s without retries but got %v and %v", *job.Spec.ActiveDeadlineSeconds, *job.Spec.BackoffLimit)
+	}
+
+	container := job.Spec.Template.Spec.Containers[0]
+	args := strings.Join(container.Args, " ")
+	if args != "aqua-image-scan --registry Quay quay.io/abc123/app:1.0" {
+		t.Errorf("scannercli was called with the wrong arguments %v", args)
+	}
+	if script := container.Command[len(container.Command)-1]; !strings.Contains(script, "--jsonfile") || strings.Contains(script, "quay.io") {
+		t.Errorf("the script was supposed to write the json report without the image in it but got %v", script)
+	}
+
+	for _, env := range container.Env {
+		if env.ValueFrom.SecretKeyRef.Name != "scanner-aqua-credentials" || env.ValueFrom.SecretKeyRef.Key != env.Name {
+			t.Errorf("%v was supposed to come from the credentials secret but got %+v", env.Name, env.ValueFrom.SecretKeyRef)
+		}
+	}
+
+	if job.Spec.Template.Spec.ImagePullSecrets[0].Name != "aqua-registry" || job.Spec.Template.Labels[ImageScanLabel] != "app" {
+		t.Errorf("the pod was supposed to use the pull secret and be labelled with the scan but got %+v", job.Spec.Template)
+	}
+
+	scan.Name = strings.Repeat("a", 70)
+	if name := ImageScanJobName(scan); len(name) != 63 || !strings.HasPrefix(name, strings.Repeat("a", 52)+"-") {
+		t.Errorf("long scan names were supposed to be shortened to 63 characters but got %v", name)
+	}
+}
+
+func TestImageScanPhase(t *testing.T) {
+	for _, test := range []struct {
+		status batchv1.JobStatus
+		phase  string
+	}{
+		{status: batchv1.JobStatus{}, phase: asa.ImageScanPending},
+		{status: batchv1.JobStatus{Active: 1}, phase: asa.ImageScanRunning},
+		{status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}}, phase: asa.ImageScanSucceeded},
+		{status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}}, phase: asa.ImageScanFailed},
+		{status: batchv1.JobStatus{Active: 1, Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded"}}}, phase: asa.ImageScanFailed},
+	} {
+		phase, message := ImageScanPhase(&batchv1.Job{Status: test.status})
+		if phase != test.phase {
+			t.Errorf("a Job with status %+v was supposed to be %v but got %v (%v)", test.status, test.phase, phase, message)
+		}
+	}
+
+	_, message := ImageScanPhase(&batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded"}}}})
+	if !strings.Contains(message, "timed out") {
+		t.Errorf("a Job past its deadline was supposed to be reported as timed out but got %v", message)
+	}
+}
+
+func TestImageScanReportSummary(t *testing.T) {
+	report := &scanreport.Report{
+		Digest:         "sha256:abc",
+		FailedPolicies: []string{"Default: max_severity"},
+		Counts:         scanreport.SeverityCounts{Critical: 1, High: 6},
+	}
+	for i := 0; i < 7; i++ {
+		report.Vulnerabilities = append(report.Vulnerabilities, scanreport.Vulnerability{Name: fmt.Sprintf("CVE-2021-%v", i), Severity: scanreport.High, Score: 7.5})
+	}
+
+	summary := ImageScanReportSummary(report)
+
+	if summary.Policy != asa.ImageScanPolicyFail || summary.Digest != "sha256:abc" || summary.Vulnerabilities.Critical != 1 || summary.Vulnerabilities.High != 6 {
+		t.Errorf("the summary was supposed to fail the policy and keep the digest and counts but got %+v", summary)
+	}
+	if len(summary.TopCVEs) != imageScanTopCVEs || summary.TopCVEs[0].Score != "7.5" {
+		t.Errorf("the summary was supposed to keep the %v most severe CVEs but got %+v", imageScanTopCVEs, summary.TopCVEs)
+	}
+
+	report.PolicyPassed = true
+	if summary := ImageScanReportSummary(report); summary.Policy != asa.ImageScanPolicyPass {
+		t.Errorf("the summary was supposed to pass the policy but got %v", summary.Policy)
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// connection details for a single aqua console, built by the AquaInstance controller from the AquaInstance spec
+type AquaInstanceConfig struct {
+	Url         string
+	Credentials AquaCredentials
+	Http        AquaHttpConfig
+}
+
+type aquaInstanceEntry struct {
+	config AquaInstanceConfig
+	auth   *AquaAuth
+}
+
+var instanceLock = &sync.Mutex{}
+var aquaInstances = map[string]*aquaInstanceEntry{}
+
+/*
+	Registers the connection details for an AquaInstance and returns its AquaAuth. Every instance keeps its own
+	jwt cache, which is only thrown away when the connection details change.
+*/
+func SetAquaInstanceAuth(name string, config AquaInstanceConfig) (*AquaAuth, error) {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	if entry, ok := aquaInstances[name]; ok && reflect.DeepEqual(entry.config, config) {
+		return entry.auth, nil
+	}
+
+	client, err := NewAquaHttpClient(config.Http)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := NewAquaAuth(config.Url, config.Credentials, client)
+	if err != nil {
+		return nil, err
+	}
+
+	aquaInstances[name] = &aquaInstanceEntry{config: config, auth: auth}
+	return auth, nil
+}
+
+// returns the AquaAuth registered for an AquaInstance, the boolean is false if the instance has not been registered yet
+func GetAquaInstanceAuth(name string) (*AquaAuth, bool) {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	entry, ok := aquaInstances[name]
+	if !ok {
+		return nil, false
+	}
+	return entry.auth, true
+}
+
+func RemoveAquaInstanceAuth(name string) {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	delete(aquaInstances, name)
+}
+
+/*
+	Returns true when the AquaInstance called instanceName allows AquaScannerAccounts in namespace. The namespace is
+	only read when the instance has a spec.namespaceSelector. A missing instance is returned as a NotFound error.
+*/
+func AquaInstanceAllowsNamespace(ctx context.Context, reader client.Reader, instanceName string, namespace string) (bool, error) {
+	aquaInstance := &asa.AquaInstance{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: instanceName}, aquaInstance); err != nil {
+		return false, err
+	}
+	if aquaInstance.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+	return aquaInstance.AllowsNamespace(ns.GetLabels())
+}
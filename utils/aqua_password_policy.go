@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+)
+
+// the password policy of an aqua instance, the zero value does not restrict passwords
+type AquaPasswordPolicy struct {
+	MinLength                int  `json:"min_length"`
+	MaxLength                int  `json:"max_length"`
+	RequireLowercase         bool `json:"require_lowercase"`
+	RequireUppercase         bool `json:"require_uppercase"`
+	RequireDigits            bool `json:"require_digits"`
+	RequireSpecialCharacters bool `json:"require_special_characters"`
+}
+
+/*
+	Returns the password policy of aqua and true, or false when it can not be read, in which case the operator's own
+	policy is used as it is. Aqua versions that do not expose a policy answer 404, any other failure, such as a 403 when
+	the operator's user may not read the settings, is logged so that passwords can still be generated.
+*/
+func GetAquaPasswordPolicy(ctx context.Context, reqLogger logr.Logger, aquaAuth *AquaAuth, api AquaAPI) (AquaPasswordPolicy, bool) {
+	policy := AquaPasswordPolicy{}
+
+	body, found, err := getAquaObject(ctx, reqLogger, aquaAuth, api, "PasswordPolicy", api.PasswordPolicyPath())
+	if err != nil {
+		reqLogger.Error(err, "Failed to read the password policy of aqua, the configured policy is used")
+		return policy, false
+	}
+	if !found {
+		return policy, false
+	}
+
+	if err := json.Unmarshal(body, &policy); err != nil {
+		reqLogger.Error(err, "Failed to read the password policy of aqua, the configured policy is used")
+		return AquaPasswordPolicy{}, false
+	}
+	return policy, true
+}
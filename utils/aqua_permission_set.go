@@ -1,128 +1,120 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"html/template"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type PermissionSet struct {
 	Name               string
 	Description        string
 	TechnicalLeadEmail string
+	// the aqua actions allowed, see PermissionSetActions
+	Actions  []string
+	UIAccess bool
 }
 
-func DeleteAquaPermissionSet(reqLogger *log.DelegatingLogger, permissionSet string) error {
-	reqLogger.Info("Deleting permissionSet %v in aqua", "permissionSet", permissionSet)
-
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
+// the actions of the Scanner profile, scanning plus reading and acknowledging results in the aqua console
+var scannerActions = []string{
+	"image_assurance.read",
+	"image_profiles.read",
+	"dashboard.read",
+	"images.read",
+	"images.write",
+	"containers.read",
+	"risks.vulnerabilities.read",
+	"risks.vulnerabilities.write",
+	"scan.read",
+}
 
-	reqPayload, jsonErr := json.Marshal([]string{permissionSet})
+// the actions of the ScanOnly profile, what scannercli needs to scan and report and nothing else
+var scanOnlyActions = []string{
+	"image_assurance.read",
+	"images.read",
+	"images.write",
+	"risks.vulnerabilities.read",
+	"scan.read",
+}
 
-	if jsonErr != nil {
-		reqLogger.Error(jsonErr, "Failed to marshal json %v", []string{permissionSet})
-		return jsonErr
+// returns the actions of a profile of AquaScannerAccounts and whether it can log in to the aqua console
+func PermissionSetActions(profile string) ([]string, bool) {
+	if profile == asav2.ProfileScanOnly {
+		return scanOnlyActions, false
 	}
+	return scannerActions, true
+}
 
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v2/access_management/permissions/" + permissionSet
-	client := &http.Client{}
-
-	req, _ := http.NewRequest("DELETE", reqUrl, bytes.NewBuffer(reqPayload))
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+func DeleteAquaPermissionSet(ctx context.Context, c AquaResourceClient, permissionSet string) error {
+	c.Logger.Info("Deleting permissionSet in aqua", "permissionSet", permissionSet)
 
-	res, err := client.Do(req)
+	statusCode, ok, err := c.deleteAquaObject(ctx, "PermissionSet", permissionSet)
 
 	if err != nil {
-		reqLogger.Error(err, "Failed request to DELETE to /api/v2/access_management/permissions/"+permissionSet+" in aqua")
 		return err
 	}
 
-	if res.StatusCode != 204 && res.StatusCode != 404 {
-		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not delete Permission Set, the response status from aqua was %v", res.StatusCode))
+	if !ok {
+		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not delete Permission Set, the response status from aqua was %v", statusCode))
 		return e
 	}
 	return nil
 }
 
-func CreateAquaPermissionSet(reqLogger *log.DelegatingLogger, permissionSet PermissionSet) error {
-	wd, _ := os.Getwd()
-
-	reqLogger.Info("Creating permissionSet %v in aqua", "Name", permissionSet.Name)
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
+func CreateAquaPermissionSet(ctx context.Context, c AquaResourceClient, permissionSet PermissionSet) error {
+	c.Logger.Info("Creating permissionSet in aqua", "permissionSet", permissionSet.Name)
 
-	path := filepath.Join(wd, "./templates/PermissionSet.json.tmpl")
+	payload, renderErr := renderAquaTemplate(c.Logger, c.Templates, "PermissionSet", permissionSet)
 
-	b, fileErr := ioutil.ReadFile(path)
-
-	if fileErr != nil {
-		reqLogger.Error(fileErr, "Failed to read template file PermissionSet.json.tmpl")
-		return fileErr
+	if renderErr != nil {
+		return renderErr
 	}
 
-	ut, templateErr := template.New("PermissionSet").Parse(string(b))
+	statusCode, ok, err := c.createAquaObject(ctx, "PermissionSet", permissionSet.Name, payload)
 
-	if templateErr != nil {
-		reqLogger.Error(templateErr, "Failed to parse template file PermissionSet.json.tmpl")
-		return templateErr
+	if err != nil {
+		return err
 	}
 
-	var permissionSetBuffer bytes.Buffer
-	ut.Execute(&permissionSetBuffer, permissionSet)
-
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v2/access_management/permissions"
-	client := &http.Client{}
-	req, clientErr := http.NewRequest("POST", reqUrl, bytes.NewBuffer(permissionSetBuffer.Bytes()))
+	if ok {
+		return nil
+	} else {
+		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not create PermissionSet, the response status from aqua was %v", statusCode))
 
-	if clientErr != nil {
-		reqLogger.Error(clientErr, "unable to create client")
-		return clientErr
+		c.Logger.Error(e, "Unable to create PermissionSet", "permissionSet", permissionSet.Name)
+		return e
 	}
+}
 
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// the permission set granted by an AquaScannerAccount's role
+type PermissionSetResource struct {
+	AquaResourceClient
+	PermissionSet PermissionSet
+}
 
-	res, err := client.Do(req)
+func (r *PermissionSetResource) Kind() string { return "PermissionSet" }
 
-	if err != nil {
-		reqLogger.Error(err, "Failed request to POST to  api/v2/access_management/permissions in aqua")
-		return err
-	}
-	defer res.Body.Close()
+func (r *PermissionSetResource) DependsOn() []string { return nil }
+
+func (r *PermissionSetResource) Observe(ctx context.Context) ([]byte, bool, error) {
+	return r.getAquaObject(ctx, "PermissionSet", r.PermissionSet.Name)
+}
 
-	var jsonData AquaResponseJson
-	body, _ := ioutil.ReadAll(res.Body)
+func (r *PermissionSetResource) Render() ([]byte, error) {
+	return renderAquaTemplate(r.Logger, r.Templates, "PermissionSet", r.PermissionSet)
+}
 
-	json.Unmarshal(body, &jsonData)
+func (r *PermissionSetResource) Create(ctx context.Context) error {
+	return CreateAquaPermissionSet(ctx, r.AquaResourceClient, r.PermissionSet)
+}
 
-	// idempotency check
-	if res.StatusCode == 404 && strings.Contains(jsonData.Message, "permission "+permissionSet.Name+" already exists") || res.StatusCode == 201 {
-		return nil
-	} else {
-		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not create PermissionSet, the response status from aqua was %v", res.StatusCode))
+func (r *PermissionSetResource) Update(ctx context.Context) error {
+	r.Logger.Info("Updating permissionSet in aqua", "permissionSet", r.PermissionSet.Name)
+	return sendAquaTemplate(ctx, r.AquaResourceClient, "PermissionSet", AquaUpdate, r.PermissionSet.Name, r.PermissionSet)
+}
 
-		reqLogger.Error(e, "Unable to create PermissionSet")
-		return e
-	}
+func (r *PermissionSetResource) Delete(ctx context.Context) error {
+	return DeleteAquaPermissionSet(ctx, r.AquaResourceClient, r.PermissionSet.Name)
 }
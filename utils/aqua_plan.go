@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// replaces the value of secret fields in plans, events and logs
+const redactedValue = "<redacted>"
+
+// fields of an aqua payload that hold secrets, matched case insensitively against the field name
+var secretAquaFields = []string{"password", "secret", "token"}
+
+func isSecretAquaField(field string) bool {
+	for _, secretField := range secretAquaFields {
+		if strings.Contains(strings.ToLower(field), secretField) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the json payload with the value of every secret field, including nested ones, replaced
+func RedactAquaPayload(payload []byte) ([]byte, error) {
+	var object interface{}
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, err
+	}
+	return marshalPlanJson(redact(object))
+}
+
+// json without html escaping, plans are read by people and < > & are common in aqua payloads
+func marshalPlanJson(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range v {
+			if isSecretAquaField(field) {
+				v[field] = redactedValue
+			} else {
+				v[field] = redact(fieldValue)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
+
+/*
+	Compares the top level fields of the payload the operator would send with the object in aqua. Fields aqua does not
+	return, such as the password of a user, can not be compared and are left out. Secret fields are never part of the
+	diff. The diff is sorted by field.
+*/
+func DiffAquaPayload(current []byte, desired []byte) ([]asav2.AquaFieldDiff, error) {
+	currentObject := map[string]interface{}{}
+	desiredObject := map[string]interface{}{}
+
+	if err := json.Unmarshal(current, &currentObject); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(desired, &desiredObject); err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for field := range desiredObject {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	diff := []asav2.AquaFieldDiff{}
+	for _, field := range fields {
+		currentValue, ok := currentObject[field]
+		if !ok || isSecretAquaField(field) || reflect.DeepEqual(currentValue, desiredObject[field]) {
+			continue
+		}
+
+		currentJson, _ := marshalPlanJson(redact(currentValue))
+		desiredJson, _ := marshalPlanJson(redact(desiredObject[field]))
+		diff = append(diff, asav2.AquaFieldDiff{Field: field, Current: string(currentJson), Desired: string(desiredJson)})
+	}
+	return diff, nil
+}
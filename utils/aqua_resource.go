@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// what every AquaResource of an AquaScannerAccount needs to talk to aqua
+type AquaResourceClient struct {
+	// the logger of the reconcile the resources are applied in
+	Logger   logr.Logger
+	AquaAuth *AquaAuth
+	// the generation of the aqua api the aqua objects are managed through, see AquaAuth.API
+	API       AquaAPI
+	Templates AquaTemplates
+	// where the changes made in aqua are recorded, nil leaves them out of the audit trail
+	Audit AuditSink
+	// the account and reconcile the changes are made for, shared by the resources of a reconcile
+	Subject *AuditSubject
+}
+
+/*
+	Sends a POST, PUT or DELETE of path to aqua and records it in the audit trail. accepted decides from the status code
+	and message aqua answers with whether the change went through, the second return. A request that could not be sent
+	is returned as the error.
+*/
+func (c AquaResourceClient) changeAquaObject(ctx context.Context, aquaObject string, method string, path string, payload []byte, accepted func(statusCode int, message string) bool) (int, bool, error) {
+	record := newAuditRecord(c.Subject, aquaObject, method, c.AquaAuth.GetUrl(), path, payload)
+	defer c.recordAudit(&record)
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, clientErr := http.NewRequestWithContext(ctx, method, c.AquaAuth.GetUrl()+path, body)
+
+	if clientErr != nil {
+		c.Logger.Error(clientErr, "Unable to create the request to aqua", "method", method, "endpoint", path)
+		record.Outcome, record.Error = AuditError, clientErr.Error()
+		return 0, false, clientErr
+	}
+
+	if authErr := c.AquaAuth.Authenticate(req); authErr != nil {
+		c.Logger.Error(authErr, "Failed to login to Aqua", "method", method, "endpoint", path)
+		record.Outcome, record.Error = AuditError, authErr.Error()
+		return 0, false, authErr
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.AquaAuth.HttpClient().Do(req)
+
+	if err != nil {
+		c.Logger.Error(err, "Failed request to aqua", "method", method, "endpoint", path)
+		record.Outcome, record.Error = AuditError, err.Error()
+		return 0, false, err
+	}
+	defer res.Body.Close()
+
+	var jsonData AquaResponseJson
+	resBody, _ := ioutil.ReadAll(res.Body)
+
+	json.Unmarshal(resBody, &jsonData)
+
+	record.StatusCode = res.StatusCode
+	ok := accepted(res.StatusCode, jsonData.Message)
+	if ok {
+		record.Outcome = AuditSucceeded
+	} else {
+		record.Outcome, record.Error = AuditFailed, jsonData.Message
+		c.Logger.Info("Aqua did not accept the change", "method", method, "endpoint", path, "statusCode", res.StatusCode, "message", jsonData.Message)
+	}
+	return res.StatusCode, ok, nil
+}
+
+// a sink that can not be written to is logged, it does not stop the change that was already made in aqua
+func (c AquaResourceClient) recordAudit(record *AuditRecord) {
+	if c.Audit == nil {
+		return
+	}
+	if err := c.Audit.Record(*record); err != nil {
+		c.Logger.Error(err, "Failed to write the audit record of a change in aqua", "method", record.Method, "endpoint", record.Endpoint)
+	}
+}
+
+// accepts the 2xx answers of aqua
+func isSuccessStatus(statusCode int, message string) bool {
+	return statusCode >= 200 && statusCode <= 299
+}
+
+// the request for action on the aqua object of kind called name, an error while the version of aqua is not known
+func (c AquaResourceClient) request(kind string, action AquaAction, name string) (AquaRequest, error) {
+	if c.API == nil {
+		return AquaRequest{}, fmt.Errorf("the version of aqua at %v has not been detected", c.AquaAuth.GetUrl())
+	}
+	return c.API.Request(kind, action, name)
+}
+
+// reads the aqua object of kind called name, false when it does not exist
+func (c AquaResourceClient) getAquaObject(ctx context.Context, kind string, name string) ([]byte, bool, error) {
+	request, err := c.request(kind, AquaGet, name)
+	if err != nil {
+		return nil, false, err
+	}
+	return getAquaObject(ctx, c.Logger, c.AquaAuth, c.API, kind, request.Path)
+}
+
+/*
+	Sends the payload to create the aqua object of kind called name. An object that exists already is accepted, the
+	create of a reconcile that failed afterwards is repeated by the next one.
+*/
+func (c AquaResourceClient) createAquaObject(ctx context.Context, kind string, name string, payload []byte) (int, bool, error) {
+	request, err := c.request(kind, AquaCreate, name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	alreadyExists := false
+	statusCode, ok, err := c.changeAquaObject(ctx, kind, request.Method, request.Path, payload, func(statusCode int, message string) bool {
+		alreadyExists = c.API.IsAlreadyExists(kind, name, statusCode, message)
+		return isSuccessStatus(statusCode, message) || alreadyExists
+	})
+	if alreadyExists {
+		c.Logger.Info("Object already exists in aqua", "kind", kind, "name", name)
+	}
+	return statusCode, ok, err
+}
+
+// deletes the aqua object of kind called name, an object that does not exist is accepted as deleted
+func (c AquaResourceClient) deleteAquaObject(ctx context.Context, kind string, name string) (int, bool, error) {
+	request, err := c.request(kind, AquaDelete, name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return c.changeAquaObject(ctx, kind, request.Method, request.Path, request.Payload, func(statusCode int, message string) bool {
+		return isSuccessStatus(statusCode, message) || c.API.IsNotFound(kind, statusCode, message)
+	})
+}
+
+/*
+	Returns the json of the object aqua answers a GET of path with and true, or false when api says the answer means
+	the object of kind does not exist.
+*/
+func getAquaObject(ctx context.Context, reqLogger logr.Logger, aquaAuth *AquaAuth, api AquaAPI, kind string, path string) ([]byte, bool, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", aquaAuth.GetUrl()+path, nil)
+	if authErr := aquaAuth.Authenticate(req); authErr != nil {
+		reqLogger.Error(authErr, "Failed to login to Aqua", "method", "GET", "endpoint", path)
+		return nil, false, authErr
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := aquaAuth.HttpClient().Do(req)
+
+	if err != nil {
+		reqLogger.Error(err, "Failed request to aqua", "method", "GET", "endpoint", path)
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	var jsonData AquaResponseJson
+	body, _ := ioutil.ReadAll(res.Body)
+
+	json.Unmarshal(body, &jsonData)
+
+	switch {
+	case res.StatusCode == 200:
+		return body, true, nil
+	case api.IsNotFound(kind, res.StatusCode, jsonData.Message):
+		return nil, false, nil
+	}
+	return nil, false, errors.NewBadRequest(fmt.Sprintf("Error: Could not get %v, the response status from aqua was %v", path, res.StatusCode))
+}
+
+// renders the template called name with data into the payload sent to aqua
+func renderAquaTemplate(reqLogger logr.Logger, templates AquaTemplates, name string, data interface{}) ([]byte, error) {
+	b, fileErr := ioutil.ReadFile(templates.Path(name))
+
+	if fileErr != nil {
+		reqLogger.Error(fileErr, "Failed to read template file", "template", name+".json.tmpl")
+		return nil, fileErr
+	}
+
+	ut, templateErr := template.New(name).Parse(string(b))
+
+	if templateErr != nil {
+		reqLogger.Error(templateErr, "Failed to parse template file", "template", name+".json.tmpl")
+		return nil, templateErr
+	}
+
+	var buffer bytes.Buffer
+	if executeErr := ut.Execute(&buffer, data); executeErr != nil {
+		return nil, executeErr
+	}
+	return buffer.Bytes(), nil
+}
+
+// renders the template of kind with data and sends it to aqua for action on the object called name
+func sendAquaTemplate(ctx context.Context, c AquaResourceClient, kind string, action AquaAction, name string, data interface{}) error {
+	payload, renderErr := renderAquaTemplate(c.Logger, c.Templates, kind, data)
+
+	if renderErr != nil {
+		return renderErr
+	}
+
+	request, requestErr := c.request(kind, action, name)
+	if requestErr != nil {
+		return requestErr
+	}
+
+	statusCode, ok, err := c.changeAquaObject(ctx, kind, request.Method, request.Path, payload, isSuccessStatus)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.NewBadRequest(fmt.Sprintf("Error: Could not %v %v, the response status from aqua was %v", request.Method, request.Path, statusCode))
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// an object the operator manages in aqua for an AquaScannerAccount
+type AquaResource interface {
+	// the kind of aqua object, also the field of the AquaScannerAccount currentState that tracks it
+	Kind() string
+	// kinds that have to exist in aqua before this resource is created and that are only deleted after it
+	DependsOn() []string
+	// returns the object as aqua has it and true, or false when the object does not exist in aqua
+	Observe(ctx context.Context) ([]byte, bool, error)
+	// returns the payload Create and Update send to aqua
+	Render() ([]byte, error)
+	Create(ctx context.Context) error
+	// brings an object that already exists in aqua back in line with the resource
+	Update(ctx context.Context) error
+	// deletes the object, an object that does not exist is not an error
+	Delete(ctx context.Context) error
+}
+
+/*
+	Orders AquaResources by their dependencies. Resources are grouped into waves where every resource only depends on
+	resources in earlier waves, the resources within a wave are independent of each other and run in parallel.
+*/
+type AquaResourceGraph struct {
+	resources map[string]AquaResource
+	waves     [][]string
+}
+
+// returned for resources that were not applied because one of their dependencies failed
+type DependencyFailedError struct {
+	Kind       string
+	Dependency string
+}
+
+func (e *DependencyFailedError) Error() string {
+	return fmt.Sprintf("%v was not applied because its dependency %v failed", e.Kind, e.Dependency)
+}
+
+func NewAquaResourceGraph(resources ...AquaResource) (*AquaResourceGraph, error) {
+	graph := &AquaResourceGraph{resources: map[string]AquaResource{}}
+
+	for _, resource := range resources {
+		if _, ok := graph.resources[resource.Kind()]; ok {
+			return nil, errors.NewBadRequest("Error: aqua resource " + resource.Kind() + " is declared more than once")
+		}
+		graph.resources[resource.Kind()] = resource
+	}
+
+	remaining := map[string][]string{}
+	for kind, resource := range graph.resources {
+		for _, dependency := range resource.DependsOn() {
+			if _, ok := graph.resources[dependency]; !ok {
+				return nil, errors.NewBadRequest("Error: aqua resource " + kind + " depends on " + dependency + " which is not declared")
+			}
+		}
+		remaining[kind] = resource.DependsOn()
+	}
+
+	done := map[string]bool{}
+	for len(remaining) > 0 {
+		wave := []string{}
+		for kind, dependencies := range remaining {
+			ready := true
+			for _, dependency := range dependencies {
+				ready = ready && done[dependency]
+			}
+			if ready {
+				wave = append(wave, kind)
+			}
+		}
+
+		if len(wave) == 0 {
+			cycle := []string{}
+			for kind := range remaining {
+				cycle = append(cycle, kind)
+			}
+			sort.Strings(cycle)
+			return nil, errors.NewBadRequest("Error: aqua resources " + strings.Join(cycle, ", ") + " have circular dependencies")
+		}
+
+		sort.Strings(wave)
+		for _, kind := range wave {
+			done[kind] = true
+			delete(remaining, kind)
+		}
+		graph.waves = append(graph.waves, wave)
+	}
+
+	return graph, nil
+}
+
+// returns the kinds in the order they are created, kinds in the same wave are created in parallel
+func (g *AquaResourceGraph) Waves() [][]string {
+	return g.waves
+}
+
+// returns every kind in the graph in creation order
+func (g *AquaResourceGraph) Kinds() []string {
+	kinds := []string{}
+	for _, wave := range g.waves {
+		kinds = append(kinds, wave...)
+	}
+	return kinds
+}
+
+/*
+	Creates the resources that do not exist in aqua and updates the ones that do, a wave at a time. A resource is not
+	applied when one of its dependencies failed. The map holds the result of every resource, nil meaning the object
+	exists in aqua, and the error is the aggregate of the failures.
+*/
+func (g *AquaResourceGraph) Apply(ctx context.Context) (map[string]error, error) {
+	results := map[string]error{}
+
+	for _, wave := range g.waves {
+		pending := []string{}
+
+		for _, kind := range wave {
+			results[kind] = nil
+			for _, dependency := range g.resources[kind].DependsOn() {
+				if results[dependency] != nil {
+					results[kind] = &DependencyFailedError{Kind: kind, Dependency: dependency}
+					break
+				}
+			}
+			if results[kind] == nil {
+				pending = append(pending, kind)
+			}
+		}
+
+		for kind, err := range g.run(ctx, "Apply", pending, applyAquaResource) {
+			results[kind] = err
+		}
+	}
+
+	return results, aggregate(results)
+}
+
+/*
+	Deletes the resources in the reverse order they are created so nothing in aqua is left referencing a deleted object.
+	Teardown stops at the first wave with a failure, the dependencies of the failed resource are kept until it is gone.
+*/
+func (g *AquaResourceGraph) Teardown(ctx context.Context) error {
+	for i := len(g.waves) - 1; i >= 0; i-- {
+		results := g.run(ctx, "Delete", g.waves[i], func(ctx context.Context, resource AquaResource) error {
+			return resource.Delete(ctx)
+		})
+
+		if err := aggregate(results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+	Works out what Apply would do without changing anything in aqua. Every payload is rendered and compared with the
+	object in aqua, resources that are already up to date are left out. A resource whose current state could not be read
+	is left out of the plan and its error is part of the returned aggregate.
+*/
+func (g *AquaResourceGraph) Plan(ctx context.Context) ([]asav2.AquaPlannedChange, error) {
+	return g.plan(ctx, g.Kinds(), planAquaResource)
+}
+
+// works out what Teardown would delete without changing anything in aqua
+func (g *AquaResourceGraph) PlanTeardown(ctx context.Context) ([]asav2.AquaPlannedChange, error) {
+	kinds := []string{}
+	for i := len(g.waves) - 1; i >= 0; i-- {
+		kinds = append(kinds, g.waves[i]...)
+	}
+
+	return g.plan(ctx, kinds, func(ctx context.Context, resource AquaResource) (*asav2.AquaPlannedChange, error) {
+		_, exists, err := resource.Observe(ctx)
+		if err != nil || !exists {
+			return nil, err
+		}
+		return &asav2.AquaPlannedChange{Kind: resource.Kind(), Action: "Delete"}, nil
+	})
+}
+
+// plans every kind in parallel and returns the changes in the order of kinds
+func (g *AquaResourceGraph) plan(ctx context.Context, kinds []string, planResource func(context.Context, AquaResource) (*asav2.AquaPlannedChange, error)) ([]asav2.AquaPlannedChange, error) {
+	changes := map[string]*asav2.AquaPlannedChange{}
+	mu := sync.Mutex{}
+
+	results := g.run(ctx, "Plan", kinds, func(ctx context.Context, resource AquaResource) error {
+		change, err := planResource(ctx, resource)
+		mu.Lock()
+		changes[resource.Kind()] = change
+		mu.Unlock()
+		return err
+	})
+
+	plan := []asav2.AquaPlannedChange{}
+	for _, kind := range kinds {
+		if changes[kind] != nil {
+			plan = append(plan, *changes[kind])
+		}
+	}
+	return plan, aggregate(results)
+}
+
+func planAquaResource(ctx context.Context, resource AquaResource) (*asav2.AquaPlannedChange, error) {
+	desired, err := resource.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	current, exists, err := resource.Observe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		payload, err := RedactAquaPayload(desired)
+		if err != nil {
+			return nil, err
+		}
+		return &asav2.AquaPlannedChange{Kind: resource.Kind(), Action: "Create", Payload: string(payload)}, nil
+	}
+
+	diff, err := DiffAquaPayload(current, desired)
+	if err != nil || len(diff) == 0 {
+		return nil, err
+	}
+	return &asav2.AquaPlannedChange{Kind: resource.Kind(), Action: "Update", Diff: diff}, nil
+}
+
+func applyAquaResource(ctx context.Context, resource AquaResource) error {
+	_, exists, err := resource.Observe(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return resource.Update(ctx)
+	}
+	return resource.Create(ctx)
+}
+
+// runs operation on every kind in parallel, each in a span named after the action and kind, and waits for all of them
+func (g *AquaResourceGraph) run(ctx context.Context, action string, kinds []string, operation func(context.Context, AquaResource) error) map[string]error {
+	results := map[string]error{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for _, kind := range kinds {
+		wg.Add(1)
+		go func(resource AquaResource) {
+			defer wg.Done()
+			ctx, span := Tracer().Start(ctx, action+" "+resource.Kind(), trace.WithAttributes(attribute.String("aqua.object", resource.Kind())))
+			err := operation(ctx, resource)
+			RecordSpanError(span, err)
+			span.End()
+			mu.Lock()
+			results[resource.Kind()] = err
+			mu.Unlock()
+		}(g.resources[kind])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// aggregates the errors in kind order so the message is stable between reconciles
+func aggregate(results map[string]error) error {
+	kinds := []string{}
+	for kind := range results {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	errs := []error{}
+	for _, kind := range kinds {
+		if results[kind] != nil {
+			errs = append(errs, results[kind])
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,306 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+)
+
+// records the operations the graph runs so the order can be checked
+type fakeAquaResource struct {
+	kind      string
+	dependsOn []string
+	exists    bool
+	current   string
+	desired   string
+	createErr error
+	deleteErr error
+	log       *operationLog
+}
+
+type operationLog struct {
+	mu         sync.Mutex
+	operations []string
+}
+
+func (l *operationLog) add(operation string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.operations = append(l.operations, operation)
+}
+
+func (l *operationLog) indexOf(operation string) int {
+	for i, o := range l.operations {
+		if o == operation {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *fakeAquaResource) Kind() string        { return r.kind }
+func (r *fakeAquaResource) DependsOn() []string { return r.dependsOn }
+func (r *fakeAquaResource) Observe(ctx context.Context) ([]byte, bool, error) {
+	return []byte(r.current), r.exists, nil
+}
+func (r *fakeAquaResource) Render() ([]byte, error) { return []byte(r.desired), nil }
+func (r *fakeAquaResource) Create(ctx context.Context) error {
+	r.log.add("create " + r.kind)
+	return r.createErr
+}
+func (r *fakeAquaResource) Update(ctx context.Context) error {
+	r.log.add("update " + r.kind)
+	return nil
+}
+func (r *fakeAquaResource) Delete(ctx context.Context) error {
+	r.log.add("delete " + r.kind)
+	return r.deleteErr
+}
+
+func scannerAccountResources(log *operationLog) []*fakeAquaResource {
+	return []*fakeAquaResource{
+		{kind: "User", dependsOn: []string{"Role"}, log: log},
+		{kind: "Role", dependsOn: []string{"ApplicationScope", "PermissionSet"}, log: log},
+		{kind: "ApplicationScope", log: log},
+		{kind: "PermissionSet", log: log},
+	}
+}
+
+func newFakeGraph(t *testing.T, fakes []*fakeAquaResource) *AquaResourceGraph {
+	resources := []AquaResource{}
+	for _, fake := range fakes {
+		resources = append(resources, fake)
+	}
+	graph, err := NewAquaResourceGraph(resources...)
+	if err != nil {
+		t.Fatalf("NewAquaResourceGraph was not supposed to return an error but got %v", err)
+	}
+	return graph
+}
+
+func TestAquaResourceGraphWaves(t *testing.T) {
+	graph := newFakeGraph(t, scannerAccountResources(&operationLog{}))
+
+	expected := [][]string{{"ApplicationScope", "PermissionSet"}, {"Role"}, {"User"}}
+
+	if !reflect.DeepEqual(graph.Waves(), expected) {
+		t.Errorf("NewAquaResourceGraph was supposed to order the resources as %v but got %v", expected, graph.Waves())
+	}
+
+	_, err := NewAquaResourceGraph(
+		&fakeAquaResource{kind: "Role", dependsOn: []string{"User"}},
+		&fakeAquaResource{kind: "User", dependsOn: []string{"Role"}},
+	)
+	if err == nil {
+		t.Errorf("NewAquaResourceGraph was supposed to return an error for circular dependencies")
+	}
+
+	_, err = NewAquaResourceGraph(&fakeAquaResource{kind: "User", dependsOn: []string{"Role"}})
+	if err == nil {
+		t.Errorf("NewAquaResourceGraph was supposed to return an error for a dependency that is not declared")
+	}
+}
+
+func TestAquaResourceGraphApply(t *testing.T) {
+	log := &operationLog{}
+	fakes := scannerAccountResources(log)
+	// the permission set already exists in aqua
+	fakes[3].exists = true
+
+	results, err := newFakeGraph(t, fakes).Apply(context.Background())
+
+	if err != nil {
+		t.Fatalf("Apply was not supposed to return an error but got %v", err)
+	}
+
+	for kind, result := range results {
+		if result != nil {
+			t.Errorf("Apply was supposed to succeed for %v but got %v", kind, result)
+		}
+	}
+
+	if log.indexOf("update PermissionSet") == -1 || log.indexOf("create PermissionSet") != -1 {
+		t.Errorf("Apply was supposed to update the existing permission set instead of creating it, got %v", log.operations)
+	}
+
+	if log.indexOf("create Role") < log.indexOf("create ApplicationScope") || log.indexOf("create Role") < log.indexOf("update PermissionSet") {
+		t.Errorf("Apply was supposed to create the role after the scope and permission set, got %v", log.operations)
+	}
+
+	if log.indexOf("create User") < log.indexOf("create Role") {
+		t.Errorf("Apply was supposed to create the user after the role, got %v", log.operations)
+	}
+}
+
+func TestAquaResourceGraphApplySkipsDependents(t *testing.T) {
+	log := &operationLog{}
+	fakes := scannerAccountResources(log)
+	fakes[2].createErr = errors.New("aqua is down")
+
+	results, err := newFakeGraph(t, fakes).Apply(context.Background())
+
+	if err == nil {
+		t.Fatalf("Apply was supposed to return an error when a resource fails")
+	}
+
+	if results["PermissionSet"] != nil {
+		t.Errorf("Apply was supposed to create the permission set independently of the scope but got %v", results["PermissionSet"])
+	}
+
+	var dependencyErr *DependencyFailedError
+	if !errors.As(results["Role"], &dependencyErr) || !errors.As(results["User"], &dependencyErr) {
+		t.Errorf("Apply was supposed to skip the role and user when the scope fails but got %v", results)
+	}
+
+	if log.indexOf("create Role") != -1 || log.indexOf("create User") != -1 {
+		t.Errorf("Apply was not supposed to create anything depending on the failed scope, got %v", log.operations)
+	}
+}
+
+func TestAquaResourceGraphTeardown(t *testing.T) {
+	log := &operationLog{}
+	fakes := scannerAccountResources(log)
+
+	if err := newFakeGraph(t, fakes).Teardown(context.Background()); err != nil {
+		t.Fatalf("Teardown was not supposed to return an error but got %v", err)
+	}
+
+	if log.indexOf("delete User") > log.indexOf("delete Role") || log.indexOf("delete Role") > log.indexOf("delete ApplicationScope") || log.indexOf("delete Role") > log.indexOf("delete PermissionSet") {
+		t.Errorf("Teardown was supposed to delete in the reverse order of creation, got %v", log.operations)
+	}
+
+	log = &operationLog{}
+	fakes = scannerAccountResources(log)
+	fakes[1].deleteErr = errors.New("aqua is down")
+
+	if err := newFakeGraph(t, fakes).Teardown(context.Background()); err == nil {
+		t.Fatalf("Teardown was supposed to return an error when a resource fails to delete")
+	}
+
+	if log.indexOf("delete ApplicationScope") != -1 || log.indexOf("delete PermissionSet") != -1 {
+		t.Errorf("Teardown was not supposed to delete the dependencies of a role that failed to delete, got %v", log.operations)
+	}
+}
+
+func TestAquaResourceGraphPlan(t *testing.T) {
+	log := &operationLog{}
+	fakes := scannerAccountResources(log)
+	fakes[0].desired = `{"id":"ScannerCLI_abc123","password":"hunter2","roles":["ScannerCLI_abc123"]}`
+	fakes[1].exists = true
+	fakes[1].current = `{"name":"ScannerCLI_abc123","description":"old","permission":"ScannerCLI_abc123"}`
+	fakes[1].desired = `{"name":"ScannerCLI_abc123","description":"new","permission":"ScannerCLI_abc123"}`
+	fakes[2].exists = true
+	fakes[2].current = `{"name":"ScannerCLI_abc123","description":"scope"}`
+	fakes[2].desired = `{"name":"ScannerCLI_abc123","description":"scope"}`
+	fakes[3].desired = `{"name":"ScannerCLI_abc123"}`
+
+	graph := newFakeGraph(t, fakes)
+
+	plan, err := graph.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan was not supposed to return an error but got %v", err)
+	}
+
+	expected := []asav2.AquaPlannedChange{
+		{Kind: "PermissionSet", Action: "Create", Payload: `{"name":"ScannerCLI_abc123"}`},
+		{Kind: "Role", Action: "Update", Diff: []asav2.AquaFieldDiff{{Field: "description", Current: `"old"`, Desired: `"new"`}}},
+		{Kind: "User", Action: "Create", Payload: `{"id":"ScannerCLI_abc123","password":"<redacted>","roles":["ScannerCLI_abc123"]}`},
+	}
+
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("Plan was supposed to return %v but got %v", expected, plan)
+	}
+
+	plan, err = graph.PlanTeardown(context.Background())
+	if err != nil {
+		t.Fatalf("PlanTeardown was not supposed to return an error but got %v", err)
+	}
+
+	expected = []asav2.AquaPlannedChange{{Kind: "Role", Action: "Delete"}, {Kind: "ApplicationScope", Action: "Delete"}}
+
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("PlanTeardown was supposed to return %v but got %v", expected, plan)
+	}
+
+	if len(log.operations) != 0 {
+		t.Errorf("planning was not supposed to change anything in aqua, got %v", log.operations)
+	}
+}
+
+func TestDiffAquaPayload(t *testing.T) {
+	current := `{"id":"ScannerCLI_abc123","roles":["old"],"first_time":false,"extra":"only in aqua"}`
+	desired := `{"id":"ScannerCLI_abc123","password":"hunter2","passwordConfirm":"hunter2","roles":["new"],"first_time":false}`
+
+	diff, err := DiffAquaPayload([]byte(current), []byte(desired))
+	if err != nil {
+		t.Fatalf("DiffAquaPayload was not supposed to return an error but got %v", err)
+	}
+
+	expected := []asav2.AquaFieldDiff{{Field: "roles", Current: `["old"]`, Desired: `["new"]`}}
+
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("DiffAquaPayload was supposed to return %v but got %v", expected, diff)
+	}
+
+	redacted, err := RedactAquaPayload([]byte(`{"user":{"password":"hunter2","api_token":"abc"},"name":"x"}`))
+	if err != nil {
+		t.Fatalf("RedactAquaPayload was not supposed to return an error but got %v", err)
+	}
+
+	if string(redacted) != `{"name":"x","user":{"api_token":"<redacted>","password":"<redacted>"}}` {
+		t.Errorf("RedactAquaPayload was supposed to redact nested secrets but got %v", string(redacted))
+	}
+}
+
+// the scope of a Complete account is re-applied by the AquaScannerAccount controller when its spec changes
+func TestAquaResourceGraphApplyUpdatesAChangedScope(t *testing.T) {
+	mu := sync.Mutex{}
+	stored := []byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path != "/api/v2/access_management/scopes/ScannerCLI_abc123":
+			w.WriteHeader(404)
+		case r.Method == "GET":
+			w.Write(stored)
+		case r.Method == "PUT":
+			stored, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	c := AquaResourceClient{Logger: ctrl.Log, AquaAuth: aa, API: aquaAPIV5{}, Templates: AquaTemplates{Dir: "../templates"}}
+	scope := func(registries ...string) *ApplicationScopeResource {
+		return &ApplicationScopeResource{AquaResourceClient: c, ApplicationScope: ApplicationScope{Name: "ScannerCLI_abc123", NamespacePrefix: "abc123", Registries: NewApplicationScopeRegistries(registries)}}
+	}
+
+	// aqua holds the scope the account was Complete with
+	stored, _ = scope(asav2.DefaultScopeRegistry).Render()
+
+	graph, err := NewAquaResourceGraph(scope("Quay"))
+	if err != nil {
+		t.Fatalf("NewAquaResourceGraph was not supposed to return an error but got %v", err)
+	}
+	if _, err := graph.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply was not supposed to return an error but got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(string(stored), `\"Quay\"`) || strings.Contains(string(stored), `\"Docker Hub\"`) {
+		t.Errorf("the scope in aqua was supposed to be updated to the changed registries but got %s", stored)
+	}
+}
@@ -1,18 +1,10 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"html/template"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type Role struct {
@@ -22,99 +14,74 @@ type Role struct {
 	PermissionSet
 }
 
-func DeleteAquaRole(reqLogger *log.DelegatingLogger, role string) error {
-	reqLogger.Info("Deleting role %v in aqua", "role", role)
+func DeleteAquaRole(ctx context.Context, c AquaResourceClient, role string) error {
+	c.Logger.Info("Deleting role in aqua", "role", role)
 
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
-
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v2/access_management/roles/" + role
-	client := &http.Client{}
-
-	req, _ := http.NewRequest("DELETE", reqUrl, nil)
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	res, err := client.Do(req)
+	statusCode, ok, err := c.deleteAquaObject(ctx, "Role", role)
 
 	if err != nil {
-		reqLogger.Error(err, "Failed request to DELETE to /api/v2/access_management/roles/ in aqua")
 		return err
 	}
 
-	if res.StatusCode != 204 && res.StatusCode != 404 {
-		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not delete role, the response status from aqua was %v", res.StatusCode))
+	if !ok {
+		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not delete role, the response status from aqua was %v", statusCode))
 		return e
 	}
 	return nil
 }
 
-func CreateAquaRole(reqLogger *log.DelegatingLogger, role Role) error {
-	reqLogger.Info("Creating Role %v in aqua", "role", role.Name)
+func CreateAquaRole(ctx context.Context, c AquaResourceClient, role Role) error {
+	c.Logger.Info("Creating role in aqua", "role", role.Name)
 
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
-	wd, _ := os.Getwd()
-	path := filepath.Join(wd, "./templates/Role.json.tmpl")
-
-	b, fileErr := ioutil.ReadFile(path)
+	payload, renderErr := renderAquaTemplate(c.Logger, c.Templates, "Role", role)
 
-	if fileErr != nil {
-		reqLogger.Error(fileErr, "Failed to read template file Role.json.tmpl")
-		return fileErr
+	if renderErr != nil {
+		return renderErr
 	}
 
-	ut, templateErr := template.New("Role").Parse(string(b))
+	statusCode, ok, err := c.createAquaObject(ctx, "Role", role.Name, payload)
 
-	if templateErr != nil {
-		reqLogger.Error(templateErr, "Failed to parse template file Role.json.tmpl")
-		return templateErr
+	if err != nil {
+		return err
 	}
 
-	var roleBuffer bytes.Buffer
-	ut.Execute(&roleBuffer, role)
-
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v2/access_management/roles"
-	client := &http.Client{}
-	req, clientErr := http.NewRequest("POST", reqUrl, &roleBuffer)
+	if ok {
+		return nil
+	} else {
+		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not create role, the response status from aqua was %v", statusCode))
 
-	if clientErr != nil {
-		reqLogger.Error(clientErr, "unable to create client")
-		return clientErr
+		c.Logger.Error(e, "Unable to create Role", "role", role.Name)
+		return e
 	}
+}
 
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// the role tying an AquaScannerAccount's application scope to its permission set
+type RoleResource struct {
+	AquaResourceClient
+	Role Role
+}
 
-	res, err := client.Do(req)
+func (r *RoleResource) Kind() string { return "Role" }
 
-	if err != nil {
-		reqLogger.Error(err, "Failed request to POST to  /api/v2/access_management/roles in aqua")
-		return err
-	}
-	defer res.Body.Close()
+func (r *RoleResource) DependsOn() []string { return []string{"ApplicationScope", "PermissionSet"} }
 
-	var jsonData AquaResponseJson
-	body, _ := ioutil.ReadAll(res.Body)
+func (r *RoleResource) Observe(ctx context.Context) ([]byte, bool, error) {
+	return r.getAquaObject(ctx, "Role", r.Role.Name)
+}
 
-	json.Unmarshal(body, &jsonData)
+func (r *RoleResource) Render() ([]byte, error) {
+	return renderAquaTemplate(r.Logger, r.Templates, "Role", r.Role)
+}
 
-	if res.StatusCode == 404 && strings.Contains(jsonData.Message, "role "+role.Name+" already exists") || res.StatusCode == 201 {
-		return nil
-	} else {
-		e := errors.NewBadRequest(fmt.Sprintf("Error: Could not create role, the response status from aqua was %v", res.StatusCode))
+func (r *RoleResource) Create(ctx context.Context) error {
+	return CreateAquaRole(ctx, r.AquaResourceClient, r.Role)
+}
 
-		reqLogger.Error(e, "Unable to create Role")
-		return e
-	}
+func (r *RoleResource) Update(ctx context.Context) error {
+	r.Logger.Info("Updating role in aqua", "role", r.Role.Name)
+	return sendAquaTemplate(ctx, r.AquaResourceClient, "Role", AquaUpdate, r.Role.Name, r.Role)
+}
+
+func (r *RoleResource) Delete(ctx context.Context) error {
+	return DeleteAquaRole(ctx, r.AquaResourceClient, r.Role.Name)
 }
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// where the json templates posted to aqua are read from
+type AquaTemplates struct {
+	// directory holding the <name>.json.tmpl files, relative paths are resolved against the working directory
+	Dir string
+	// paths to individual templates that replace the one in Dir, keyed by template name
+	Overrides map[string]string
+}
+
+// returns the path to the template with name, e.g. Role for Role.json.tmpl
+func (t AquaTemplates) Path(name string) string {
+	if path, ok := t.Overrides[name]; ok && path != "" {
+		return path
+	}
+
+	dir := t.Dir
+	if dir == "" {
+		dir = "templates"
+	}
+	if !filepath.IsAbs(dir) {
+		wd, _ := os.Getwd()
+		dir = filepath.Join(wd, dir)
+	}
+	return filepath.Join(dir, name+".json.tmpl")
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type renderedImageScope struct {
+	Categories struct {
+		Artifacts struct {
+			Image struct {
+				Expression string `json:"expression"`
+				Variables  []struct {
+					Attribute string `json:"attribute"`
+					Value     string `json:"value"`
+				} `json:"variables"`
+			} `json:"image"`
+		} `json:"artifacts"`
+	} `json:"categories"`
+}
+
+func TestApplicationScopeTemplate(t *testing.T) {
+	templates := AquaTemplates{Dir: "../templates"}
+
+	render := func(registries []string) renderedImageScope {
+		payload, err := renderAquaTemplate(ctrl.Log, templates, "ApplicationScope", ApplicationScope{Name: "ScannerCLI_abc123", NamespacePrefix: "abc123", Registries: NewApplicationScopeRegistries(registries)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		scope := renderedImageScope{}
+		if err := json.Unmarshal(payload, &scope); err != nil {
+			t.Fatalf("the ApplicationScope template was supposed to render json but got %v\n%s", err, payload)
+		}
+		return scope
+	}
+
+	// the default scope renders what the template did before the scope could be configured
+	image := render([]string{asav2.DefaultScopeRegistry}).Categories.Artifacts.Image
+	if image.Expression != "(v1 && v2) || (v3 && v4) || (v5 && v6)" || len(image.Variables) != 6 || image.Variables[4].Value != `"Docker Hub"` {
+		t.Errorf("the default scope was supposed to add Docker Hub but got %+v", image)
+	}
+
+	image = render([]string{"Quay", "GHCR"}).Categories.Artifacts.Image
+	if image.Expression != "(v1 && v2) || (v3 && v4) || (v5 && v6) || (v7 && v8)" || len(image.Variables) != 8 || image.Variables[6].Value != `"GHCR"` {
+		t.Errorf("every registry was supposed to be added to the scope but got %+v", image)
+	}
+
+	image = render(nil).Categories.Artifacts.Image
+	if image.Expression != "(v1 && v2) || (v3 && v4)" || len(image.Variables) != 4 {
+		t.Errorf("an empty scope was supposed to only have the namespace's repositories but got %+v", image)
+	}
+}
+
+func TestPermissionSetTemplate(t *testing.T) {
+	templates := AquaTemplates{Dir: "../templates"}
+
+	for _, profile := range []string{asav2.ProfileScanner, asav2.ProfileScanOnly} {
+		actions, uiAccess := PermissionSetActions(profile)
+
+		payload, err := renderAquaTemplate(ctrl.Log, templates, "PermissionSet", PermissionSet{Name: "ScannerCLI_abc123", Actions: actions, UIAccess: uiAccess})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		permissionSet := struct {
+			Actions  []string `json:"actions"`
+			UIAccess bool     `json:"ui_access"`
+		}{}
+		if err := json.Unmarshal(payload, &permissionSet); err != nil {
+			t.Fatalf("the PermissionSet template was supposed to render json but got %v\n%s", err, payload)
+		}
+
+		if strings.Join(permissionSet.Actions, ",") != strings.Join(actions, ",") || permissionSet.UIAccess != uiAccess {
+			t.Errorf("the %v profile was supposed to render its actions %v but got %+v", profile, actions, permissionSet)
+		}
+	}
+
+	if actions, uiAccess := PermissionSetActions(asav2.ProfileScanOnly); uiAccess || strings.Contains(strings.Join(actions, ","), "dashboard.read") {
+		t.Errorf("the ScanOnly profile was not supposed to have access to the aqua console but got %v", actions)
+	}
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the instrumentation name of the operator's spans
+const TracerName = "github.com/bcgov-platform-services/aqua-scan-cli-operator"
+
+// returns the tracer of the global TracerProvider, spans are dropped until one is installed with InstallTracing
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// where the spans of the operator are exported to and how many of them
+type TracingOptions struct {
+	// host:port of an OTLP/HTTP receiver such as an OpenTelemetry collector
+	Endpoint string
+	// sends the spans over http instead of https
+	Insecure bool
+	// the fraction of reconciles that are traced, from 0 to 1. Spans whose parent is sampled are always recorded
+	SamplingRatio float64
+	// the service.name of the spans
+	ServiceName string
+}
+
+// returns a TracerProvider that exports the spans it samples to the OTLP/HTTP receiver at options.Endpoint
+func NewOTLPTracerProvider(ctx context.Context, options TracingOptions) (*sdktrace.TracerProvider, error) {
+	otlpOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		otlpOptions = append(otlpOptions, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlpOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTracerProvider(sdktrace.NewBatchSpanProcessor(exporter), options), nil
+}
+
+// returns a TracerProvider that samples as options say and hands the spans to processor, tests use a syncer
+func NewTracerProvider(processor sdktrace.SpanProcessor, options TracingOptions) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SamplingRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(options.ServiceName))),
+	)
+}
+
+/*
+	Makes provider the global TracerProvider and propagates the W3C trace context and baggage. The propagator is
+	installed without a provider as well so the traces of callers are passed on to aqua.
+*/
+func InstallTracing(provider *sdktrace.TracerProvider) {
+	if provider != nil {
+		otel.SetTracerProvider(provider)
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+/*
+	Records a client span for every request made to aqua and sends its trace context along in the traceparent header.
+	The span ends when aqua's response headers arrive, reading the body is part of the caller's span.
+*/
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func NewTracingTransport(base http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{base: base}
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(req.Context(), "aqua "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...),
+	)
+	defer span.End()
+
+	// a RoundTripper must not change the request it was given
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		RecordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(res.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(res.StatusCode))
+	return res, nil
+}
+
+// records err on the span and marks it failed, nil leaves the span as it is
+func RecordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
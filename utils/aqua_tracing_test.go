@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// installs a TracerProvider that hands every span it samples to an in-memory exporter for the length of the test
+func installTestTracing(t *testing.T, samplingRatio float64) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), TracingOptions{SamplingRatio: samplingRatio, ServiceName: "test"})
+
+	previous := otel.GetTracerProvider()
+	InstallTracing(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+	return exporter
+}
+
+func spansNamed(spans tracetest.SpanStubs, name string) []tracetest.SpanStub {
+	named := []tracetest.SpanStub{}
+	for _, span := range spans {
+		if span.Name == name {
+			named = append(named, span)
+		}
+	}
+	return named
+}
+
+func TestAquaRequestsAreTraced(t *testing.T) {
+	exporter := installTestTracing(t, 1)
+
+	traceparents := []string{}
+	mu := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		mu.Unlock()
+
+		switch {
+		case r.Method == "GET":
+			w.WriteHeader(404)
+		case r.Method == "POST" && r.URL.Path == "/api/v1/users":
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(201)
+		}
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	c := AquaResourceClient{Logger: ctrl.Log, AquaAuth: aa, API: aquaAPIV5{}, Templates: AquaTemplates{Dir: "../templates"}}
+	graph, _ := NewAquaResourceGraph(
+		&UserResource{AquaResourceClient: c, User: User{Name: "ScannerCLI_abc123", Role: Role{Name: "ScannerCLI_abc123"}, Password: "hunter2hunter2"}},
+		&RoleResource{AquaResourceClient: c, Role: Role{Name: "ScannerCLI_abc123"}},
+		&ApplicationScopeResource{AquaResourceClient: c, ApplicationScope: ApplicationScope{Name: "ScannerCLI_abc123", NamespacePrefix: "abc123"}},
+		&PermissionSetResource{AquaResourceClient: c, PermissionSet: PermissionSet{Name: "ScannerCLI_abc123"}},
+	)
+
+	ctx, reconcile := Tracer().Start(context.Background(), "Reconcile AquaScannerAccount")
+	if _, err := graph.Apply(ctx); err != nil {
+		t.Fatalf("Apply was not supposed to return an error but got %v", err)
+	}
+	reconcile.End()
+
+	spans := exporter.GetSpans()
+	traceID := reconcile.SpanContext().TraceID()
+
+	applyUser := spansNamed(spans, "Apply User")
+	if len(applyUser) != 1 || applyUser[0].Parent.SpanID() != reconcile.SpanContext().SpanID() {
+		t.Fatalf("applying the user was supposed to be a span of the reconcile but got %+v", applyUser)
+	}
+
+	// every resource is observed with a GET and then created with a POST
+	requests := append(spansNamed(spans, "aqua GET"), spansNamed(spans, "aqua POST")...)
+	if len(requests) != 8 {
+		t.Fatalf("every request to aqua was supposed to be a span but got %v", len(requests))
+	}
+	userRequests := 0
+	for _, request := range requests {
+		if request.SpanKind != trace.SpanKindClient || request.SpanContext.TraceID() != traceID {
+			t.Errorf("the request was supposed to be a client span of the reconcile's trace but got %+v", request)
+		}
+		if request.Parent.SpanID() == applyUser[0].SpanContext.SpanID() {
+			userRequests++
+		}
+	}
+	if userRequests != 2 {
+		t.Errorf("the GET and POST of the user were supposed to be spans of applying the user but got %v", userRequests)
+	}
+
+	for _, request := range spansNamed(spans, "aqua POST") {
+		found := false
+		for _, attribute := range request.Attributes {
+			found = found || attribute == semconv.HTTPStatusCodeKey.Int(204) || attribute == semconv.HTTPStatusCodeKey.Int(201)
+		}
+		if !found {
+			t.Errorf("the span of a request was supposed to hold the status code aqua answered with but got %+v", request.Attributes)
+		}
+	}
+
+	if len(traceparents) != len(requests) {
+		t.Errorf("aqua was supposed to receive %v requests but got %v", len(requests), len(traceparents))
+	}
+	for _, traceparent := range traceparents {
+		if len(traceparent) != 55 || traceparent[3:35] != traceID.String() {
+			t.Errorf("the trace context was supposed to be sent to aqua but got %q", traceparent)
+		}
+	}
+}
+
+func TestAquaRequestErrorsAreRecorded(t *testing.T) {
+	exporter := installTestTracing(t, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	c := AquaResourceClient{Logger: ctrl.Log, AquaAuth: aa, API: aquaAPIV5{}, Templates: AquaTemplates{Dir: "../templates"}}
+	graph, _ := NewAquaResourceGraph(&PermissionSetResource{AquaResourceClient: c, PermissionSet: PermissionSet{Name: "ScannerCLI_abc123"}})
+
+	if err := graph.Teardown(context.Background()); err == nil {
+		t.Fatalf("Teardown was supposed to return an error when aqua can not be reached")
+	}
+
+	for _, name := range []string{"aqua DELETE", "Delete PermissionSet"} {
+		spans := spansNamed(exporter.GetSpans(), name)
+		if len(spans) != 1 || spans[0].Status.Code != codes.Error || len(spans[0].Events) == 0 {
+			t.Errorf("the %v span was supposed to record the error but got %+v", name, spans)
+		}
+	}
+}
+
+func TestTracingSamplingRatio(t *testing.T) {
+	exporter := installTestTracing(t, 0)
+
+	_, span := Tracer().Start(context.Background(), "Reconcile AquaScannerAccount")
+	span.End()
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("a sampling ratio of 0 was supposed to leave reconciles untraced but got %v spans", len(spans))
+	}
+
+	// a caller that traces the request decides for the operator
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, span = Tracer().Start(trace.ContextWithRemoteSpanContext(context.Background(), parent), "Reconcile AquaScannerAccount")
+	span.End()
+	if spans := exporter.GetSpans(); len(spans) != 1 || spans[0].SpanContext.TraceID() != parent.TraceID() {
+		t.Errorf("a span whose parent is sampled was supposed to be recorded but got %+v", spans)
+	}
+}
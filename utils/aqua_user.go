@@ -1,17 +1,9 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
-	"html/template"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"context"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type User struct {
@@ -24,104 +16,78 @@ type AquaResponseJson struct {
 	Message string `json:"message"`
 }
 
-func DeleteAquaAccount(reqLogger *log.DelegatingLogger, accountName string) error {
-	reqLogger.Info("Deleting user %v in aqua", "user", accountName)
+func DeleteAquaAccount(ctx context.Context, c AquaResourceClient, accountName string) error {
+	c.Logger.Info("Deleting user in aqua", "user", accountName)
 
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
-
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v1/users/" + accountName
-	client := &http.Client{}
-	req, _ := http.NewRequest("DELETE", reqUrl, nil)
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Accept", "application/json")
-
-	res, err := client.Do(req)
+	statusCode, ok, err := c.deleteAquaObject(ctx, "User", accountName)
 
 	if err != nil {
-		reqLogger.Error(err, "Failed request to DELETE /api/v1/users %v from aqua", accountName)
 		return err
 	}
-	var jsonData AquaResponseJson
-	body, _ := ioutil.ReadAll(res.Body)
 
-	json.Unmarshal(body, &jsonData)
-
-	if res.StatusCode == 204 || res.StatusCode == 400 && jsonData.Message == "No such user" {
-		reqLogger.Info("User %v deleted", "user", accountName)
+	if ok {
+		c.Logger.Info("Deleted user in aqua", "user", accountName)
 		return nil
 	}
 
-	reqLogger.Error(err, "Failed to DELETE /api/v1/users %v from aqua", "user", accountName, "status", res.Status)
-	return errors.NewBadRequest("Failed to DELETE user from aqua")
+	deleteErr := errors.NewBadRequest("Failed to DELETE user from aqua")
+	c.Logger.Error(deleteErr, "Failed to delete user in aqua", "user", accountName, "statusCode", statusCode)
+	return deleteErr
 }
 
-func CreateAquaAccount(reqLogger *log.DelegatingLogger, user User) error {
-	reqLogger.Info("Creating user %v in aqua", "user", user.Name)
+func CreateAquaAccount(ctx context.Context, c AquaResourceClient, user User) error {
+	c.Logger.Info("Creating user in aqua", "user", user.Name)
 
-	aquaAuth := GetAquaAuth()
-	jwt, jwtErr := aquaAuth.GetJWT()
-	if jwtErr != nil {
-		reqLogger.Error(jwtErr, "Failed to login to Aqua")
-		return jwtErr
-	}
-	wd, _ := os.Getwd()
-	path := filepath.Join(wd, "templates/User.json.tmpl")
-	b, fileErr := ioutil.ReadFile(path)
+	payload, renderErr := renderAquaTemplate(c.Logger, c.Templates, "User", user)
 
-	if fileErr != nil {
-		reqLogger.Error(fileErr, "Failed to read template file User.json.tmpl")
-		return fileErr
+	if renderErr != nil {
+		return renderErr
 	}
 
-	ut, templateErr := template.New("User").Parse(string(b))
+	statusCode, ok, err := c.createAquaObject(ctx, "User", user.Name, payload)
 
-	if templateErr != nil {
-		reqLogger.Error(templateErr, "Failed to parse template file User.json.tmpl")
-		return templateErr
+	if err != nil {
+		return err
 	}
 
-	var userBuffer bytes.Buffer
-	ut.Execute(&userBuffer, user)
-
-	reqUrl := os.Getenv("AQUA_URL") + "/api/v1/users"
-	client := &http.Client{}
-	req, clientErr := http.NewRequest("POST", reqUrl, &userBuffer)
-
-	if clientErr != nil {
-		reqLogger.Error(clientErr, "unable to create client")
-		return clientErr
+	if ok {
+		c.Logger.Info("Created user in aqua", "user", user.Name)
+		return nil
 	}
 
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
+	createErr := errors.NewBadRequest("Failed to POST user from aqua")
+	c.Logger.Error(createErr, "Failed to create user in aqua", "user", user.Name, "statusCode", statusCode)
+	return createErr
+}
+
+// the scanner user whose credentials are handed to the team
+type UserResource struct {
+	AquaResourceClient
+	User User
+}
 
-	res, err := client.Do(req)
+func (r *UserResource) Kind() string { return "User" }
 
-	if err != nil {
-		reqLogger.Error(err, "Failed request to POST /api/v1/ %v from aqua", user.Name)
-		return err
-	}
+func (r *UserResource) DependsOn() []string { return []string{"Role"} }
 
-	var jsonData AquaResponseJson
-	body, _ := ioutil.ReadAll(res.Body)
+func (r *UserResource) Observe(ctx context.Context) ([]byte, bool, error) {
+	return r.getAquaObject(ctx, "User", r.User.Name)
+}
 
-	json.Unmarshal(body, &jsonData)
+func (r *UserResource) Render() ([]byte, error) {
+	return renderAquaTemplate(r.Logger, r.Templates, "User", r.User)
+}
 
-	if res.StatusCode == 204 {
-		reqLogger.Info("User %v created in aqua", "user", user.Name)
-		return nil
-	}
+func (r *UserResource) Create(ctx context.Context) error {
+	return CreateAquaAccount(ctx, r.AquaResourceClient, r.User)
+}
 
-	if res.StatusCode == 400 && strings.Contains(jsonData.Message, "User with username "+user.Name+" already exists") {
-		reqLogger.Info("User %v already exists in aqua", "user", user.Name)
-		return nil
-	}
+// also resets the password of the user to the one stored in the AquaScannerAccount status
+func (r *UserResource) Update(ctx context.Context) error {
+	r.Logger.Info("Updating user in aqua", "user", r.User.Name)
+	return sendAquaTemplate(ctx, r.AquaResourceClient, "User", AquaUpdate, r.User.Name, r.User)
+}
 
-	reqLogger.Error(err, "Failed to POST %v from aqua. Status code is %v", "name", user.Name, "statusCode", res.StatusCode)
-	return errors.NewBadRequest("Failed to POST user from aqua")
+func (r *UserResource) Delete(ctx context.Context) error {
+	return DeleteAquaAccount(ctx, r.AquaResourceClient, r.User.Name)
 }
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+/*
+	Sets the rules of the Role that lets spec.delivery.credentialReaders read the credentials Secret of the account, and the
+	Jenkins Secret when there is one. Only get is granted on the named Secrets, listing them would reveal every Secret
+	in the namespace.
+*/
+func SetCredentialReadersRole(role *rbacv1.Role, account *asav2.AquaScannerAccount) {
+	secrets := []string{account.CredentialsSecretName()}
+	if jenkinsSecret := account.JenkinsSecretName(); jenkinsSecret != "" {
+		secrets = append(secrets, jenkinsSecret)
+	}
+
+	role.Rules = []rbacv1.PolicyRule{{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: secrets,
+		Verbs:         []string{"get"},
+	}}
+}
+
+// binds the Role of SetCredentialReadersRole to spec.delivery.credentialReaders
+func SetCredentialReadersRoleBinding(binding *rbacv1.RoleBinding, account *asav2.AquaScannerAccount) {
+	binding.RoleRef = rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: account.CredentialReadersRoleName()}
+	binding.Subjects = CredentialReaderSubjects(account)
+}
+
+// returns spec.delivery.credentialReaders with the api group and the namespace of ServiceAccounts defaulted
+func CredentialReaderSubjects(account *asav2.AquaScannerAccount) []rbacv1.Subject {
+	subjects := []rbacv1.Subject{}
+
+	for _, subject := range account.Spec.Delivery.CredentialReaders {
+		switch subject.Kind {
+		case rbacv1.ServiceAccountKind:
+			subject.APIGroup = ""
+			if subject.Namespace == "" {
+				subject.Namespace = account.Namespace
+			}
+		case rbacv1.UserKind, rbacv1.GroupKind:
+			subject.APIGroup = rbacv1.GroupName
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects
+}
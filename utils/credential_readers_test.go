@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCredentialReaders(t *testing.T) {
+	account := &asav2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Spec: asav2.AquaScannerAccountSpec{
+			Delivery: asav2.AquaScannerAccountDelivery{CredentialReaders: []rbacv1.Subject{
+				{Kind: "User", Name: "jane@github"},
+				{Kind: "ServiceAccount", Name: "pipeline"},
+				{Kind: "ServiceAccount", Name: "deployer", Namespace: "abc123-dev"},
+			}},
+		},
+	}
+
+	role := &rbacv1.Role{}
+	SetCredentialReadersRole(role, account)
+
+	if len(role.Rules) != 1 || strings.Join(role.Rules[0].Verbs, ",") != "get" || strings.Join(role.Rules[0].ResourceNames, ",") != "scanner-aqua-credentials" {
+		t.Errorf("the Role was only supposed to allow get on the credentials secret but got %+v", role.Rules)
+	}
+
+	account.Spec.Delivery.Jenkins = &asav2.AquaScannerAccountJenkinsDelivery{}
+	SetCredentialReadersRole(role, account)
+
+	if strings.Join(role.Rules[0].ResourceNames, ",") != "scanner-aqua-credentials,scanner-aqua-jenkins" {
+		t.Errorf("the Role was supposed to include the Jenkins secret but got %v", role.Rules[0].ResourceNames)
+	}
+
+	binding := &rbacv1.RoleBinding{}
+	SetCredentialReadersRoleBinding(binding, account)
+
+	if binding.RoleRef.Kind != "Role" || binding.RoleRef.Name != "scanner-aqua-credentials-reader" {
+		t.Errorf("the RoleBinding was supposed to refer to the reader Role but got %+v", binding.RoleRef)
+	}
+
+	expected := []rbacv1.Subject{
+		{Kind: "User", APIGroup: rbacv1.GroupName, Name: "jane@github"},
+		{Kind: "ServiceAccount", Name: "pipeline", Namespace: "abc123-tools"},
+		{Kind: "ServiceAccount", Name: "deployer", Namespace: "abc123-dev"},
+	}
+	for i, subject := range expected {
+		if binding.Subjects[i] != subject {
+			t.Errorf("subject %v was supposed to be %+v but got %+v", i, subject, binding.Subjects[i])
+		}
+	}
+
+	// a reader that is removed from the spec loses access on the next reconcile
+	account.Spec.Delivery.CredentialReaders = account.Spec.Delivery.CredentialReaders[1:]
+	SetCredentialReadersRoleBinding(binding, account)
+
+	if len(binding.Subjects) != 2 || binding.Subjects[0].Name != "pipeline" {
+		t.Errorf("the removed reader was supposed to be dropped from the RoleBinding but got %+v", binding.Subjects)
+	}
+}
@@ -1,82 +1,213 @@
 package utils
 
 import (
-	"math/rand"
-	"regexp"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
 )
 
-func makePassword(length int, generationString []rune) string {
-	s := make([]rune, length)
-	for i := range s {
-		s[i] = generationString[rand.Intn(len(generationString))]
-	}
-	return string(s)
-}
+// the character classes a PasswordPolicy can require
+const (
+	PasswordLowercase = "lowercase"
+	PasswordUppercase = "uppercase"
+	PasswordDigits    = "digits"
+	PasswordSymbols   = "symbols"
+)
 
-func GeneratePassword(length int, hasSymbols bool, hasNumbers bool, hasUppercase bool) string {
-	const (
-		lowercase = "abcdefghijklmnopqrstuvwxyz"
-		numbers   = "0123456789"
-		symbols   = "!@#$"
-		uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	)
+const (
+	lowercaseCharacters = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitCharacters     = "0123456789"
+	// the symbols used when a policy does not list its own
+	DefaultPasswordSymbols = "!@#$"
+	// characters html/template escapes or that have to be escaped in the json of the user template, they can not
+	// be used as symbols
+	UnsafePasswordSymbols = "\"\\'<>&+` \t\r\n"
+)
 
-	reLowercase, _ := regexp.Compile("[a-z]+")
-	reUppercase, _ := regexp.Compile("[A-Z]+")
-	reNumbers, _ := regexp.Compile("[0-9]+")
-	reSymbols, _ := regexp.Compile("[!@#$]+")
+// the longest password generated while lengthening it to reach PasswordPolicy.MinEntropyBits
+const maxPasswordLength = 128
+
+/*
+	PasswordPolicy describes the passwords GeneratePasswordWithPolicy makes. Passwords are drawn from the characters of
+	the required classes and hold at least one character of every one of them.
+*/
+type PasswordPolicy struct {
+	Length int
+	// any of PasswordLowercase, PasswordUppercase, PasswordDigits and PasswordSymbols
+	RequiredClasses []string
+	// the characters of PasswordSymbols, defaults to DefaultPasswordSymbols
+	Symbols string
+	// the password is made longer than Length when Length characters of the policy's alphabet hold fewer bits
+	MinEntropyBits int
+	// the longest password aqua accepts, 0 when aqua does not limit it
+	MaxLength int
+}
 
-	var generationString = lowercase
+// the characters of every required class in the order of RequiredClasses
+func (p PasswordPolicy) classes() ([]string, error) {
+	classes := []string{}
+	seen := map[string]bool{}
+	for _, class := range p.RequiredClasses {
+		if seen[class] {
+			continue
+		}
+		seen[class] = true
+
+		switch class {
+		case PasswordLowercase:
+			classes = append(classes, lowercaseCharacters)
+		case PasswordUppercase:
+			classes = append(classes, uppercaseCharacters)
+		case PasswordDigits:
+			classes = append(classes, digitCharacters)
+		case PasswordSymbols:
+			symbols := p.Symbols
+			if symbols == "" {
+				symbols = DefaultPasswordSymbols
+			}
+			if strings.ContainsAny(symbols, UnsafePasswordSymbols) {
+				return nil, fmt.Errorf("the password symbols %q contain a character that can not be sent to aqua", symbols)
+			}
+			classes = append(classes, symbols)
+		default:
+			return nil, fmt.Errorf("unknown password character class %q", class)
+		}
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("a password policy needs at least one character class")
+	}
+	return classes, nil
+}
 
-	if hasUppercase {
-		generationString = generationString + uppercase
+// EntropyBits is the entropy of a password of length characters drawn uniformly from an alphabet of alphabetSize
+func EntropyBits(length int, alphabetSize int) float64 {
+	if alphabetSize < 2 {
+		return 0
 	}
+	return float64(length) * math.Log2(float64(alphabetSize))
+}
 
-	if hasSymbols {
-		generationString = generationString + symbols
+// the length of the passwords made with the policy, Length raised to reach MinEntropyBits
+func (p PasswordPolicy) length(alphabetSize int, requiredClasses int) (int, error) {
+	length := p.Length
+	if length < requiredClasses {
+		length = requiredClasses
+	}
+	for EntropyBits(length, alphabetSize) < float64(p.MinEntropyBits) && length < maxPasswordLength {
+		length++
 	}
 
-	if hasNumbers {
-		generationString = generationString + numbers
+	if p.MaxLength > 0 && length > p.MaxLength {
+		// aqua rejects longer passwords, the policy can still be met when the entropy was not what made it longer
+		length = p.MaxLength
+	}
+	if length < requiredClasses {
+		return 0, fmt.Errorf("a password of at most %v characters can not hold one character of each of %v", p.MaxLength, p.RequiredClasses)
 	}
+	if EntropyBits(length, alphabetSize) < float64(p.MinEntropyBits) {
+		return 0, fmt.Errorf("a password of %v characters can not hold %v bits of entropy", length, p.MinEntropyBits)
+	}
+	return length, nil
+}
+
+// MergeAquaPolicy returns the policy made stricter wherever aqua's password policy asks for more
+func (p PasswordPolicy) MergeAquaPolicy(aquaPolicy AquaPasswordPolicy) PasswordPolicy {
+	merged := p
+	merged.RequiredClasses = append([]string{}, p.RequiredClasses...)
 
-	var password string
+	if aquaPolicy.MinLength > merged.Length {
+		merged.Length = aquaPolicy.MinLength
+	}
+	merged.MaxLength = aquaPolicy.MaxLength
+
+	aquaClasses := []struct {
+		class    string
+		required bool
+	}{
+		{PasswordLowercase, aquaPolicy.RequireLowercase},
+		{PasswordUppercase, aquaPolicy.RequireUppercase},
+		{PasswordDigits, aquaPolicy.RequireDigits},
+		{PasswordSymbols, aquaPolicy.RequireSpecialCharacters},
+	}
+	for _, c := range aquaClasses {
+		if c.required && !containsString(merged.RequiredClasses, c.class) {
+			merged.RequiredClasses = append(merged.RequiredClasses, c.class)
+		}
+	}
 
-	genStringRunes := []rune(generationString)
+	return merged
+}
 
-	for {
-		password = makePassword(length, genStringRunes)
-		pBytes := []byte(password)
+/*
+	GeneratePasswordWithPolicy makes a password following policy with random bytes read from random, which is
+	crypto/rand.Reader outside of tests. One character of every required class is placed first and the rest are drawn
+	from the whole alphabet, then the password is shuffled so the classes do not sit at known positions.
+*/
+func GeneratePasswordWithPolicy(policy PasswordPolicy, random io.Reader) (string, error) {
+	classes, err := policy.classes()
+	if err != nil {
+		return "", err
+	}
+	alphabet := []rune(strings.Join(classes, ""))
 
-		if hasNumbers {
+	length, err := policy.length(len(alphabet), len(classes))
+	if err != nil {
+		return "", err
+	}
 
-			numbersMatch := reNumbers.Match(pBytes)
-			if !numbersMatch {
-				continue
-			}
+	password := make([]rune, 0, length)
+	for _, class := range classes {
+		c, err := randomRune(random, []rune(class))
+		if err != nil {
+			return "", err
 		}
-
-		if hasSymbols {
-			symbolsMatch := reSymbols.Match(pBytes)
-			if !symbolsMatch {
-				continue
-			}
+		password = append(password, c)
+	}
+	for len(password) < length {
+		c, err := randomRune(random, alphabet)
+		if err != nil {
+			return "", err
 		}
+		password = append(password, c)
+	}
 
-		if hasUppercase {
-			uppercaseMatches := reUppercase.Match(pBytes)
-			if !uppercaseMatches {
-				continue
-			}
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(random, i+1)
+		if err != nil {
+			return "", err
 		}
+		password[i], password[j] = password[j], password[i]
+	}
 
-		lowercaseMatches := reLowercase.Match(pBytes)
+	return string(password), nil
+}
 
-		if !lowercaseMatches {
-			continue
-		}
+// a uniformly random index below n, big.Int rejects the values that would bias the result
+func randomIndex(random io.Reader, n int) (int, error) {
+	i, err := rand.Int(random, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read random bytes for a password: %w", err)
+	}
+	return int(i.Int64()), nil
+}
 
-		break
+func randomRune(random io.Reader, characters []rune) (rune, error) {
+	i, err := randomIndex(random, len(characters))
+	if err != nil {
+		return 0, err
+	}
+	return characters[i], nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	return password
+	return false
 }
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var allPasswordClasses = []string{PasswordLowercase, PasswordUppercase, PasswordDigits, PasswordSymbols}
+
+// reads the bytes 0, 1, 2, ... 255, 0, 1, ...
+type countingReader struct {
+	next byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = c.next
+		c.next++
+	}
+	return len(p), nil
+}
+
+func TestGeneratePasswordWithPolicyKnownAnswers(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   PasswordPolicy
+		random   func() io.Reader
+		expected string
+	}{
+		{
+			// every index drawn is 0, so the classes are a, A, 0 and ! and the shuffle swaps each position with the first
+			name:     "zero bytes",
+			policy:   PasswordPolicy{Length: 8, RequiredClasses: allPasswordClasses},
+			random:   func() io.Reader { return bytes.NewReader(make([]byte, 64)) },
+			expected: "A0!aaaaa",
+		},
+		{
+			name:     "zero bytes with own symbols",
+			policy:   PasswordPolicy{Length: 4, RequiredClasses: []string{PasswordSymbols, PasswordDigits}, Symbols: "%^"},
+			random:   func() io.Reader { return bytes.NewReader(make([]byte, 64)) },
+			expected: "0%%%",
+		},
+		{
+			name:     "counting bytes",
+			policy:   PasswordPolicy{Length: 12, RequiredClasses: allPasswordClasses},
+			random:   func() io.Reader { return &countingReader{} },
+			expected: "ihgjklfe$2Ba",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			password, err := GeneratePasswordWithPolicy(c.policy, c.random())
+			if err != nil {
+				t.Fatalf("GeneratePasswordWithPolicy was not supposed to return an error but got %v", err)
+			}
+			if password != c.expected {
+				t.Errorf("GeneratePasswordWithPolicy was supposed to return %v for the known random bytes but got %v", c.expected, password)
+			}
+		})
+	}
+}
+
+func TestGeneratePasswordWithPolicyClassGuarantees(t *testing.T) {
+	policies := []PasswordPolicy{
+		{Length: 4, RequiredClasses: allPasswordClasses},
+		{Length: 16, RequiredClasses: allPasswordClasses, Symbols: "!@#$%^*()-_="},
+		{Length: 8, RequiredClasses: []string{PasswordUppercase, PasswordDigits}},
+		{Length: 6, RequiredClasses: []string{PasswordLowercase}},
+	}
+	characters := map[string]string{
+		PasswordLowercase: lowercaseCharacters,
+		PasswordUppercase: uppercaseCharacters,
+		PasswordDigits:    digitCharacters,
+	}
+
+	for _, policy := range policies {
+		classes := map[string]string{}
+		alphabet := ""
+		for _, class := range policy.RequiredClasses {
+			classes[class] = characters[class]
+			if class == PasswordSymbols {
+				classes[class] = policy.Symbols
+				if policy.Symbols == "" {
+					classes[class] = DefaultPasswordSymbols
+				}
+			}
+			alphabet += classes[class]
+		}
+
+		for seed := int64(0); seed < 1000; seed++ {
+			password, err := GeneratePasswordWithPolicy(policy, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				t.Fatalf("GeneratePasswordWithPolicy was not supposed to return an error for %+v but got %v", policy, err)
+			}
+
+			if len(password) != policy.Length {
+				t.Errorf("GeneratePasswordWithPolicy was supposed to return %v characters for %+v but got %v", policy.Length, policy, password)
+			}
+			for class, classCharacters := range classes {
+				if !strings.ContainsAny(password, classCharacters) {
+					t.Errorf("GeneratePasswordWithPolicy was supposed to return a password with %v for %+v but got %v", class, policy, password)
+				}
+			}
+			for _, c := range password {
+				if !strings.ContainsRune(alphabet, c) {
+					t.Errorf("GeneratePasswordWithPolicy was supposed to only use the characters of the required classes for %+v but got %v", policy, password)
+				}
+			}
+		}
+	}
+}
+
+func TestGeneratePasswordWithPolicyEntropy(t *testing.T) {
+	// log2(10) bits per digit, 24 digits hold 79.7 bits
+	password, err := GeneratePasswordWithPolicy(PasswordPolicy{Length: 8, RequiredClasses: []string{PasswordDigits}, MinEntropyBits: 80}, &countingReader{})
+	if err != nil || len(password) != 25 {
+		t.Errorf("GeneratePasswordWithPolicy was supposed to lengthen a digit password to 25 characters to reach 80 bits but got %v, %v", password, err)
+	}
+
+	// 10 characters of 66 hold 60 bits
+	if _, err := GeneratePasswordWithPolicy(PasswordPolicy{Length: 8, RequiredClasses: allPasswordClasses, MinEntropyBits: 80, MaxLength: 10}, &countingReader{}); err == nil {
+		t.Errorf("GeneratePasswordWithPolicy was supposed to return an error when aqua's maximum length can not hold the entropy")
+	}
+
+	password, err = GeneratePasswordWithPolicy(PasswordPolicy{Length: 16, RequiredClasses: allPasswordClasses, MaxLength: 12}, &countingReader{})
+	if err != nil || len(password) != 12 {
+		t.Errorf("GeneratePasswordWithPolicy was supposed to shorten the password to aqua's maximum length but got %v, %v", password, err)
+	}
+}
+
+func TestGeneratePasswordWithPolicyErrors(t *testing.T) {
+	policies := map[string]PasswordPolicy{
+		"no classes":     {Length: 8},
+		"unknown class":  {Length: 8, RequiredClasses: []string{"emoji"}},
+		"unsafe symbols": {Length: 8, RequiredClasses: []string{PasswordSymbols}, Symbols: "!<"},
+		"too short":      {Length: 8, RequiredClasses: allPasswordClasses, MaxLength: 3},
+	}
+	for name, policy := range policies {
+		if _, err := GeneratePasswordWithPolicy(policy, &countingReader{}); err == nil {
+			t.Errorf("GeneratePasswordWithPolicy was supposed to return an error for a policy with %v", name)
+		}
+	}
+
+	if _, err := GeneratePasswordWithPolicy(PasswordPolicy{Length: 8, RequiredClasses: allPasswordClasses}, iotest.ErrReader(iotest.ErrTimeout)); err == nil {
+		t.Errorf("GeneratePasswordWithPolicy was supposed to return an error when the random bytes can not be read")
+	}
+}
+
+func TestAquaPasswordPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/forbidden") {
+			w.WriteHeader(403)
+			return
+		}
+		if r.URL.Path != (aquaAPIV5{}).PasswordPolicyPath() {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte(`{"min_length":20,"max_length":64,"require_lowercase":true,"require_digits":true,"require_special_characters":true}`))
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+
+	aquaPolicy, found := GetAquaPasswordPolicy(context.Background(), ctrl.Log, aa, aquaAPIV5{})
+	if !found {
+		t.Fatalf("GetAquaPasswordPolicy was supposed to read the policy aqua serves")
+	}
+
+	policy := PasswordPolicy{Length: 16, RequiredClasses: []string{PasswordUppercase}}.MergeAquaPolicy(aquaPolicy)
+	expectedClasses := []string{PasswordUppercase, PasswordLowercase, PasswordDigits, PasswordSymbols}
+	if policy.Length != 20 || policy.MaxLength != 64 || strings.Join(policy.RequiredClasses, ",") != strings.Join(expectedClasses, ",") {
+		t.Errorf("MergeAquaPolicy was supposed to follow the stricter parts of aqua's policy but got %+v", policy)
+	}
+
+	aa, _ = NewAquaAuth(server.URL+"/older", AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	if _, found := GetAquaPasswordPolicy(context.Background(), ctrl.Log, aa, aquaAPIV5{}); found {
+		t.Errorf("GetAquaPasswordPolicy was supposed to report an aqua without a password policy")
+	}
+
+	// the operator's user may not be allowed to read the settings of aqua, the configured policy is used then
+	aa, _ = NewAquaAuth(server.URL+"/forbidden", AquaCredentials{Method: TokenAuthMethod, Token: "token"}, nil)
+	if aquaPolicy, found := GetAquaPasswordPolicy(context.Background(), ctrl.Log, aa, aquaAPIV5{}); found || aquaPolicy != (AquaPasswordPolicy{}) {
+		t.Errorf("GetAquaPasswordPolicy was supposed to fall back to the configured policy when aqua answers 403 but got %+v, %v", aquaPolicy, found)
+	}
+}
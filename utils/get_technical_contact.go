@@ -23,3 +23,40 @@ func GetTechnicalContactFromAnnotation(contacts string) string {
 	// technical contact still has "email: foo@bar.com" which needs to be trimmed
 	return strings.TrimPrefix(strings.Trim(technicalContact, " "), "email: ")
 }
+
+// the annotation of a namespace holding its contacts in the form above
+const ContactsAnnotation = "contacts"
+
+// a contact of a namespace, e.g. its Product Owner
+type Contact struct {
+	Role  string `json:"role"`
+	Email string `json:"email"`
+}
+
+func (c Contact) String() string {
+	return c.Role + " <" + c.Email + ">"
+}
+
+// returns every contact in the contacts annotation that has an email
+func GetContactsFromAnnotation(contacts string) []Contact {
+	found := []Contact{}
+
+	for _, line := range strings.Split(contacts, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "- role:"):
+			found = append(found, Contact{Role: strings.TrimSpace(strings.TrimPrefix(line, "- role:"))})
+		case strings.HasPrefix(line, "email:") && len(found) > 0:
+			found[len(found)-1].Email = strings.TrimSpace(strings.TrimPrefix(line, "email:"))
+		}
+	}
+
+	withEmail := []Contact{}
+	for _, contact := range found {
+		if contact.Email != "" {
+			withEmail = append(withEmail, contact)
+		}
+	}
+	return withEmail
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// annotations the OpenShift console and Jenkins show as the description of the credential
+var jenkinsDescriptionAnnotations = []string{"openshift.io/description", "kubernetes.io/description"}
+
+/*
+	Sets the secret up as a kubernetes.io/basic-auth Secret with the sync label, which the OpenShift Jenkins sync plugin
+	turns into a username/password credential holding the account's scanner credentials. Labels and annotations others
+	set on the Secret are kept.
+*/
+func SetJenkinsCredentials(secret *corev1.Secret, account *asav2.AquaScannerAccount, aquaURL string, password string) {
+	jenkins := account.Spec.Delivery.Jenkins
+
+	secret.Type = corev1.SecretTypeBasicAuth
+	secret.Data = map[string][]byte{
+		corev1.BasicAuthUsernameKey: []byte(account.Status.AccountName),
+		corev1.BasicAuthPasswordKey: []byte(password),
+	}
+
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[asav2.JenkinsCredentialSyncLabel] = "true"
+
+	description := jenkins.Description
+	if description == "" {
+		description = fmt.Sprintf("Aqua scanner credentials of AquaScannerAccount %v/%v for %v", account.Namespace, account.Name, aquaURL)
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	for _, annotation := range jenkinsDescriptionAnnotations {
+		secret.Annotations[annotation] = description
+	}
+
+	if jenkins.CredentialID != "" {
+		secret.Annotations[asav2.JenkinsCredentialNameAnnotation] = jenkins.CredentialID
+	} else {
+		delete(secret.Annotations, asav2.JenkinsCredentialNameAnnotation)
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetJenkinsCredentials(t *testing.T) {
+	account := &asav2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Spec:       asav2.AquaScannerAccountSpec{Delivery: asav2.AquaScannerAccountDelivery{Jenkins: &asav2.AquaScannerAccountJenkinsDelivery{}}},
+		Status:     asav2.AquaScannerAccountStatus{AccountName: "ScannerCLI_abc123"},
+	}
+
+	if name := account.JenkinsSecretName(); name != "scanner-aqua-jenkins" {
+		t.Errorf("the Jenkins secret was supposed to default to scanner-aqua-jenkins but got %v", name)
+	}
+
+	// the sync plugin adds its own annotations, they are kept
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"jenkins.openshift.io/sync": "done"}}}
+	SetJenkinsCredentials(secret, account, "https://aqua.example.com", "first")
+
+	if secret.Type != corev1.SecretTypeBasicAuth || string(secret.Data["username"]) != "ScannerCLI_abc123" || string(secret.Data["password"]) != "first" {
+		t.Errorf("the secret was supposed to be a basic-auth secret with the scanner credentials but got %v %v", secret.Type, secret.Data)
+	}
+	if secret.Labels[asav2.JenkinsCredentialSyncLabel] != "true" {
+		t.Errorf("the secret was supposed to have the sync label but got %v", secret.Labels)
+	}
+	if description := secret.Annotations["openshift.io/description"]; !strings.Contains(description, "abc123-tools/scanner") || !strings.Contains(description, "https://aqua.example.com") {
+		t.Errorf("the description was supposed to name the account and aqua url but got %v", description)
+	}
+	if _, ok := secret.Annotations[asav2.JenkinsCredentialNameAnnotation]; ok || secret.Annotations["jenkins.openshift.io/sync"] != "done" {
+		t.Errorf("only the operator's annotations were supposed to be set but got %v", secret.Annotations)
+	}
+
+	// a rotation and a custom credential id and description are picked up on the next reconcile
+	account.Spec.Delivery.Jenkins = &asav2.AquaScannerAccountJenkinsDelivery{SecretName: "aqua", CredentialID: "aqua-scanner", Description: "Aqua for the pipelines"}
+	SetJenkinsCredentials(secret, account, "https://aqua.example.com", "rotated")
+
+	if string(secret.Data["password"]) != "rotated" || secret.Annotations[asav2.JenkinsCredentialNameAnnotation] != "aqua-scanner" || secret.Annotations["kubernetes.io/description"] != "Aqua for the pipelines" {
+		t.Errorf("the secret was supposed to follow the rotation and delivery settings but got %v %v", secret.Data, secret.Annotations)
+	}
+	if name := account.JenkinsSecretName(); name != "aqua" {
+		t.Errorf("the Jenkins secret was supposed to be called aqua but got %v", name)
+	}
+
+	account.Spec.Delivery.Jenkins = nil
+	if name := account.JenkinsSecretName(); name != "" {
+		t.Errorf("there was not supposed to be a Jenkins secret without delivery but got %v", name)
+	}
+}
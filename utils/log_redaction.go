@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+/*
+	Keys whose value is never logged, matched case insensitively against the key with - and _ removed. The names of
+	kubernetes Secrets are logged under "secret", so unlike in aqua payloads that key is not one of them.
+*/
+var secretLogKeys = []string{"password", "passwd", "token", "jwt", "apikey", "apisecret", "authorization"}
+
+func isSecretLogKey(key string) bool {
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+	for _, secretKey := range secretLogKeys {
+		if strings.Contains(normalized, secretKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// secrets found in messages, errors and values, e.g. a jwt in an error aqua answered with or a payload in a message
+var secretLogPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// a jwt, the header is always json and so always starts with eyJ
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), redactedValue},
+	{regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`), "${1}" + redactedValue},
+	// a secret field of a json payload
+	{regexp.MustCompile(`(?i)("[^"]*(?:password|secret|token)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`), "${1}\"" + redactedValue + "\""},
+	// password=..., e.g. in a query string or an env
+	{regexp.MustCompile(`(?i)\b((?:password|passwd|token|api_?key|api_?secret)=)[^&\s]+`), "${1}" + redactedValue},
+}
+
+// returns s with every secret the patterns find in it replaced
+func RedactLogString(s string) string {
+	for _, secret := range secretLogPatterns {
+		s = secret.pattern.ReplaceAllString(s, secret.replacement)
+	}
+	return s
+}
+
+/*
+	A logr.Logger that redacts passwords and jwts before they reach the logger it wraps. The values of secret keys are
+	dropped whatever they hold, messages, errors and other values have the secrets found in them replaced. The manager
+	logs through one, so every logger derived from ctrl.Log or the ctx of a reconcile is covered.
+*/
+type redactingLogger struct {
+	logger logr.Logger
+}
+
+func NewRedactingLogger(logger logr.Logger) logr.Logger {
+	if _, ok := logger.(*redactingLogger); ok {
+		return logger
+	}
+	return &redactingLogger{logger: logger}
+}
+
+func (l *redactingLogger) Enabled() bool {
+	return l.logger.Enabled()
+}
+
+func (l *redactingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Info(RedactLogString(msg), redactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.logger.Error(redactError(err), RedactLogString(msg), redactKeysAndValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) V(level int) logr.Logger {
+	return &redactingLogger{logger: l.logger.V(level)}
+}
+
+func (l *redactingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &redactingLogger{logger: l.logger.WithValues(redactKeysAndValues(keysAndValues)...)}
+}
+
+func (l *redactingLogger) WithName(name string) logr.Logger {
+	return &redactingLogger{logger: l.logger.WithName(name)}
+}
+
+// a copy of keysAndValues with the secrets redacted, a trailing key without a value is redacted as a value
+func redactKeysAndValues(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, isString := keysAndValues[i].(string)
+		if !isString || i+1 == len(keysAndValues) {
+			redacted[i] = redactLogValue(keysAndValues[i])
+		} else {
+			redacted[i] = key
+		}
+		if i+1 == len(keysAndValues) {
+			break
+		}
+		if isString && isSecretLogKey(key) {
+			redacted[i+1] = redactedValue
+		} else {
+			redacted[i+1] = redactLogValue(keysAndValues[i+1])
+		}
+	}
+	return redacted
+}
+
+/*
+	Values that can not hold a secret are passed on as they are so the logger encodes them as it would. Anything else
+	is passed on as it is unless its text or json holds a secret, then the redacted text is logged instead.
+*/
+func redactLogValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Time, time.Duration:
+		return value
+	case string:
+		return RedactLogString(v)
+	case []byte:
+		return []byte(RedactLogString(string(v)))
+	case error:
+		return redactError(v)
+	case fmt.Stringer:
+		if s := v.String(); RedactLogString(s) != s {
+			return RedactLogString(s)
+		}
+		return value
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var object interface{}
+	if json.Unmarshal(raw, &object) != nil {
+		return value
+	}
+	// both are marshalled from object so the fields are in the same order
+	plain, _ := marshalPlanJson(object)
+	redacted, _ := marshalPlanJson(redactLogJson(object))
+	if redactedJson := RedactLogString(string(redacted)); redactedJson != string(plain) {
+		return redactedJson
+	}
+	return value
+}
+
+// like redact, with the fields of a value such as AquaCredentials that are secret as log keys redacted as well
+func redactLogJson(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range v {
+			if isSecretAquaField(field) || isSecretLogKey(field) {
+				v[field] = redactedValue
+			} else {
+				v[field] = redactLogJson(fieldValue)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactLogJson(v[i])
+		}
+	}
+	return value
+}
+
+// an error whose text held a secret
+type redactedError struct {
+	message string
+}
+
+func (e *redactedError) Error() string { return e.message }
+
+// returns err when its text holds no secret, otherwise an error with the redacted text that does not wrap err
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	if redacted := RedactLogString(message); redacted != message {
+		return &redactedError{message: redacted}
+	}
+	if _, isFormatter := err.(fmt.Formatter); isFormatter && RedactLogString(fmt.Sprintf("%+v", err)) != fmt.Sprintf("%+v", err) {
+		// the verbose form some loggers add, e.g. with the errors wrapped by err, holds a secret
+		return errors.New(message)
+	}
+	return err
+}
@@ -1,23 +1,28 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"context"
 	"net/http"
 	"os"
 	"sync"
 	"time"
 
-	"github.com/kataras/jwt"
-	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"github.com/go-logr/logr"
 )
 
 type AquaAuth struct {
-	jwt string
-	exp int64
+	Url           string
+	Credentials   AquaCredentials
+	Client        *http.Client
+	authenticator Authenticator
+	mu            sync.Mutex
+	// the api picked from the version aqua reported, read again once it is older than aquaVersionTTL
+	api        AquaAPI
+	version    AquaVersion
+	detectedAt time.Time
+	// the outcome of the last CheckLogin
+	loginChecked bool
+	loginErr     error
 }
 
 type LoginReqBody struct {
@@ -36,95 +41,181 @@ type JwtPayload struct {
 var lock = &sync.Mutex{}
 var aquaAuth *AquaAuth
 
+func NewAquaAuth(url string, creds AquaCredentials, client *http.Client) (*AquaAuth, error) {
+	authenticator, err := NewAuthenticator(creds)
+	if err != nil {
+		return nil, err
+	}
+	return &AquaAuth{Url: url, Credentials: creds, Client: client, authenticator: authenticator}, nil
+}
+
+/*
+	Checks that aqua accepts the operator's credentials, logging in when needed. The returned token is the bearer
+	token sent to aqua and is empty for auth methods that sign requests instead.
+*/
 func (aa *AquaAuth) GetJWT() (string, error) {
-	now := time.Now().Unix()
+	authenticator, err := aa.getAuthenticator()
+	if err != nil {
+		return "", err
+	}
+	return authenticator.Verify(aa)
+}
 
-	if aa.exp == 0 || now > aa.exp {
-		err := aa.Login()
+// sets the headers that authenticate a request with aqua
+func (aa *AquaAuth) Authenticate(req *http.Request) error {
+	authenticator, err := aa.getAuthenticator()
+	if err != nil {
+		return err
+	}
+	return authenticator.Authenticate(aa, req)
+}
 
+func (aa *AquaAuth) getAuthenticator() (Authenticator, error) {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+
+	if aa.authenticator == nil {
+		authenticator, err := NewAuthenticator(aa.Credentials)
 		if err != nil {
-			aa.jwt = ""
+			return nil, err
 		}
-
-		return aa.jwt, err
+		aa.authenticator = authenticator
 	}
-
-	return aa.jwt, nil
+	return aa.authenticator, nil
 }
 
-func (aa *AquaAuth) Login() error {
-	aquaUrl := os.Getenv("AQUA_URL")
-	aquaUsername := os.Getenv("AQUA_USER")
-	aquaPassword := os.Getenv("AQUA_PASSWORD")
-
-	reqBody := LoginReqBody{Id: aquaUsername, Password: aquaPassword}
-	buffer, _ := json.Marshal(reqBody)
-	reqUrl := aquaUrl + "/api/v1/login"
-	client := &http.Client{}
-	req, _ := http.NewRequest("POST", reqUrl, bytes.NewBuffer(buffer))
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+/*
+	Replaces the credentials used to authenticate with aqua. Any cached login is thrown away when they changed so the
+	next request authenticates with the new credentials. The boolean return is true when the credentials changed.
+*/
+func (aa *AquaAuth) SetCredentials(url string, creds AquaCredentials) (bool, error) {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
 
-	res, err := client.Do(req)
+	if aa.Url == url && aa.Credentials == creds && aa.authenticator != nil {
+		return false, nil
+	}
 
+	authenticator, err := NewAuthenticator(creds)
 	if err != nil {
-		return errors.NewInternalError(err)
+		return false, err
 	}
 
-	defer res.Body.Close()
+	if aa.Url != url {
+		// another aqua may run another version
+		aa.api, aa.version, aa.detectedAt = nil, AquaVersion{}, time.Time{}
+	}
+	aa.Url = url
+	aa.Credentials = creds
+	aa.authenticator = authenticator
+
+	return true, nil
+}
 
-	var jsonData LoginRes
-	body, jsonErr := ioutil.ReadAll(res.Body)
+// returns the auth method used with aqua, safe to call while the credentials are being reloaded
+func (aa *AquaAuth) GetAuthMethod() string {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
 
-	if jsonErr != nil {
-		return jsonErr
+	if aa.Credentials.Method == "" {
+		return PasswordAuthMethod
 	}
+	return aa.Credentials.Method
+}
 
-	json.Unmarshal(body, &jsonData)
+// returns the base url of the aqua instance, safe to call while the credentials are being reloaded
+func (aa *AquaAuth) GetUrl() string {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
 
-	if res.StatusCode == 200 {
-		aa.jwt = jsonData.Token
+	return aa.Url
+}
 
-		exp := JwtPayload{}
-		token, decodeErr := jwt.Decode([]byte(jsonData.Token))
+/*
+	Returns the AquaAPI for the version aqua runs. The version is read from aqua the first time and again once the
+	version read last is older than aquaVersionTTL, so the api follows upgrades of aqua.
+*/
+func (aa *AquaAuth) API(ctx context.Context) (AquaAPI, AquaVersion, error) {
+	aa.mu.Lock()
+	api, version, detectedAt := aa.api, aa.version, aa.detectedAt
+	aa.mu.Unlock()
 
-		if decodeErr != nil {
-			return decodeErr
-		}
+	if api != nil && time.Since(detectedAt) < aquaVersionTTL {
+		return api, version, nil
+	}
+	return aa.DetectAPI(ctx)
+}
 
-		json.Unmarshal(token.Payload, &exp)
-		aa.exp = exp.Exp
+/*
+	Reads the version of aqua and picks the AquaAPI for it. The version is returned along with the
+	UnsupportedAquaVersionError of a version no AquaAPI supports, it is empty when it could not be read.
+*/
+func (aa *AquaAuth) DetectAPI(ctx context.Context) (AquaAPI, AquaVersion, error) {
+	version, err := getAquaVersion(ctx, aa)
+	if err != nil {
+		return nil, AquaVersion{}, err
+	}
 
-		return nil
-	} else {
-		// failure operator needs to quit
-		e := fmt.Errorf("failed to login to Aqua, returned status code was %v", res.StatusCode)
-		return e
+	api, err := AquaAPIForVersion(version)
+
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	aa.api, aa.version, aa.detectedAt = api, version, time.Now()
+	return api, version, err
+}
+
+// returns the http client used for every request to this aqua instance
+func (aa *AquaAuth) HttpClient() *http.Client {
+	if aa.Client == nil {
+		return &http.Client{Transport: NewTracingTransport(http.DefaultTransport)}
 	}
+	return aa.Client
 }
 
-func GetAquaAuth() *AquaAuth {
+// returns the AquaAuth for the aqua instance configured through the AQUA_URL and AQUA_AUTH_METHOD env vars
+// along with the credentials for the method (AQUA_USER and AQUA_PASSWORD, AQUA_TOKEN or AQUA_API_KEY and AQUA_API_SECRET).
+// A CA, client certificate or TLS setting in the env that can not be used is returned as an error, a client without
+// them would fail later with a confusing error or connect without mTLS.
+func GetAquaAuth() (*AquaAuth, error) {
 	lock.Lock()
 	defer lock.Unlock()
 	if aquaAuth == nil {
-		aquaAuth = &AquaAuth{}
+		httpConfig, err := AquaHttpConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		client, err := NewAquaHttpClient(httpConfig)
+		if err != nil {
+			return nil, err
+		}
+		aquaAuth = &AquaAuth{Url: os.Getenv("AQUA_URL"), Credentials: AquaCredentialsFromEnv(), Client: client}
 	}
-	return aquaAuth
+	return aquaAuth, nil
 }
 
 /*
-	Sets an env var ASA_LOGIN_CHECK_DID_FAIL to string true|false
-	this var is picked up by the main reconcilliation loop and pauses main reconcilliation when true.
-	This provides a point for operator intervention where the manager pod can be restarted to retrigger the check.
+	Logs in to aqua and records whether it worked. The main reconcilliation loop is paused for the AquaScannerAccounts
+	using this aqua while the last check failed, the OperatorCredentials controller checks again when the credentials
+	change and retries the check until it passes.
 */
-func SetEnvForAsaLoginCheck(getJWT func() (string, error), reqLogger *log.DelegatingLogger) {
-	_, jwtErr := getJWT()
+func (aa *AquaAuth) CheckLogin(reqLogger logr.Logger) error {
+	_, jwtErr := aa.GetJWT()
 	if jwtErr != nil {
 		reqLogger.Error(jwtErr, "Aqua login check Failed")
-		os.Setenv("ASA_LOGIN_CHECK_DID_FAIL", "true")
 	} else {
 		reqLogger.Info("Aqua login check Passed")
-		os.Setenv("ASA_LOGIN_CHECK_DID_FAIL", "false")
 	}
+
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	aa.loginChecked, aa.loginErr = true, jwtErr
+	return jwtErr
+}
+
+// returns true when the last CheckLogin failed, the second return is false when the login was never checked
+func (aa *AquaAuth) LoginCheckFailed() (bool, bool) {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+
+	return aa.loginErr != nil, aa.loginChecked
 }
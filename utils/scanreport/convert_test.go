@@ -0,0 +1,131 @@
+package scanreport
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func testReport(t *testing.T) *Report {
+	data, err := ioutil.ReadFile("testdata/scannercli.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestParseGate(t *testing.T) {
+	gate, err := ParseGate("high, medium=5")
+	if err != nil {
+		t.Fatalf("the thresholds were supposed to parse but got %v", err)
+	}
+	if len(gate.Max) != 3 || gate.Max[Critical] != 0 || gate.Max[High] != 0 || gate.Max[Medium] != 5 {
+		t.Errorf("high was supposed to gate critical and high at 0 and medium at 5 but got %v", gate.Max)
+	}
+
+	if gate, _ := ParseGate("critical=2,high"); gate.Max[Critical] != 2 {
+		t.Errorf("an explicit maximum was not supposed to be overridden by a bare severity but got %v", gate.Max)
+	}
+
+	for _, thresholds := range []string{"severe", "high=-1", "high=many"} {
+		if _, err := ParseGate(thresholds); err == nil {
+			t.Errorf("%v was supposed to be an invalid threshold", thresholds)
+		}
+	}
+}
+
+func TestGateEvaluate(t *testing.T) {
+	report := testReport(t)
+
+	gate, _ := ParseGate("critical=1,high=0")
+	gate.Policy = true
+	results := gate.Evaluate(report)
+
+	if len(results) != 3 || results[0].Name != "policy" || results[1].Name != Critical || results[2].Name != High {
+		t.Fatalf("the policy was supposed to be checked before the severities but got %+v", results)
+	}
+	if results[0].Passed || !strings.Contains(results[0].Message, "max_severity") {
+		t.Errorf("the policy check was supposed to fail with the failed control but got %+v", results[0])
+	}
+	if !results[1].Passed || results[2].Passed {
+		t.Errorf("one critical was supposed to be allowed and one high not but got %+v", results)
+	}
+	if !Failed(results) {
+		t.Errorf("the gate was supposed to fail")
+	}
+
+	if gate, _ := ParseGate(""); Failed(gate.Evaluate(report)) {
+		t.Errorf("an empty gate was not supposed to fail")
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	data, err := SARIF(testReport(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := sarifLog{}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("the SARIF log was supposed to be json but got %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("a SARIF 2.1.0 log with one run was supposed to be written but got %v", string(data))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 5 || len(run.Results) != 5 {
+		t.Errorf("a rule and result per CVE plus the failed policy were supposed to be written but got %v rules and %v results", len(run.Tool.Driver.Rules), len(run.Results))
+	}
+
+	first := run.Results[0]
+	if first.RuleID != "CVE-2019-14697" || first.Level != "error" || run.Tool.Driver.Rules[first.RuleIndex].ID != first.RuleID {
+		t.Errorf("the critical CVE was supposed to be the first error result but got %+v", first)
+	}
+	if first.Locations[0].PhysicalLocation.ArtifactLocation.URI != "quay.io/abc123/app:1.0" || first.Locations[0].LogicalLocations[0].Name != "musl" {
+		t.Errorf("the result was supposed to point at the image and package but got %+v", first.Locations)
+	}
+	if severity := run.Tool.Driver.Rules[0].Properties["security-severity"]; severity != "9.8" {
+		t.Errorf("the rule was supposed to carry the score as security-severity but got %v", severity)
+	}
+
+	last := run.Results[len(run.Results)-1]
+	if last.RuleID != policyRuleID || last.Level != "error" {
+		t.Errorf("the failed policy was supposed to be an error result but got %+v", last)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	report := testReport(t)
+	gate, _ := ParseGate("critical")
+
+	data, err := JUnit(report, gate.Evaluate(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suites := junitTestSuites{}
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("the JUnit report was supposed to be xml but got %v", err)
+	}
+
+	if len(suites.Suites) != 2 || suites.Tests != 5 || suites.Failures != 5 {
+		t.Fatalf("4 failed vulnerabilities and a failed gate were supposed to be written but got %v", string(data))
+	}
+	if vulnerabilities := suites.Suites[0]; vulnerabilities.Name != "quay.io/abc123/app:1.0" || vulnerabilities.Cases[0].Failure.Type != Critical {
+		t.Errorf("the vulnerabilities were supposed to be failures in a suite named after the image but got %+v", vulnerabilities)
+	}
+	if gate := suites.Suites[1]; gate.Cases[0].Name != Critical || gate.Cases[0].Failure == nil {
+		t.Errorf("the critical threshold was supposed to fail but got %+v", gate)
+	}
+
+	if data, _ := JUnit(report, nil); strings.Contains(string(data), "quality gate") {
+		t.Errorf("no gate suite was supposed to be written without results but got %v", string(data))
+	}
+}
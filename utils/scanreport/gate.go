@@ -0,0 +1,109 @@
+package scanreport
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// a quality gate on a report, e.g. no critical and at most 5 high vulnerabilities
+type Gate struct {
+	// the most vulnerabilities of a severity that are allowed, severities that are not in the map are not gated
+	Max map[string]int
+	// fail when the image did not pass its image assurance policies
+	Policy bool
+}
+
+// the outcome of one check of a gate
+type GateResult struct {
+	// the severity checked, or "policy"
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// name of the GateResult for the image assurance policies
+const policyCheck = "policy"
+
+/*
+	ParseGate reads thresholds such as "critical=0,high=5". A bare severity is a threshold of zero for it and every
+	more severe severity, so "high" fails on any critical or high vulnerability. An empty string gates nothing.
+*/
+func ParseGate(thresholds string) (Gate, error) {
+	gate := Gate{Max: map[string]int{}}
+
+	for _, threshold := range strings.Split(thresholds, ",") {
+		threshold = strings.TrimSpace(threshold)
+		if threshold == "" {
+			continue
+		}
+
+		parts := strings.SplitN(threshold, "=", 2)
+		severity := strings.ToLower(strings.TrimSpace(parts[0]))
+		rank, ok := severityRank[severity]
+		if !ok {
+			return Gate{}, fmt.Errorf("unknown severity %q in threshold %q, use one of %v", severity, threshold, strings.Join(Severities, ", "))
+		}
+
+		if len(parts) == 1 {
+			for _, s := range Severities[:rank+1] {
+				if _, set := gate.Max[s]; !set {
+					gate.Max[s] = 0
+				}
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 {
+			return Gate{}, fmt.Errorf("the maximum in threshold %q is not a number of vulnerabilities", threshold)
+		}
+		gate.Max[severity] = n
+	}
+
+	return gate, nil
+}
+
+// Evaluate checks the report against every threshold of the gate, from the most to the least severe
+func (g Gate) Evaluate(r *Report) []GateResult {
+	results := []GateResult{}
+
+	if g.Policy {
+		result := GateResult{Name: policyCheck, Passed: r.PolicyPassed, Message: "the image passed its image assurance policies"}
+		if !r.PolicyPassed {
+			result.Message = "the image did not pass its image assurance policies"
+			if len(r.FailedPolicies) > 0 {
+				result.Message += ": " + strings.Join(r.FailedPolicies, ", ")
+			}
+		}
+		results = append(results, result)
+	}
+
+	severities := []string{}
+	for severity := range g.Max {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool { return severityRank[severities[i]] < severityRank[severities[j]] })
+
+	for _, severity := range severities {
+		found, max := r.Counts.Of(severity), g.Max[severity]
+		results = append(results, GateResult{
+			Name:    severity,
+			Passed:  found <= max,
+			Message: fmt.Sprintf("%v %v vulnerabilities found, at most %v allowed", found, severity, max),
+		})
+	}
+
+	return results
+}
+
+// Failed returns true when any check of the gate failed
+func Failed(results []GateResult) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package scanreport
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// the parts of the JUnit xml format CI systems read
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+/*
+	JUnit converts the report to JUnit xml. Every vulnerability is a failed test case in a suite named after the image,
+	and when results are given the checks of the quality gate make up a second suite so CI shows why the gate failed.
+*/
+func JUnit(r *Report, results []GateResult) ([]byte, error) {
+	vulnerabilities := junitTestSuite{Name: r.Image}
+	if r.Digest != "" {
+		vulnerabilities.Properties = []junitProperty{{Name: "digest", Value: r.Digest}}
+	}
+
+	for _, v := range r.Vulnerabilities {
+		text := v.Description
+		if v.FixVersion != "" {
+			text = fmt.Sprintf("Fixed in %v %v. %v", v.Resource, v.FixVersion, text)
+		}
+		if v.URL != "" {
+			text += "\n" + v.URL
+		}
+
+		vulnerabilities.Cases = append(vulnerabilities.Cases, junitTestCase{
+			ClassName: v.Resource,
+			Name:      fmt.Sprintf("[%v] %v", v.Severity, v.Name),
+			Failure: &junitFailure{
+				Message: fmt.Sprintf("%v %v in %v %v", v.Severity, v.Name, v.Resource, v.ResourceVersion),
+				Type:    v.Severity,
+				Text:    text,
+			},
+		})
+	}
+
+	suites := junitTestSuites{Name: "aqua scan"}
+	suites.Suites = append(suites.Suites, countSuite(vulnerabilities))
+
+	if len(results) > 0 {
+		gate := junitTestSuite{Name: "quality gate"}
+		for _, result := range results {
+			testCase := junitTestCase{ClassName: "quality gate", Name: result.Name}
+			if !result.Passed {
+				testCase.Failure = &junitFailure{Message: result.Message, Type: "threshold"}
+			}
+			gate.Cases = append(gate.Cases, testCase)
+		}
+		suites.Suites = append(suites.Suites, countSuite(gate))
+	}
+
+	for _, suite := range suites.Suites {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+	}
+
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+func countSuite(suite junitTestSuite) junitTestSuite {
+	suite.Tests = len(suite.Cases)
+	for _, testCase := range suite.Cases {
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+	}
+	return suite
+}
@@ -0,0 +1,187 @@
+package scanreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// rule of the results for failed image assurance policies
+	policyRuleID = "aqua-image-assurance"
+)
+
+// the parts of the SARIF 2.1.0 log format that are written
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool         `json:"tool"`
+	Results    []sarifResult     `json:"results"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	FullDescription      *sarifMessage          `json:"fullDescription,omitempty"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration     `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// the score used for security-severity when aqua gave none, the lower bound of the severity's cvss range
+var defaultScores = map[string]float64{Critical: 9.0, High: 7.0, Medium: 4.0, Low: 0.1}
+
+/*
+	SARIF converts the report to a SARIF 2.1.0 log with a rule per CVE and a result per vulnerable package, plus a
+	result for each failed image assurance policy. The image is the artifact of every result.
+*/
+func SARIF(r *Report) ([]byte, error) {
+	driver := sarifDriver{Name: "Aqua scannercli", InformationURI: "https://www.aquasec.com", Rules: []sarifRule{}}
+	results := []sarifResult{}
+	ruleIndex := map[string]int{}
+
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: r.Image}}}
+
+	for _, v := range r.Vulnerabilities {
+		index, ok := ruleIndex[v.Name]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[v.Name] = index
+			driver.Rules = append(driver.Rules, vulnerabilityRule(v))
+		}
+
+		message := fmt.Sprintf("%v in %v %v", v.Name, v.Resource, v.ResourceVersion)
+		if v.FixVersion != "" {
+			message += ", fixed in " + v.FixVersion
+		}
+
+		vulnerable := location
+		vulnerable.LogicalLocations = []sarifLogicalLocation{{Name: v.Resource, Kind: "package"}}
+
+		results = append(results, sarifResult{
+			RuleID:    v.Name,
+			RuleIndex: index,
+			Level:     sarifLevel(v.Severity),
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{vulnerable},
+		})
+	}
+
+	if len(r.FailedPolicies) > 0 || !r.PolicyPassed {
+		index := len(driver.Rules)
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   policyRuleID,
+			ShortDescription:     sarifMessage{Text: "The image must pass its Aqua image assurance policies"},
+			DefaultConfiguration: sarifConfiguration{Level: "error"},
+		})
+
+		failed := r.FailedPolicies
+		if len(failed) == 0 {
+			failed = []string{"an image assurance policy"}
+		}
+		for _, policy := range failed {
+			results = append(results, sarifResult{
+				RuleID:    policyRuleID,
+				RuleIndex: index,
+				Level:     "error",
+				Message:   sarifMessage{Text: "The image did not pass " + policy},
+				Locations: []sarifLocation{location},
+			})
+		}
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: results}
+	if r.Digest != "" {
+		run.Properties = map[string]string{"imageDigest": r.Digest}
+	}
+
+	b, err := json.MarshalIndent(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func vulnerabilityRule(v Vulnerability) sarifRule {
+	score := v.Score
+	if score == 0 {
+		score = defaultScores[v.Severity]
+	}
+
+	rule := sarifRule{
+		ID:                   v.Name,
+		ShortDescription:     sarifMessage{Text: v.Name},
+		HelpURI:              v.URL,
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(v.Severity)},
+		Properties: map[string]interface{}{
+			// github code scanning ranks alerts by this
+			"security-severity": strconv.FormatFloat(score, 'f', 1, 64),
+			"tags":              []string{"security", "vulnerability", v.Severity},
+		},
+	}
+	if v.Description != "" {
+		rule.FullDescription = &sarifMessage{Text: v.Description}
+	}
+	return rule
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case Critical, High:
+		return "error"
+	case Medium:
+		return "warning"
+	}
+	return "note"
+}
@@ -0,0 +1,323 @@
+/*
+	Package scanreport parses the json report scannercli writes with --jsonfile into a compact Report: the counts by
+	severity, whether the image passed its image assurance policies, the vulnerabilities found and the image digest.
+	It has no dependencies on the operator so pipelines can use it on their own scannercli output.
+*/
+package scanreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lines the Jobs of AquaImageScans print around the json report in their log
+const (
+	BeginMarker = "----- BEGIN AQUA SCAN REPORT -----"
+	EndMarker   = "----- END AQUA SCAN REPORT -----"
+)
+
+// severities from the most to the least severe
+const (
+	Critical   = "critical"
+	High       = "high"
+	Medium     = "medium"
+	Low        = "low"
+	Negligible = "negligible"
+	Unknown    = "unknown"
+)
+
+var severityRank = map[string]int{Critical: 0, High: 1, Medium: 2, Low: 3, Negligible: 4, Unknown: 5}
+
+// Severities lists the severities from the most to the least severe
+var Severities = []string{Critical, High, Medium, Low, Negligible, Unknown}
+
+type SeverityCounts struct {
+	Critical   int `json:"critical"`
+	High       int `json:"high"`
+	Medium     int `json:"medium"`
+	Low        int `json:"low"`
+	Negligible int `json:"negligible"`
+	Unknown    int `json:"unknown,omitempty"`
+}
+
+// Of returns the number of vulnerabilities of a severity
+func (c SeverityCounts) Of(severity string) int {
+	switch severity {
+	case Critical:
+		return c.Critical
+	case High:
+		return c.High
+	case Medium:
+		return c.Medium
+	case Low:
+		return c.Low
+	case Negligible:
+		return c.Negligible
+	}
+	return c.Unknown
+}
+
+// Total returns the number of vulnerabilities of every severity
+func (c SeverityCounts) Total() int {
+	return c.Critical + c.High + c.Medium + c.Low + c.Negligible + c.Unknown
+}
+
+// a vulnerability of a resource, e.g. a package, in the image
+type Vulnerability struct {
+	Name            string  `json:"name"`
+	Severity        string  `json:"severity"`
+	Score           float64 `json:"score,omitempty"`
+	Resource        string  `json:"resource,omitempty"`
+	ResourceVersion string  `json:"resourceVersion,omitempty"`
+	FixVersion      string  `json:"fixVersion,omitempty"`
+	Description     string  `json:"description,omitempty"`
+	// where the vulnerability is described, e.g. on nvd
+	URL string `json:"url,omitempty"`
+}
+
+type Report struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+	// false when the image was disallowed by an image assurance policy
+	PolicyPassed bool `json:"policyPassed"`
+	// the policies and controls that failed, e.g. "Default: max_severity"
+	FailedPolicies []string       `json:"failedPolicies,omitempty"`
+	Counts         SeverityCounts `json:"counts"`
+	// sorted from the most to the least severe
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+	// the number of vulnerabilities left out by Marshal to respect a size limit
+	OmittedVulnerabilities int `json:"omittedVulnerabilities,omitempty"`
+}
+
+// the parts of scannercli's json report that are read
+type scannerOutput struct {
+	Image                 string `json:"image"`
+	Digest                string `json:"digest"`
+	Disallowed            bool   `json:"disallowed"`
+	ImageAssuranceResults struct {
+		Disallowed      bool `json:"disallowed"`
+		ChecksPerformed []struct {
+			Failed     bool   `json:"failed"`
+			PolicyName string `json:"policy_name"`
+			Control    string `json:"control"`
+		} `json:"checks_performed"`
+	} `json:"image_assurance_results"`
+	VulnerabilitySummary *struct {
+		Critical   int `json:"critical"`
+		High       int `json:"high"`
+		Medium     int `json:"medium"`
+		Low        int `json:"low"`
+		Negligible int `json:"negligible"`
+	} `json:"vulnerability_summary"`
+	Resources []struct {
+		Resource struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			Path    string `json:"path"`
+		} `json:"resource"`
+		Vulnerabilities []struct {
+			Name           string  `json:"name"`
+			Description    string  `json:"description"`
+			NvdURL         string  `json:"nvd_url"`
+			VendorURL      string  `json:"vendor_url"`
+			AquaSeverity   string  `json:"aqua_severity"`
+			AquaScore      float64 `json:"aqua_score"`
+			NvdSeverityV3  string  `json:"nvd_severity_v3"`
+			NvdScoreV3     float64 `json:"nvd_score_v3"`
+			NvdSeverity    string  `json:"nvd_severity"`
+			NvdScore       float64 `json:"nvd_score"`
+			VendorSeverity string  `json:"vendor_severity"`
+			FixVersion     string  `json:"fix_version"`
+		} `json:"vulnerabilities"`
+	} `json:"resources"`
+}
+
+// Parse reads the json report of scannercli
+func Parse(data []byte) (*Report, error) {
+	output := scannerOutput{}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("the scannercli report is not valid json: %v", err)
+	}
+
+	report := &Report{
+		Image:        output.Image,
+		Digest:       output.Digest,
+		PolicyPassed: !output.Disallowed && !output.ImageAssuranceResults.Disallowed,
+	}
+
+	for _, check := range output.ImageAssuranceResults.ChecksPerformed {
+		if check.Failed {
+			report.FailedPolicies = append(report.FailedPolicies, strings.TrimPrefix(check.PolicyName+": "+check.Control, ": "))
+		}
+	}
+
+	for _, resource := range output.Resources {
+		name := resource.Resource.Name
+		if name == "" {
+			name = resource.Resource.Path
+		}
+
+		for _, v := range resource.Vulnerabilities {
+			// aqua's own severity is used when aqua has one, then nvd's and then the vendor's
+			severity, score := firstSeverity([]rating{
+				{v.AquaSeverity, v.AquaScore},
+				{v.NvdSeverityV3, v.NvdScoreV3},
+				{v.NvdSeverity, v.NvdScore},
+				{v.VendorSeverity, 0},
+			})
+			report.Vulnerabilities = append(report.Vulnerabilities, Vulnerability{
+				Name:            v.Name,
+				Severity:        severity,
+				Score:           score,
+				Resource:        name,
+				ResourceVersion: resource.Resource.Version,
+				FixVersion:      v.FixVersion,
+				Description:     v.Description,
+				URL:             firstNonEmpty(v.NvdURL, v.VendorURL),
+			})
+		}
+	}
+
+	sortVulnerabilities(report.Vulnerabilities)
+
+	if output.VulnerabilitySummary != nil {
+		summary := output.VulnerabilitySummary
+		report.Counts = SeverityCounts{Critical: summary.Critical, High: summary.High, Medium: summary.Medium, Low: summary.Low, Negligible: summary.Negligible}
+	} else {
+		report.Counts = Count(report.Vulnerabilities)
+	}
+
+	return report, nil
+}
+
+// Extract returns the json between BeginMarker and EndMarker in the log of a scan Job
+func Extract(log []byte) ([]byte, bool) {
+	begin := bytes.LastIndex(log, []byte(BeginMarker))
+	if begin == -1 {
+		return nil, false
+	}
+	rest := log[begin+len(BeginMarker):]
+
+	end := bytes.Index(rest, []byte(EndMarker))
+	if end == -1 {
+		return nil, false
+	}
+	return bytes.TrimSpace(rest[:end]), true
+}
+
+// Count returns the number of vulnerabilities of each severity
+func Count(vulnerabilities []Vulnerability) SeverityCounts {
+	counts := SeverityCounts{}
+	for _, v := range vulnerabilities {
+		switch v.Severity {
+		case Critical:
+			counts.Critical++
+		case High:
+			counts.High++
+		case Medium:
+			counts.Medium++
+		case Low:
+			counts.Low++
+		case Negligible:
+			counts.Negligible++
+		default:
+			counts.Unknown++
+		}
+	}
+	return counts
+}
+
+// Top returns the n most severe vulnerabilities, a CVE found in several resources is only returned once
+func (r *Report) Top(n int) []Vulnerability {
+	top := []Vulnerability{}
+	seen := map[string]bool{}
+
+	for _, v := range r.Vulnerabilities {
+		if len(top) == n {
+			break
+		}
+		if seen[v.Name] {
+			continue
+		}
+		seen[v.Name] = true
+		top = append(top, v)
+	}
+	return top
+}
+
+/*
+	Marshal returns the report as json of at most limit bytes. When the full report is larger the least severe
+	vulnerabilities are left out, the number left out is returned and set in OmittedVulnerabilities. The counts always
+	cover every vulnerability.
+*/
+func (r *Report) Marshal(limit int) ([]byte, int, error) {
+	trimmed := *r
+	trimmed.OmittedVulnerabilities = 0
+
+	b, err := json.Marshal(trimmed)
+	if err != nil || len(b) <= limit {
+		return b, 0, err
+	}
+
+	// binary search for the most vulnerabilities that fit
+	low, high := 0, len(r.Vulnerabilities)
+	for low < high {
+		mid := (low + high + 1) / 2
+		trimmed.Vulnerabilities = r.Vulnerabilities[:mid]
+		trimmed.OmittedVulnerabilities = len(r.Vulnerabilities) - mid
+		if b, _ = json.Marshal(trimmed); len(b) <= limit {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	trimmed.Vulnerabilities = r.Vulnerabilities[:low]
+	trimmed.OmittedVulnerabilities = len(r.Vulnerabilities) - low
+	if b, err = json.Marshal(trimmed); err == nil && len(b) > limit {
+		return nil, 0, fmt.Errorf("the scan report does not fit in %v bytes even without vulnerabilities", limit)
+	}
+	return b, trimmed.OmittedVulnerabilities, err
+}
+
+// a severity and score given by one source, e.g. nvd
+type rating struct {
+	severity string
+	score    float64
+}
+
+func firstSeverity(ratings []rating) (string, float64) {
+	for _, r := range ratings {
+		severity := strings.ToLower(r.severity)
+		if _, ok := severityRank[severity]; ok && severity != Unknown {
+			return severity, r.score
+		}
+	}
+	return Unknown, 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// sorts by severity, then by score and then by name so the order is stable
+func sortVulnerabilities(vulnerabilities []Vulnerability) {
+	sort.SliceStable(vulnerabilities, func(i, j int) bool {
+		a, b := vulnerabilities[i], vulnerabilities[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,116 @@
+package scanreport
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/scannercli.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Parse(data)
+	if err != nil {
+		t.Fatalf("the scannercli report was supposed to parse but got %v", err)
+	}
+
+	if report.Image != "quay.io/abc123/app:1.0" || report.Digest[:7] != "sha256:" {
+		t.Errorf("the image and digest were supposed to be read but got %v and %v", report.Image, report.Digest)
+	}
+
+	if report.PolicyPassed || len(report.FailedPolicies) != 1 || report.FailedPolicies[0] != "Default: max_severity" {
+		t.Errorf("the image was supposed to fail the max_severity control but got %v %v", report.PolicyPassed, report.FailedPolicies)
+	}
+
+	if report.Counts != (SeverityCounts{Critical: 1, High: 1, Medium: 1, Low: 1}) {
+		t.Errorf("the counts were supposed to come from the vulnerability summary but got %+v", report.Counts)
+	}
+
+	names := []string{}
+	for _, v := range report.Vulnerabilities {
+		names = append(names, v.Name+":"+v.Severity)
+	}
+	want := []string{"CVE-2019-14697:critical", "CVE-2019-1549:high", "CVE-2020-28928:medium", "CVE-2019-1551:low"}
+	if len(names) != len(want) {
+		t.Fatalf("the vulnerabilities were supposed to be %v but got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("the vulnerabilities were supposed to be sorted by severity as %v but got %v", want, names)
+			break
+		}
+	}
+
+	if top := report.Top(2); len(top) != 2 || top[0].Resource != "musl" || top[0].FixVersion != "1.1.22-r3" || top[0].Score != 9.8 {
+		t.Errorf("the top vulnerability was supposed to be the musl CVE but got %+v", top)
+	}
+
+	if _, err := Parse([]byte("scan failed")); err == nil {
+		t.Errorf("output that is not json was supposed to be an error")
+	}
+}
+
+func TestTopSkipsDuplicateCVEs(t *testing.T) {
+	report := &Report{Vulnerabilities: []Vulnerability{
+		{Name: "CVE-1", Severity: High, Resource: "a"},
+		{Name: "CVE-1", Severity: High, Resource: "b"},
+		{Name: "CVE-2", Severity: Low, Resource: "a"},
+	}}
+
+	if top := report.Top(5); len(top) != 2 || top[1].Name != "CVE-2" {
+		t.Errorf("a CVE in two resources was supposed to be returned once but got %+v", top)
+	}
+}
+
+func TestCount(t *testing.T) {
+	counts := Count([]Vulnerability{{Severity: Critical}, {Severity: Critical}, {Severity: Negligible}, {Severity: "whatever"}})
+	if counts != (SeverityCounts{Critical: 2, Negligible: 1, Unknown: 1}) || counts.Total() != 4 {
+		t.Errorf("the vulnerabilities were counted wrong %+v", counts)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	log := []byte("Scanning image quay.io/abc123/app:1.0\n" + BeginMarker + "\n{\"image\": \"app\"}\n\n" + EndMarker + "\n")
+
+	data, found := Extract(log)
+	if !found || string(data) != `{"image": "app"}` {
+		t.Errorf("the report between the markers was supposed to be extracted but got %q", data)
+	}
+
+	if _, found := Extract([]byte("Error: failed to login\n" + BeginMarker + "\n{")); found {
+		t.Errorf("a report without an end marker was supposed to be missing")
+	}
+}
+
+func TestMarshalRespectsLimit(t *testing.T) {
+	report := &Report{Image: "app", Counts: SeverityCounts{High: 100}}
+	for i := 0; i < 100; i++ {
+		report.Vulnerabilities = append(report.Vulnerabilities, Vulnerability{Name: "CVE-2021-0000", Severity: High, Resource: "openssl"})
+	}
+
+	full, omitted, err := report.Marshal(1 << 20)
+	if err != nil || omitted != 0 {
+		t.Fatalf("the report was supposed to fit whole but got %v omitted and %v", omitted, err)
+	}
+
+	limit := len(full) / 2
+	data, omitted, err := report.Marshal(limit)
+	if err != nil || len(data) > limit || omitted == 0 {
+		t.Fatalf("the report was supposed to be trimmed to %v bytes but got %v bytes, %v omitted and %v", limit, len(data), omitted, err)
+	}
+
+	trimmed := Report{}
+	if err := json.Unmarshal(data, &trimmed); err != nil {
+		t.Fatal(err)
+	}
+	if trimmed.OmittedVulnerabilities != omitted || len(trimmed.Vulnerabilities)+omitted != 100 || trimmed.Counts.High != 100 {
+		t.Errorf("the trimmed report was supposed to keep the counts and say what was left out but got %+v", trimmed)
+	}
+
+	if _, _, err := report.Marshal(10); err == nil {
+		t.Errorf("a limit smaller than an empty report was supposed to be an error")
+	}
+}
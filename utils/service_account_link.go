@@ -0,0 +1,54 @@
+package utils
+
+import (
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+	Adds the credentials Secret to the secrets of the ServiceAccount and names it in the aqua-credentials annotation.
+	Returns false when the ServiceAccount was already linked.
+*/
+func LinkServiceAccount(serviceAccount *corev1.ServiceAccount, secretName string) bool {
+	changed := false
+
+	if serviceAccount.Annotations[asav2.ServiceAccountCredentialsAnnotation] != secretName {
+		if serviceAccount.Annotations == nil {
+			serviceAccount.Annotations = map[string]string{}
+		}
+		serviceAccount.Annotations[asav2.ServiceAccountCredentialsAnnotation] = secretName
+		changed = true
+	}
+
+	for _, secret := range serviceAccount.Secrets {
+		if secret.Name == secretName {
+			return changed
+		}
+	}
+	serviceAccount.Secrets = append(serviceAccount.Secrets, corev1.ObjectReference{Name: secretName})
+	return true
+}
+
+// removes what LinkServiceAccount added, returns false when the ServiceAccount was not linked
+func UnlinkServiceAccount(serviceAccount *corev1.ServiceAccount, secretName string) bool {
+	changed := false
+
+	// the annotation may name the Secret of another account linking the ServiceAccount, which is left alone
+	if serviceAccount.Annotations[asav2.ServiceAccountCredentialsAnnotation] == secretName {
+		delete(serviceAccount.Annotations, asav2.ServiceAccountCredentialsAnnotation)
+		changed = true
+	}
+
+	secrets := []corev1.ObjectReference{}
+	for _, secret := range serviceAccount.Secrets {
+		if secret.Name == secretName {
+			changed = true
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	if len(secrets) != len(serviceAccount.Secrets) {
+		serviceAccount.Secrets = secrets
+	}
+	return changed
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLinkServiceAccount(t *testing.T) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "pipeline"},
+		Secrets:    []corev1.ObjectReference{{Name: "pipeline-token-abcde"}},
+	}
+
+	if !LinkServiceAccount(serviceAccount, "scanner-aqua-credentials") {
+		t.Errorf("linking a new ServiceAccount was supposed to change it")
+	}
+	if len(serviceAccount.Secrets) != 2 || serviceAccount.Secrets[1].Name != "scanner-aqua-credentials" || serviceAccount.Annotations[asav2.ServiceAccountCredentialsAnnotation] != "scanner-aqua-credentials" {
+		t.Errorf("the credentials secret was supposed to be added to the secrets and annotation but got %+v", serviceAccount)
+	}
+
+	if LinkServiceAccount(serviceAccount, "scanner-aqua-credentials") {
+		t.Errorf("linking a linked ServiceAccount again was not supposed to change it")
+	}
+
+	// another account's link is left alone
+	if UnlinkServiceAccount(serviceAccount, "other-aqua-credentials") || len(serviceAccount.Secrets) != 2 {
+		t.Errorf("unlinking another secret was not supposed to change the ServiceAccount but got %+v", serviceAccount)
+	}
+
+	if !UnlinkServiceAccount(serviceAccount, "scanner-aqua-credentials") {
+		t.Errorf("unlinking a linked ServiceAccount was supposed to change it")
+	}
+	if len(serviceAccount.Secrets) != 1 || serviceAccount.Secrets[0].Name != "pipeline-token-abcde" {
+		t.Errorf("only the credentials secret was supposed to be removed but got %+v", serviceAccount.Secrets)
+	}
+	if _, ok := serviceAccount.Annotations[asav2.ServiceAccountCredentialsAnnotation]; ok {
+		t.Errorf("the annotation was supposed to be removed but got %v", serviceAccount.Annotations)
+	}
+}
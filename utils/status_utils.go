@@ -4,27 +4,29 @@ import (
 	"context"
 	"time"
 
-	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 /*
 	Returns the desired state if needed, the boolean return allows calling function to decide if it should update status or now
 */
-func SetDesiredStateIfNeeded(state asa.AquaScannerAccountAquaObjectState) (asa.AquaScannerAccountAquaObjectState, bool) {
-	if state == (asa.AquaScannerAccountAquaObjectState{}) {
-		return asa.AquaScannerAccountAquaObjectState{ApplicationScope: asa.Created.String(), PermissionSet: asa.Created.String(), Role: asa.Created.String(), User: asa.Created.String()}, true
+func SetDesiredStateIfNeeded(state asav2.AquaScannerAccountAquaObjectState) (asav2.AquaScannerAccountAquaObjectState, bool) {
+	if state == (asav2.AquaScannerAccountAquaObjectState{}) {
+		return asav2.AquaScannerAccountAquaObjectState{ApplicationScope: asav2.Created.String(), PermissionSet: asav2.Created.String(), Role: asav2.Created.String(), User: asav2.Created.String()}, true
 	}
-	return asa.AquaScannerAccountAquaObjectState{}, false
+	return asav2.AquaScannerAccountAquaObjectState{}, false
 }
 
 /*
 	A struct merge with the caveat desiredStatus is not merged and remains static from the old status since it should never change
 */
-func MergeStatus(oldStatus asa.AquaScannerAccountStatus, newStatus asa.AquaScannerAccountStatus) asa.AquaScannerAccountStatus {
-	var mergedStatus asa.AquaScannerAccountStatus
+func MergeStatus(oldStatus asav2.AquaScannerAccountStatus, newStatus asav2.AquaScannerAccountStatus) asav2.AquaScannerAccountStatus {
+	var mergedStatus asav2.AquaScannerAccountStatus
 
 	if newStatus.State != "" {
 		mergedStatus.State = newStatus.State
@@ -38,12 +40,6 @@ func MergeStatus(oldStatus asa.AquaScannerAccountStatus, newStatus asa.AquaScann
 		mergedStatus.AccountName = oldStatus.AccountName
 	}
 
-	if newStatus.AccountSecret != "" {
-		mergedStatus.AccountSecret = newStatus.AccountSecret
-	} else {
-		mergedStatus.AccountSecret = oldStatus.AccountSecret
-	}
-
 	if newStatus.Message != "" {
 		mergedStatus.Message = newStatus.Message
 	} else {
@@ -52,33 +48,114 @@ func MergeStatus(oldStatus asa.AquaScannerAccountStatus, newStatus asa.AquaScann
 
 	// because updateStatus doesn't necessarily need to provide a new status with the currentState or desiredState
 	// check if the newStatus is updating currentState, if so use it, otherwise, use the old status
-	if newStatus.CurrentState != (asa.AquaScannerAccountAquaObjectState{}) {
+	if newStatus.CurrentState != (asav2.AquaScannerAccountAquaObjectState{}) {
 		mergedStatus.CurrentState = newStatus.CurrentState
 	} else {
 		mergedStatus.CurrentState = oldStatus.CurrentState
 	}
 
-	if newStatus.DesiredState != (asa.AquaScannerAccountAquaObjectState{}) {
+	if newStatus.DesiredState != (asav2.AquaScannerAccountAquaObjectState{}) {
 		mergedStatus.DesiredState = newStatus.DesiredState
 	} else {
 		mergedStatus.DesiredState = oldStatus.DesiredState
 	}
 
+	if newStatus.Instance != "" {
+		mergedStatus.Instance = newStatus.Instance
+	} else {
+		mergedStatus.Instance = oldStatus.Instance
+	}
+
+	if newStatus.Plan != nil {
+		mergedStatus.Plan = newStatus.Plan
+	} else {
+		mergedStatus.Plan = oldStatus.Plan
+	}
+
+	if newStatus.CredentialsSecretRef != nil {
+		mergedStatus.CredentialsSecretRef = newStatus.CredentialsSecretRef
+	} else {
+		mergedStatus.CredentialsSecretRef = oldStatus.CredentialsSecretRef
+	}
+
+	if newStatus.LastRotation != "" {
+		mergedStatus.LastRotation = newStatus.LastRotation
+	} else {
+		mergedStatus.LastRotation = oldStatus.LastRotation
+	}
+
+	if newStatus.LastRotationTime != nil {
+		mergedStatus.LastRotationTime = newStatus.LastRotationTime
+	} else {
+		mergedStatus.LastRotationTime = oldStatus.LastRotationTime
+	}
+
+	if newStatus.Conditions != nil {
+		mergedStatus.Conditions = newStatus.Conditions
+	} else {
+		mergedStatus.Conditions = oldStatus.Conditions
+	}
+
+	if newStatus.LastResync != "" {
+		mergedStatus.LastResync = newStatus.LastResync
+	} else {
+		mergedStatus.LastResync = oldStatus.LastResync
+	}
+
+	if newStatus.JenkinsSecret != "" {
+		mergedStatus.JenkinsSecret = newStatus.JenkinsSecret
+	} else {
+		mergedStatus.JenkinsSecret = oldStatus.JenkinsSecret
+	}
+
+	if newStatus.TektonTask != "" {
+		mergedStatus.TektonTask = newStatus.TektonTask
+	} else {
+		mergedStatus.TektonTask = oldStatus.TektonTask
+	}
+
+	if newStatus.ServiceAccounts != nil {
+		mergedStatus.ServiceAccounts = newStatus.ServiceAccounts
+	} else {
+		mergedStatus.ServiceAccounts = oldStatus.ServiceAccounts
+	}
+
+	if newStatus.CredentialReadersRole != "" {
+		mergedStatus.CredentialReadersRole = newStatus.CredentialReadersRole
+	} else {
+		mergedStatus.CredentialReadersRole = oldStatus.CredentialReadersRole
+	}
+
+	if newStatus.AquaVersion != "" {
+		mergedStatus.AquaVersion = newStatus.AquaVersion
+	} else {
+		mergedStatus.AquaVersion = oldStatus.AquaVersion
+	}
+
 	return mergedStatus
 }
 
-func UpdateStatus(ctx context.Context, account *asa.AquaScannerAccount, newStatus asa.AquaScannerAccountStatus, clientWriter client.StatusWriter, reqLogger *log.DelegatingLogger) error {
-
+// merges newStatus into the status of the account in memory, the status is written with PatchStatus
+func SetStatus(account *asav2.AquaScannerAccount, newStatus asav2.AquaScannerAccountStatus) {
 	mergedStatus := MergeStatus(account.Status, newStatus)
 	mergedStatus.Timestamp = v1.Timestamp{Seconds: time.Now().Unix(), Nanos: int32(time.Now().UnixNano())}
 	account.Status = mergedStatus
+}
+
+/*
+	Writes the status of the account with a single merge patch against original, the account as it was read at the start
+	of the reconcile. The patch carries the resourceVersion of the account so a write that raced with someone else's fails
+	with a Conflict instead of silently overwriting their change. Nothing is written when the status has not changed.
+*/
+func PatchStatus(ctx context.Context, account *asav2.AquaScannerAccount, original *asav2.AquaScannerAccount, clientWriter client.StatusWriter, reqLogger logr.Logger) error {
+	if equality.Semantic.DeepEqual(original.Status, account.Status) {
+		return nil
+	}
 
-	// to prevent race condition between rapid updates
-	time.Sleep(200 * time.Millisecond)
-	err := clientWriter.Update(ctx, account)
+	err := clientWriter.Patch(ctx, account, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
 
-	if err != nil {
-		reqLogger.Error(err, "Failed to update aquaScannerAccount status")
+	if err != nil && !errors.IsConflict(err) && !errors.IsNotFound(err) {
+		reqLogger.Error(err, "Failed to patch aquaScannerAccount status")
 	}
 
 	return err
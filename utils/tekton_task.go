@@ -0,0 +1,124 @@
+package utils
+
+import (
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// the Tekton Task kind, Tekton is not a dependency so Tasks are handled as unstructured objects
+var TektonTaskGVK = schema.GroupVersionKind{Group: "tekton.dev", Version: "v1beta1", Kind: "Task"}
+
+// where the scan step leaves the json report for the report step
+const tektonReportPath = "/aqua-report/scan.json"
+
+/*
+	Runs scannercli and leaves the gating to the report step, so the Task only fails here when scannercli could not
+	write a report. The image and registry come from the environment so params never end up in the script.
+*/
+const tektonScanScript = `#!/bin/sh
+if [ -n "$REGISTRY" ]; then
+  set -- --registry "$REGISTRY"
+fi
+/opt/aquasec/scannercli scan --host "$AQUA_URL" --user "$SCANNER_USER" --password "$SCANNER_PASSWORD" --jsonfile ` + tektonReportPath + ` "$@" "$IMAGE"
+status=$?
+if [ ! -s ` + tektonReportPath + ` ]; then
+  exit $status
+fi
+`
+
+// what the Tekton Task of an AquaScannerAccount needs besides the account
+type TektonTaskOptions struct {
+	ScannerImage string
+	// image with kubectl-aqua
+	ReportImage string
+	// the Secret of the AquaScannerAccount with the aqua url and scanner credentials
+	CredentialsSecret string
+}
+
+// the parts of a Tekton Task spec that are written
+type tektonTaskSpec struct {
+	Description string          `json:"description"`
+	Params      []tektonParam   `json:"params"`
+	Steps       []tektonStep    `json:"steps"`
+	Volumes     []corev1.Volume `json:"volumes"`
+}
+
+type tektonParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// params without a default are required
+	Default *string `json:"default,omitempty"`
+}
+
+type tektonStep struct {
+	Name         string               `json:"name"`
+	Image        string               `json:"image"`
+	Command      []string             `json:"command,omitempty"`
+	Args         []string             `json:"args,omitempty"`
+	Script       string               `json:"script,omitempty"`
+	Env          []corev1.EnvVar      `json:"env,omitempty"`
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts"`
+}
+
+/*
+	Sets the spec of task to a Task that scans the image param with scannercli and the account's credentials, then gates
+	the report on the fail-on and fail-on-policy params with kubectl aqua report. The spec is replaced as a whole so the
+	Task follows the scanner image setting, labels and annotations others set are kept.
+*/
+func SetTektonTask(task *unstructured.Unstructured, account *asav2.AquaScannerAccount, options TektonTaskOptions) error {
+	env := []corev1.EnvVar{
+		{Name: "IMAGE", Value: "$(params.image)"},
+		{Name: "REGISTRY", Value: "$(params.registry)"},
+	}
+	for _, key := range []string{asav2.CredentialsSecretURLKey, asav2.CredentialsSecretUserKey, asav2.CredentialsSecretPasswordKey} {
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: options.CredentialsSecret},
+				Key:                  key,
+			}},
+		})
+	}
+
+	empty, failOnPolicy := "", "true"
+	mounts := []corev1.VolumeMount{{Name: "aqua-report", MountPath: "/aqua-report"}}
+
+	spec := tektonTaskSpec{
+		Description: "Scans an image with scannercli and the credentials of AquaScannerAccount " + account.Name + ". Managed by the aqua scanner operator, changes are overwritten.",
+		Params: []tektonParam{
+			{Name: "image", Type: "string", Description: "the image to scan"},
+			{Name: "registry", Type: "string", Description: "name of the registry in aqua, aqua works it out from the image when empty", Default: &empty},
+			{Name: "fail-on", Type: "string", Description: "severity thresholds, e.g. critical=0,high=5, or high to fail on any high or critical vulnerability", Default: &empty},
+			{Name: "fail-on-policy", Type: "string", Description: "fail when the image does not pass its aqua image assurance policies", Default: &failOnPolicy},
+		},
+		Steps: []tektonStep{
+			{
+				Name:         "scan",
+				Image:        options.ScannerImage,
+				Script:       tektonScanScript,
+				Env:          env,
+				VolumeMounts: mounts,
+			},
+			{
+				Name:         "report",
+				Image:        options.ReportImage,
+				Command:      []string{"/kubectl-aqua"},
+				Args:         []string{"report", tektonReportPath, "--fail-on=$(params.fail-on)", "--fail-on-policy=$(params.fail-on-policy)"},
+				VolumeMounts: mounts,
+			},
+		},
+		Volumes: []corev1.Volume{{Name: "aqua-report", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+	}
+
+	unstructuredSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		return err
+	}
+
+	task.SetGroupVersionKind(TektonTaskGVK)
+	return unstructured.SetNestedField(task.Object, unstructuredSpec, "spec")
+}
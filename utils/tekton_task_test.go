@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSetTektonTask(t *testing.T) {
+	account := &asav2.AquaScannerAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "scanner", Namespace: "abc123-tools"},
+		Spec:       asav2.AquaScannerAccountSpec{Delivery: asav2.AquaScannerAccountDelivery{Tekton: &asav2.AquaScannerAccountTekton{}}},
+	}
+
+	if name := account.TektonTaskName(); name != "aqua-scan" {
+		t.Errorf("the Task was supposed to default to aqua-scan but got %v", name)
+	}
+
+	task := &unstructured.Unstructured{}
+	task.SetLabels(map[string]string{"team": "abc123"})
+	options := TektonTaskOptions{ScannerImage: "registry.aquasec.com/scanner:2022.4", ReportImage: "operator:v1", CredentialsSecret: "scanner-aqua-credentials"}
+
+	if err := SetTektonTask(task, account, options); err != nil {
+		t.Fatalf("the Task was supposed to be built but got %v", err)
+	}
+
+	if task.GetAPIVersion() != "tekton.dev/v1beta1" || task.GetKind() != "Task" || task.GetLabels()["team"] != "abc123" {
+		t.Errorf("a tekton.dev/v1beta1 Task keeping its labels was supposed to be built but got %v %v %v", task.GetAPIVersion(), task.GetKind(), task.GetLabels())
+	}
+
+	params, _, _ := unstructured.NestedSlice(task.Object, "spec", "params")
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "image,registry,fail-on,fail-on-policy" {
+		t.Errorf("the Task was supposed to take image, registry and thresholds as params but got %v", names)
+	}
+	if _, hasDefault := params[0].(map[string]interface{})["default"]; hasDefault {
+		t.Errorf("the image param was supposed to be required")
+	}
+
+	steps, _, _ := unstructured.NestedSlice(task.Object, "spec", "steps")
+	scan, report := steps[0].(map[string]interface{}), steps[1].(map[string]interface{})
+
+	if scan["image"] != options.ScannerImage || !strings.Contains(scan["script"].(string), "--jsonfile") || strings.Contains(scan["script"].(string), "$(params.") {
+		t.Errorf("the scan step was supposed to run scannercli without params in its script but got %v", scan)
+	}
+
+	secrets := 0
+	for _, env := range scan["env"].([]interface{}) {
+		if valueFrom, ok := env.(map[string]interface{})["valueFrom"]; ok {
+			ref := valueFrom.(map[string]interface{})["secretKeyRef"].(map[string]interface{})
+			if ref["name"] != "scanner-aqua-credentials" {
+				t.Errorf("the credentials were supposed to come from the credentials secret but got %v", ref)
+			}
+			secrets++
+		}
+	}
+	if secrets != 3 {
+		t.Errorf("the url, user and password were supposed to come from the credentials secret but got %v", secrets)
+	}
+
+	if report["image"] != "operator:v1" || !strings.Contains(strings.Join(toStrings(report["args"]), " "), "--fail-on=$(params.fail-on)") {
+		t.Errorf("the report step was supposed to gate with kubectl aqua report but got %v", report)
+	}
+
+	// a new scanner image setting upgrades the Task
+	options.ScannerImage = "registry.aquasec.com/scanner:2022.10"
+	if err := SetTektonTask(task, account, options); err != nil {
+		t.Fatal(err)
+	}
+	steps, _, _ = unstructured.NestedSlice(task.Object, "spec", "steps")
+	if image := steps[0].(map[string]interface{})["image"]; image != options.ScannerImage {
+		t.Errorf("the Task was supposed to use the new scanner image but got %v", image)
+	}
+
+	account.Spec.Delivery.Tekton = nil
+	if name := account.TektonTaskName(); name != "" {
+		t.Errorf("there was not supposed to be a Task without spec.delivery.tekton but got %v", name)
+	}
+}
+
+func toStrings(values interface{}) []string {
+	strs := []string{}
+	for _, value := range values.([]interface{}) {
+		strs = append(strs, value.(string))
+	}
+	return strs
+}
@@ -1,18 +1,28 @@
 package utils
 
 import (
-	"errors"
-	"os"
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"regexp"
-	"strconv"
 	"testing"
 
 	asa "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v1"
+	asav2 "github.com/bcgov-platform-services/aqua-scan-cli-operator/api/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestSetDesiredStateIfNeeded(t *testing.T) {
-	emptyDesiredState := asa.AquaScannerAccountAquaObjectState{}
+	emptyDesiredState := asav2.AquaScannerAccountAquaObjectState{}
 
 	newDesiredState, shouldUpdate := SetDesiredStateIfNeeded(emptyDesiredState)
 
@@ -20,7 +30,7 @@ func TestSetDesiredStateIfNeeded(t *testing.T) {
 		t.Errorf("SetDesiredStateIfNeeded should have returned boolean as true but got false")
 	}
 
-	shouldEqual := newDesiredState == asa.AquaScannerAccountAquaObjectState{ApplicationScope: asa.Created.String(), PermissionSet: asa.Created.String(), Role: asa.Created.String(), User: asa.Created.String()}
+	shouldEqual := newDesiredState == asav2.AquaScannerAccountAquaObjectState{ApplicationScope: asav2.Created.String(), PermissionSet: asav2.Created.String(), Role: asav2.Created.String(), User: asav2.Created.String()}
 	if !shouldEqual {
 		t.Errorf("SetDesiredStateIfNeeded should have returned an initialized desired state but it returned the zero state instead, %#v", newDesiredState)
 	}
@@ -28,17 +38,17 @@ func TestSetDesiredStateIfNeeded(t *testing.T) {
 
 func TestMergeStatus(t *testing.T) {
 
-	oldStatus := asa.AquaScannerAccountStatus{}
-	newStatus := asa.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}
+	oldStatus := asav2.AquaScannerAccountStatus{}
+	newStatus := asav2.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}
 
 	mergedStatus := MergeStatus(oldStatus, newStatus)
 
-	if mergedStatus != (asa.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}) {
+	if !reflect.DeepEqual(mergedStatus, asav2.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}) {
 		t.Errorf("MergeStatus was supposed return an AquaScannerAccountStatus of %v but got %v when oldStatus is in a zero state", newStatus, mergedStatus)
 	}
 
-	oldStatus = asa.AquaScannerAccountStatus{Message: "Matt Damon", State: "Failed", AccountName: "Matt Damon"}
-	newStatus = asa.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}
+	oldStatus = asav2.AquaScannerAccountStatus{Message: "Matt Damon", State: "Failed", AccountName: "Matt Damon"}
+	newStatus = asav2.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}
 
 	mergedStatus = MergeStatus(oldStatus, newStatus)
 
@@ -54,37 +64,61 @@ func TestMergeStatus(t *testing.T) {
 		t.Errorf("MergeStatus was supposed return an AquaScannerAccountStatus with AccountName: Matt Damon but got %v when oldStatus.AccountName is Matt Damon", mergedStatus.Message)
 	}
 
-	desiredState := asa.AquaScannerAccountAquaObjectState{ApplicationScope: asa.Created.String()}
-	oldStatus = asa.AquaScannerAccountStatus{Message: "Matt Damon", State: "Failed", AccountName: "Matt Damon", DesiredState: desiredState}
-	newStatus = asa.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}
+	desiredState := asav2.AquaScannerAccountAquaObjectState{ApplicationScope: asav2.Created.String()}
+	oldStatus = asav2.AquaScannerAccountStatus{Message: "Matt Damon", State: "Failed", AccountName: "Matt Damon", DesiredState: desiredState}
+	newStatus = asav2.AquaScannerAccountStatus{Message: "Hello World", State: "Complete"}
 
 	mergedStatus = MergeStatus(oldStatus, newStatus)
 
 	if mergedStatus.DesiredState != desiredState {
 		t.Errorf("MergeStatus was supposed return an AquaScannerAccountStatus with DesiredState unchanged but got %v", mergedStatus.DesiredState)
 	}
-}
 
-func mockGetJwtFail() (string, error) {
-	return "", errors.New("this func failed")
-}
+	rotated := metav1.Now()
+	oldStatus = asav2.AquaScannerAccountStatus{
+		CredentialsSecretRef: &corev1.LocalObjectReference{Name: "scanner-aqua-credentials"},
+		Conditions:           []metav1.Condition{{Type: asav2.ConditionReady, Status: metav1.ConditionTrue}},
+		LastRotationTime:     &rotated,
+	}
+	newStatus = asav2.AquaScannerAccountStatus{State: "Running"}
 
-func mockGetJwtPass() (string, error) {
-	return "", nil
+	mergedStatus = MergeStatus(oldStatus, newStatus)
+
+	if mergedStatus.CredentialsSecretRef == nil || len(mergedStatus.Conditions) != 1 || mergedStatus.LastRotationTime != &rotated {
+		t.Errorf("MergeStatus was supposed to keep the credentials secret, conditions and rotation time but got %+v", mergedStatus)
+	}
 }
 
-func TestSetEnvForAsaLoginCheck(t *testing.T) {
-	SetEnvForAsaLoginCheck(mockGetJwtFail, ctrl.Log)
-	b, _ := strconv.ParseBool(os.Getenv("ASA_LOGIN_CHECK_DID_FAIL"))
-	if !b {
-		t.Errorf("SetEnvForAsaLoginCheck was supposed to return true but got %v", os.Getenv("ASA_LOGIN_CHECK_DID_FAIL"))
+func TestAquaAuthCheckLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good-token" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	aa, _ := NewAquaAuth(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "bad-token"}, nil)
+
+	if _, checked := aa.LoginCheckFailed(); checked {
+		t.Errorf("the login was not supposed to be checked before CheckLogin")
+	}
+
+	if err := aa.CheckLogin(ctrl.Log); err == nil {
+		t.Errorf("CheckLogin was supposed to fail for a token aqua rejects")
+	}
+	if failed, checked := aa.LoginCheckFailed(); !failed || !checked {
+		t.Errorf("the failed login was supposed to be recorded but got %v, %v", failed, checked)
 	}
 
-	SetEnvForAsaLoginCheck(mockGetJwtPass, ctrl.Log)
-	c, _ := strconv.ParseBool(os.Getenv("ASA_LOGIN_CHECK_DID_FAIL"))
+	aa.SetCredentials(server.URL, AquaCredentials{Method: TokenAuthMethod, Token: "good-token"})
 
-	if c {
-		t.Errorf("SetEnvForAsaLoginCheck was supposed to return false but got %v", os.Getenv("ASA_LOGIN_CHECK_DID_FAIL"))
+	if err := aa.CheckLogin(ctrl.Log); err != nil {
+		t.Errorf("CheckLogin was supposed to pass for a token aqua accepts but got %v", err)
+	}
+	if failed, _ := aa.LoginCheckFailed(); failed {
+		t.Errorf("the passed login was supposed to replace the failed one")
 	}
 }
 
@@ -97,11 +131,24 @@ func TestUtilsGetTechnicalContact(t *testing.T) {
 	}
 }
 
+func TestUtilsGetContacts(t *testing.T) {
+	contactsAnnotation := "- role: Product Owner\n  email: matt.damon@gov.bc.ca\n  rocketchat:\n- role: Technical Lead\n  email: patrick.simonian@gov.bc.ca\n  rocketchat:\n- role: Security Contact\n  rocketchat: '@security'\n"
+	contacts := GetContactsFromAnnotation(contactsAnnotation)
+
+	expected := []Contact{{Role: "Product Owner", Email: "matt.damon@gov.bc.ca"}, {Role: "Technical Lead", Email: "patrick.simonian@gov.bc.ca"}}
+	if !reflect.DeepEqual(contacts, expected) {
+		t.Errorf("GetContactsFromAnnotation was supposed to return %v but got %v", expected, contacts)
+	}
+}
+
 func TestUtilsGeneratePassword(t *testing.T) {
-	pw1 := GeneratePassword(8, false, false, false)
-	pw2 := GeneratePassword(8, true, true, true)
+	pw1, err1 := GeneratePasswordWithPolicy(PasswordPolicy{Length: 8, RequiredClasses: []string{PasswordLowercase}}, rand.Reader)
+	pw2, err2 := GeneratePasswordWithPolicy(PasswordPolicy{Length: 8, RequiredClasses: []string{PasswordLowercase, PasswordUppercase, PasswordDigits, PasswordSymbols}}, rand.Reader)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("GeneratePasswordWithPolicy was not supposed to fail but got %v, %v", err1, err2)
+	}
 	if len(pw1) != 8 {
-		t.Errorf("GeneratePassword was support to return a password with length 8 but got %v", len(pw1))
+		t.Errorf("GeneratePasswordWithPolicy was support to return a password with length 8 but got %v", len(pw1))
 	}
 	bPw2 := []byte(pw2)
 	matchedPw1, _ := regexp.Match("[a-z]{8}", []byte(pw1))
@@ -110,18 +157,118 @@ func TestUtilsGeneratePassword(t *testing.T) {
 	symbolCheck, _ := regexp.Compile("[!@#$]")
 
 	if !matchedPw1 {
-		t.Errorf("GeneratePassword was supposed to return a password with only lower case but got %v", pw1)
+		t.Errorf("GeneratePasswordWithPolicy was supposed to return a password with only lower case but got %v", pw1)
 	}
 
 	if !numCheck.Match(bPw2) {
-		t.Errorf("GeneratePassword was supposed to return a password with at least 1 number but got %v", pw2)
+		t.Errorf("GeneratePasswordWithPolicy was supposed to return a password with at least 1 number but got %v", pw2)
 	}
 
 	if !upperCheck.Match(bPw2) {
-		t.Errorf("GeneratePassword was supposed to return a password with at least 1 uppercase letter but got %v", pw2)
+		t.Errorf("GeneratePasswordWithPolicy was supposed to return a password with at least 1 uppercase letter but got %v", pw2)
 	}
 
 	if !symbolCheck.Match(bPw2) {
-		t.Errorf("GeneratePassword was supposed to return a password with at least 1 symbol but got %v", pw2)
+		t.Errorf("GeneratePasswordWithPolicy was supposed to return a password with at least 1 symbol but got %v", pw2)
+	}
+}
+
+func TestSetAquaInstanceAuth(t *testing.T) {
+	config := AquaInstanceConfig{Url: "https://aqua.lab", Credentials: AquaCredentials{User: "admin", Password: "password"}}
+
+	auth, err := SetAquaInstanceAuth("lab", config)
+	if err != nil {
+		t.Fatalf("SetAquaInstanceAuth was not supposed to return an error but got %v", err)
+	}
+	defer RemoveAquaInstanceAuth("lab")
+
+	sameAuth, _ := SetAquaInstanceAuth("lab", config)
+	if sameAuth != auth {
+		t.Errorf("SetAquaInstanceAuth was supposed to keep the cached AquaAuth when the config did not change")
+	}
+
+	config.Credentials.Password = "new password"
+	newAuth, _ := SetAquaInstanceAuth("lab", config)
+	if newAuth == auth || newAuth.Credentials.Password != "new password" {
+		t.Errorf("SetAquaInstanceAuth was supposed to replace the cached AquaAuth when the config changed")
+	}
+
+	registeredAuth, ok := GetAquaInstanceAuth("lab")
+	if !ok || registeredAuth != newAuth {
+		t.Errorf("GetAquaInstanceAuth was supposed to return the registered AquaAuth for lab")
+	}
+
+	if _, ok := GetAquaInstanceAuth("prod"); ok {
+		t.Errorf("GetAquaInstanceAuth was supposed to return false for an instance that was never registered")
+	}
+
+	_, caErr := SetAquaInstanceAuth("broken", AquaInstanceConfig{Url: "https://aqua.lab", Http: AquaHttpConfig{CABundle: []byte("not a certificate")}})
+	if caErr == nil {
+		t.Errorf("SetAquaInstanceAuth was supposed to return an error for a CA bundle without certificates")
+	}
+}
+
+func TestAquaInstanceAllowsNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	asa.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "abc123-tools", Labels: map[string]string{"aqua-console": "lab"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "def456-tools"}},
+		&asa.AquaInstance{ObjectMeta: metav1.ObjectMeta{Name: "lab"}, Spec: asa.AquaInstanceSpec{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"aqua-console": "lab"}}}},
+		&asa.AquaInstance{ObjectMeta: metav1.ObjectMeta{Name: "production"}},
+	).Build()
+	ctx := context.Background()
+
+	if allowed, err := AquaInstanceAllowsNamespace(ctx, c, "lab", "abc123-tools"); err != nil || !allowed {
+		t.Errorf("an instance whose selector matches the namespace was supposed to allow it but got %v, %v", allowed, err)
+	}
+	if allowed, err := AquaInstanceAllowsNamespace(ctx, c, "lab", "def456-tools"); err != nil || allowed {
+		t.Errorf("an instance whose selector does not match the namespace was not supposed to allow it but got %v, %v", allowed, err)
+	}
+	if allowed, err := AquaInstanceAllowsNamespace(ctx, c, "production", "def456-tools"); err != nil || !allowed {
+		t.Errorf("an instance without a selector was supposed to allow every namespace but got %v, %v", allowed, err)
+	}
+	if _, err := AquaInstanceAllowsNamespace(ctx, c, "staging", "abc123-tools"); !apierrors.IsNotFound(err) {
+		t.Errorf("a missing instance was supposed to be a NotFound error but got %v", err)
+	}
+}
+
+func TestPatchStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	asav2.AddToScheme(scheme)
+
+	account := &asav2.AquaScannerAccount{ObjectMeta: metav1.ObjectMeta{Name: "aquascanneraccount", Namespace: "abc123-tools"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(account).Build()
+	ctx := context.Background()
+
+	c.Get(ctx, client.ObjectKeyFromObject(account), account)
+	original := account.DeepCopy()
+
+	SetStatus(account, asav2.AquaScannerAccountStatus{State: "Running"})
+	SetStatus(account, asav2.AquaScannerAccountStatus{Message: "Beginning reconcilliation"})
+
+	if err := PatchStatus(ctx, account, original, c.Status(), ctrl.Log); err != nil {
+		t.Fatalf("PatchStatus was not supposed to return an error but got %v", err)
+	}
+
+	stored := &asav2.AquaScannerAccount{}
+	c.Get(ctx, client.ObjectKeyFromObject(account), stored)
+
+	if stored.Status.State != "Running" || stored.Status.Message != "Beginning reconcilliation" {
+		t.Errorf("PatchStatus was supposed to write every status change made in memory but got %v", stored.Status)
+	}
+
+	// someone else changes the account after it was read
+	staleOriginal := stored.DeepCopy()
+	stale := stored.DeepCopy()
+	stored.Labels = map[string]string{"team": "abc123"}
+	c.Update(ctx, stored)
+
+	SetStatus(stale, asav2.AquaScannerAccountStatus{State: "Complete"})
+
+	if err := PatchStatus(ctx, stale, staleOriginal, c.Status(), ctrl.Log); !apierrors.IsConflict(err) {
+		t.Errorf("PatchStatus was supposed to return a conflict for a stale account but got %v", err)
 	}
 }
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+/*
+	The label on the Secrets and ConfigMaps the operator watches. The manager only caches Secrets and ConfigMaps that
+	carry it so it does not hold every Secret in the cluster in memory. The operator sets it on the ones it creates, the
+	ones an AquaInstance references need it for a change to be picked up before the next health check.
+*/
+const WatchLabel = "mamoa.devops.gov.bc.ca/watch"
+
+const watchLabelValue = "true"
+
+// sets WatchLabel on the object, labels others set are kept
+func SetWatchLabel(object metav1.Object) {
+	objectLabels := object.GetLabels()
+	if objectLabels == nil {
+		objectLabels = map[string]string{}
+	}
+	objectLabels[WatchLabel] = watchLabelValue
+	object.SetLabels(objectLabels)
+}
+
+// selects the objects carrying WatchLabel
+func WatchLabelSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{WatchLabel: watchLabelValue})
+}
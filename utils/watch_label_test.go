@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSetWatchLabel(t *testing.T) {
+	secret := &corev1.Secret{}
+	SetWatchLabel(secret)
+	if !WatchLabelSelector().Matches(labels.Set(secret.Labels)) {
+		t.Errorf("SetWatchLabel was supposed to label the secret so the cache selects it but got %v", secret.Labels)
+	}
+
+	secret.Labels = map[string]string{"team": "abc123"}
+	SetWatchLabel(secret)
+	if secret.Labels["team"] != "abc123" || !WatchLabelSelector().Matches(labels.Set(secret.Labels)) {
+		t.Errorf("SetWatchLabel was supposed to keep the labels others set but got %v", secret.Labels)
+	}
+
+	if WatchLabelSelector().Matches(labels.Set{"team": "abc123"}) {
+		t.Errorf("WatchLabelSelector was not supposed to select objects without the label")
+	}
+}